@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"interview-task-worker-pool/internal/archive"
+	"interview-task-worker-pool/internal/config"
+	"interview-task-worker-pool/internal/http/dto"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const archiveUsage = `usage:
+  app archive search  [-dir DIR] [-id 1,2,...] [-from YYYY-MM-DD] [-to YYYY-MM-DD]
+  app archive restore [-addr URL] [-token TOKEN] [-id 1,2,...] [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-retain-for SECONDS]
+
+search reads the archive directory (ARCHIVE_DIR) and prints the matching tasks
+as NDJSON. restore asks the running server to put them back into its store
+(POST /v1/admin/archive/restore, needs ADMIN_TOKEN).
+`
+
+// runArchive runs the archive subcommand and returns the exit code.
+func runArchive(cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, archiveUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("archive "+args[0], flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, archiveUsage) }
+	dir := fs.String("dir", cfg.ArchiveDir, "archive directory")
+	addr := fs.String("addr", "http://localhost"+cfg.HTTPPort, "server base URL")
+	token := fs.String("token", cfg.AdminToken, "admin token")
+	ids := fs.String("id", "", "comma separated task ids")
+	from := fs.String("from", "", "first completion date, inclusive")
+	to := fs.String("to", "", "last completion date, inclusive")
+	retainFor := fs.Int("retain-for", 0, "seconds restored tasks are kept, the server default when 0")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	req := dto.ArchiveRestoreRequest{From: *from, To: *to, RetainFor: *retainFor}
+	for _, s := range strings.Split(*ids, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid id %q\n", s)
+			return 2
+		}
+		req.IDs = append(req.IDs, id)
+	}
+
+	var err error
+	switch args[0] {
+	case "search":
+		err = searchArchive(*dir, req)
+	case "restore":
+		err = restoreArchived(*addr, *token, req)
+	default:
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func searchArchive(dir string, req dto.ArchiveRestoreRequest) error {
+	if dir == "" {
+		return fmt.Errorf("no archive directory, set -dir or ARCHIVE_DIR")
+	}
+	q := archive.Query{IDs: req.IDs}
+	for _, d := range []struct {
+		value string
+		into  *time.Time
+	}{{req.From, &q.From}, {req.To, &q.To}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, d.value)
+		if err != nil {
+			return fmt.Errorf("%q is not a YYYY-MM-DD date", d.value)
+		}
+		*d.into = t
+	}
+
+	a, err := archive.Open(dir)
+	if err != nil {
+		return err
+	}
+	tasks, err := a.Search(q)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, task := range tasks {
+		if err := enc.Encode(archive.NewRecord(task)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreArchived(addr, token string, req dto.ArchiveRestoreRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, strings.TrimRight(addr, "/")+"/v1/admin/archive/restore", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("restore failed: %s: %s", resp.Status, bytes.TrimSpace(out))
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
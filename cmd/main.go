@@ -2,19 +2,39 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"interview-task-worker-pool/internal/archive"
+	"interview-task-worker-pool/internal/auth"
 	"interview-task-worker-pool/internal/config"
+	"interview-task-worker-pool/internal/dag"
+	"interview-task-worker-pool/internal/health"
 	router "interview-task-worker-pool/internal/http"
 	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/http/openapi"
+	"interview-task-worker-pool/internal/logging"
+	"interview-task-worker-pool/internal/metrics"
+	"interview-task-worker-pool/internal/ratelimit"
+	"interview-task-worker-pool/internal/retention"
+	"interview-task-worker-pool/internal/rpc"
 	"interview-task-worker-pool/internal/service"
+	storepkg "interview-task-worker-pool/internal/store"
 	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/store/sqlite"
+	"interview-task-worker-pool/internal/tasklog"
+	"interview-task-worker-pool/internal/tracing"
 	"interview-task-worker-pool/internal/workerpool"
 	"log"
+	"log/slog"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -22,19 +42,147 @@ func main() {
 
 	cfg := config.New()
 
-	store := memory.New()
+	if len(os.Args) > 1 && os.Args[1] == "archive" {
+		os.Exit(runArchive(cfg, os.Args[2:]))
+	}
+
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatalf("logger initiation failed: %v", err)
+	}
+	slog.SetDefault(logger)
+
+	store, closeStore, err := newStore(cfg, logger)
+	if err != nil {
+		fatal("store initiation failed", err)
+	}
+
+	registry := metrics.NewRegistry()
+	httpMetrics := metrics.NewHTTPMetrics(registry)
+
+	tracer, err := newTracer(cfg)
+	if err != nil {
+		fatal("tracer initiation failed", err)
+	}
+
+	taskLogs := tasklog.NewStore(tasklog.Limits{MaxLines: cfg.TaskLogMaxLines, MaxBytes: cfg.TaskLogMaxBytes})
+
+	pool := workerpool.New(cfg.PoolSize, store,
+		workerpool.WithObserver(metrics.NewPoolMetrics(registry)),
+		workerpool.WithTracer(tracer),
+		workerpool.WithLogger(logger),
+		workerpool.WithTaskLogs(taskLogs),
+		workerpool.WithTenantWeights(cfg.TenantWeights),
+	)
+	metrics.RegisterPoolGauges(registry, pool)
+
+	resolver := dag.NewResolver(store, pool)
+	pool.OnFinish(resolver.Resolve)
 
-	pool := workerpool.New(cfg.PoolSize, store)
 	pool.Start(cfg.Workers)
 
-	service, err := service.New(store, pool) // pool implements workerpool.TaskPool
+	tiers, err := ratelimit.ParsePolicy(cfg.RateLimitTiers, cfg.ClientTiers)
 	if err != nil {
-		log.Fatalf("service initiation failed: %v", err)
+		fatal("rate limit initiation failed", err)
+	}
+	limiter := ratelimit.NewLimiter()
+
+	service, err := service.New(store, pool, // pool implements workerpool.TaskPool
+		service.WithIdempotencyTTL(cfg.IdempotencyTTL),
+		service.WithDedupePolicy(storepkg.DedupePolicy(cfg.DedupePolicy)),
+		service.WithResolver(resolver),
+		service.WithTracer(tracer),
+		service.WithQuota(tiers.MaxPending),
+		service.WithTaskLogs(taskLogs),
+	)
+	if err != nil {
+		fatal("service initiation failed", err)
+	}
+
+	// expired idempotency keys and idle rate limit buckets are swept periodically,
+	// lookups ignore expired keys anyway
+	stopSweep := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				store.ExpireIdempotencyKeys(time.Now())
+				limiter.Sweep(10 * time.Minute)
+			case <-stopSweep:
+				return
+			}
+		}
+	}()
+
+	// finished tasks are evicted in the background, a page of tasks at a time,
+	// and archived first when ARCHIVE_DIR is set
+	retentionOpts := []retention.Option{
+		retention.WithBatchSize(cfg.RetentionBatch),
+		retention.WithTaskLogs(taskLogs),
+		retention.WithObserver(metrics.NewRetentionMetrics(registry)),
+		retention.WithLogger(logger),
+	}
+	var tasksArchive *archive.Archive
+	if cfg.ArchiveDir != "" {
+		if tasksArchive, err = archive.Open(cfg.ArchiveDir, archive.WithMaxFileBytes(cfg.ArchiveMaxFileBytes)); err != nil {
+			fatal("archive initiation failed", err)
+		}
+		retentionOpts = append(retentionOpts, retention.WithArchive(tasksArchive))
+		slog.Info("archiving evicted tasks", "dir", cfg.ArchiveDir)
+	}
+	janitor := retention.NewJanitor(store, retention.Policy{
+		DoneTTL:     cfg.RetainDone,
+		FailedTTL:   cfg.RetainFailed,
+		CanceledTTL: cfg.RetainCanceled,
+		MaxTasks:    cfg.MaxTasks,
+	}, retentionOpts...)
+	if tasksArchive != nil && !janitor.Enabled() {
+		// nothing would ever be evicted, so nothing archived
+		fatal("archive initiation failed", errors.New("ARCHIVE_DIR needs a retention policy (RETAIN_DONE, RETAIN_FAILED, RETAIN_CANCELED or MAX_TASKS)"))
+	}
+	retentionCtx, stopRetention := context.WithCancel(context.Background())
+	if janitor.Enabled() {
+		go janitor.Run(retentionCtx, cfg.RetentionInterval)
 	}
 
+	// the saturation check is sampled in the background too, not only on probes
+	saturation := health.NewQueueSaturation(pool, cfg.ReadyQueueThreshold, cfg.ReadySaturationPeriod)
+	sampleCtx, stopSampling := context.WithCancel(context.Background())
+	go saturation.Run(sampleCtx, time.Second)
+
+	checks := health.New(health.DefaultTimeout)
+	checks.Register(health.PoolOpen(pool), health.Store(store), saturation)
+
 	handler := handlers.New(service)
 
-	router := router.New(handler)
+	authenticators, routerOpts, err := authOptions(cfg)
+	if err != nil {
+		fatal("authentication initiation failed", err)
+	}
+
+	openAPIHandler, err := handlers.NewOpenAPIHandler(openapi.New())
+	if err != nil {
+		fatal("openapi initiation failed", err)
+	}
+
+	if tasksArchive != nil {
+		routerOpts = append(routerOpts, router.WithArchive(handlers.NewArchiveHandler(tasksArchive, store), cfg.AdminToken))
+	}
+
+	router := router.New(handler, append(routerOpts,
+		router.WithPool(handlers.NewPoolHandler(pool)),
+		router.WithTaskLogs(handlers.NewLogHandler(service, taskLogs)),
+		router.WithMetrics(registry.Handler()),
+		router.WithHealth(checks),
+		router.WithAdmin(handlers.NewAdminHandler(pool), cfg.AdminToken),
+		router.WithTransfer(handlers.NewTransferHandler(store), cfg.AdminToken),
+		router.WithRateLimit(handlers.RateLimit(limiter, tiers)),
+		router.WithOpenAPI(openAPIHandler),
+		// metrics goes last, it reads the matched pattern from the request the mux saw
+		router.WithMiddleware(logging.Middleware(logger), tracing.Middleware(tracer), httpMetrics.Middleware),
+	)...)
 
 	server := &http.Server{
 		Addr:    cfg.HTTPPort,
@@ -42,9 +190,26 @@ func main() {
 	}
 
 	go func() {
-		log.Printf("listening on %s", cfg.HTTPPort)
+		slog.Info("listening", "addr", cfg.HTTPPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server failed: %s\n", err)
+			fatal("server failed", err)
+		}
+	}()
+
+	rpcOpts := []rpc.Option{rpc.WithLogger(logger), rpc.WithRateLimit(limiter, tiers)}
+	if len(authenticators) > 0 {
+		rpcOpts = append(rpcOpts, rpc.WithAuth(authenticators...))
+	}
+	grpcServer := rpc.New(rpc.NewServer(service), rpcOpts...)
+
+	listener, err := net.Listen("tcp", cfg.GRPCPort)
+	if err != nil {
+		fatal("grpc listen failed", err)
+	}
+	go func() {
+		slog.Info("grpc listening", "addr", cfg.GRPCPort)
+		if err := grpcServer.Serve(listener); err != nil {
+			fatal("grpc server failed", err)
 		}
 	}()
 
@@ -53,20 +218,148 @@ func main() {
 	defer signal.Stop(stop)
 
 	<-stop
-	log.Printf("shut down signal received...")
+	slog.Info("shut down signal received")
+	close(stopSweep)
+	stopSampling()
+	stopRetention()
 
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
-	// 1) stop accepting new HTTP requests
+	// 1) stop accepting new HTTP requests and gRPC calls
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("server shutdown failed: %v", err)
+		fatal("server shutdown failed", err)
 	}
+	stopGRPC(ctx, grpcServer)
 
 	// 2) drain workers (get pending tasks finished)
 	if err := pool.Shutdown(ctx); err != nil {
-		log.Fatalf("pool shutdown failed: %v", err)
+		fatal("pool shutdown failed", err)
+	}
+
+	// 3) flush the spans of the drained tasks
+	if err := tracer.Shutdown(ctx); err != nil {
+		slog.Warn("tracer shutdown failed", "error", err)
+	}
+
+	// 4) nothing writes to the store anymore
+	if err := closeStore(); err != nil {
+		slog.Warn("store close failed", "error", err)
 	}
 
-	log.Printf("shut down gracefully")
+	slog.Info("shut down gracefully")
+}
+
+// stopGRPC waits for running calls (WatchTask streams included) until ctx is
+// done, then closes the rest.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Warn("grpc graceful stop timed out, closing open calls")
+		server.Stop()
+	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// authOptions turns authentication on when API keys (API_KEYS, API_KEYS_FILE)
+// or JWT keys (JWT_HS256_SECRET, JWT_JWKS_FILE) are set. The authenticators are
+// returned for the gRPC server too.
+func authOptions(cfg config.Config) ([]auth.Authenticator, []router.Option, error) {
+	var authenticators []auth.Authenticator
+	var opts []router.Option
+
+	// JWTs go first, they leave bearer tokens that aren't JWTs to the API keys
+	if cfg.JWTSecret != "" || cfg.JWTJWKSFile != "" {
+		jwks := auth.NewJWKS()
+		if cfg.JWTJWKSFile != "" {
+			var err error
+			if jwks, err = auth.LoadJWKS(cfg.JWTJWKSFile); err != nil {
+				return nil, nil, err
+			}
+		}
+		if cfg.JWTSecret != "" {
+			jwks.AddHMAC("", []byte(cfg.JWTSecret))
+		}
+		authenticators = append(authenticators, auth.NewJWTAuthenticator(auth.JWTConfig{
+			Issuer:   cfg.JWTIssuer,
+			Audience: cfg.JWTAudience,
+			Keys:     jwks,
+			Leeway:   time.Minute,
+		}))
+		slog.Info("jwt authentication enabled", "keys", jwks.Len())
+	}
+
+	if cfg.APIKeys != "" || cfg.APIKeysFile != "" {
+		keys := auth.NewKeyStore()
+		if cfg.APIKeysFile != "" {
+			var err error
+			if keys, err = auth.LoadKeyFile(cfg.APIKeysFile); err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := keys.AddSpecs(cfg.APIKeys); err != nil {
+			return nil, nil, err
+		}
+		authenticators = append(authenticators, keys)
+		opts = append(opts, router.WithAPIKeys(handlers.NewKeyHandler(keys)))
+		slog.Info("api key authentication enabled", "keys", keys.Len())
+	}
+
+	if len(authenticators) == 0 {
+		return nil, nil, nil
+	}
+	return authenticators, append(opts, router.WithAuth(handlers.Authenticate(authenticators...))), nil
+}
+
+// newStore opens the store selected by STORE. Tasks a stopped process left
+// unfinished in the sqlite database are canceled, nothing would run them: the
+// pool only knows the tasks submitted to it.
+func newStore(cfg config.Config, logger *slog.Logger) (storepkg.Backend, func() error, error) {
+	switch cfg.Store {
+	case "", "memory":
+		return memory.New(), func() error { return nil }, nil
+	case "sqlite":
+		db, err := sqlite.Open(cfg.SQLitePath, sqlite.WithLogger(logger))
+		if err != nil {
+			return nil, nil, err
+		}
+		n, err := db.CancelUnfinished("interrupted by a restart")
+		if err != nil {
+			_ = db.Close()
+			return nil, nil, err
+		}
+		slog.Info("sqlite store opened", "path", cfg.SQLitePath, "canceled_unfinished", n)
+		return db, db.Close, nil
+	}
+	return nil, nil, fmt.Errorf("unknown STORE %q", cfg.Store)
+}
+
+// newTracer returns nil (tracing off) unless TRACE_EXPORTER selects an exporter.
+func newTracer(cfg config.Config) (*tracing.Tracer, error) {
+	switch cfg.TraceExporter {
+	case "", "none":
+		return nil, nil
+	case "stdout":
+		return tracing.NewTracer(cfg.ServiceName, tracing.NewWriterExporter(os.Stdout)), nil
+	case "file":
+		exporter, err := tracing.NewFileExporter(cfg.TraceFile)
+		if err != nil {
+			return nil, err
+		}
+		return tracing.NewTracer(cfg.ServiceName, exporter), nil
+	case "otlp":
+		return tracing.NewTracer(cfg.ServiceName, tracing.NewOTLPExporter(cfg.OTLPEndpoint, cfg.ServiceName)), nil
+	}
+	return nil, fmt.Errorf("unknown TRACE_EXPORTER %q", cfg.TraceExporter)
 }
@@ -0,0 +1,333 @@
+// Package archive keeps finished tasks out of the live store in gzip-compressed
+// NDJSON files, one directory per completion date (UTC):
+//
+//	<dir>/manifest.json
+//	<dir>/2026-10-18/tasks-0001.ndjson.gz
+//	<dir>/2026-10-18/tasks-0002.ndjson.gz
+//
+// Every write appends a gzip member to the newest file of the date, a file is
+// rotated once it reached the size limit. The manifest lists every file with
+// its date and id range, so a search only opens the files that can match.
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"interview-task-worker-pool/internal/domain"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	manifestName = "manifest.json"
+	dateLayout   = time.DateOnly
+
+	maxLineBytes = 4 << 20 // a record with full metadata stays well below
+)
+
+var ErrEmptyQuery = errors.New("query needs ids or a date range")
+
+// File is a manifest entry.
+type File struct {
+	Path      string    `json:"path"` // relative to the archive directory
+	Date      string    `json:"date"` // completion date of its tasks, YYYY-MM-DD
+	Tasks     int       `json:"tasks"`
+	MinID     int64     `json:"min_id"`
+	MaxID     int64     `json:"max_id"`
+	Bytes     int64     `json:"bytes"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Manifest struct {
+	Files []File `json:"files"`
+}
+
+type Option func(*Archive)
+
+// WithMaxFileBytes sets the size a file is rotated at, 64 MiB by default.
+func WithMaxFileBytes(n int64) Option {
+	return func(a *Archive) {
+		if n > 0 {
+			a.maxFileBytes = n
+		}
+	}
+}
+
+// Archive is a directory of archive files. It is safe for concurrent use
+// within a process, a single process should write to a directory.
+type Archive struct {
+	dir          string
+	maxFileBytes int64
+
+	mu       sync.Mutex
+	manifest Manifest
+}
+
+// Open opens the archive in dir, creating the directory if needed.
+func Open(dir string, opts ...Option) (*Archive, error) {
+	a := &Archive{dir: dir, maxFileBytes: 64 << 20}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, &a.manifest); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", manifestName, err)
+		}
+	}
+	return a, nil
+}
+
+// Manifest returns a copy of the manifest.
+func (a *Archive) Manifest() Manifest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return Manifest{Files: slices.Clone(a.manifest.Files)}
+}
+
+// partition is the completion date a task is filed under.
+func partition(task domain.Task) string {
+	at := task.FinishedAt
+	if at.IsZero() {
+		at = task.CreatedAt
+	}
+	return at.UTC().Format(dateLayout)
+}
+
+// Write appends tasks to the files of their completion dates and updates the
+// manifest. When it fails, some of the tasks may already be written: archived
+// twice is fine, a search returns a task once.
+func (a *Archive) Write(tasks []domain.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byDate := make(map[string][]domain.Task)
+	var dates []string
+	for _, task := range tasks {
+		date := partition(task)
+		if _, ok := byDate[date]; !ok {
+			dates = append(dates, date)
+		}
+		byDate[date] = append(byDate[date], task)
+	}
+	slices.Sort(dates)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, date := range dates {
+		if err := a.append(date, byDate[date]); err != nil {
+			return err
+		}
+	}
+	return a.saveManifest()
+}
+
+// append writes tasks as one gzip member to the newest file of date. A failed
+// append is cut off the file and the manifest left as it was: a partial member
+// in the middle of a file would stop every later read of it.
+// caller must hold a.mu
+func (a *Archive) append(date string, tasks []domain.Task) (err error) {
+	files := slices.Clone(a.manifest.Files)
+	defer func() {
+		if err != nil {
+			a.manifest.Files = files
+		}
+	}()
+
+	i := a.current(date)
+	if i < 0 {
+		part := 1
+		for _, f := range a.manifest.Files {
+			if f.Date == date {
+				part++
+			}
+		}
+		a.manifest.Files = append(a.manifest.Files, File{
+			Path:  filepath.ToSlash(filepath.Join(date, fmt.Sprintf("tasks-%04d.ndjson.gz", part))),
+			Date:  date,
+			MinID: tasks[0].ID,
+			MaxID: tasks[0].ID,
+		})
+		i = len(a.manifest.Files) - 1
+	}
+	entry := &a.manifest.Files[i]
+
+	path := filepath.Join(a.dir, filepath.FromSlash(entry.Path))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	defer func(size int64) {
+		if err != nil {
+			_ = f.Truncate(size)
+		}
+	}(info.Size())
+
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	for _, task := range tasks {
+		if err := enc.Encode(NewRecord(task)); err != nil {
+			return err
+		}
+		entry.MinID = min(entry.MinID, task.ID)
+		entry.MaxID = max(entry.MaxID, task.ID)
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	info, err = f.Stat()
+	if err != nil {
+		return err
+	}
+	entry.Tasks += len(tasks)
+	entry.Bytes = info.Size()
+	entry.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// current is the index of the file of date still taking writes, -1 if a new
+// one has to be started.
+// caller must hold a.mu
+func (a *Archive) current(date string) int {
+	for i := len(a.manifest.Files) - 1; i >= 0; i-- {
+		if a.manifest.Files[i].Date != date {
+			continue
+		}
+		if a.manifest.Files[i].Bytes >= a.maxFileBytes {
+			return -1
+		}
+		return i
+	}
+	return -1
+}
+
+// saveManifest replaces the manifest file, readers never see a partial one.
+// caller must hold a.mu
+func (a *Archive) saveManifest() error {
+	data, err := json.MarshalIndent(a.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(a.dir, manifestName+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(a.dir, manifestName))
+}
+
+// Query selects archived tasks by id, by completion date or both. From and To
+// are inclusive dates (UTC), either may be zero for an open range.
+type Query struct {
+	IDs  []int64
+	From time.Time
+	To   time.Time
+}
+
+func (q Query) empty() bool {
+	return len(q.IDs) == 0 && q.From.IsZero() && q.To.IsZero()
+}
+
+// matches reports whether the file can hold tasks of the query.
+func (q Query) matches(f File) bool {
+	if !q.From.IsZero() && f.Date < q.From.UTC().Format(dateLayout) {
+		return false
+	}
+	if !q.To.IsZero() && f.Date > q.To.UTC().Format(dateLayout) {
+		return false
+	}
+	if len(q.IDs) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(q.IDs, func(id int64) bool { return id >= f.MinID && id <= f.MaxID })
+}
+
+// Search returns the archived tasks matching q by ascending id. A task
+// archived more than once is returned as last written.
+func (a *Archive) Search(q Query) ([]domain.Task, error) {
+	if q.empty() {
+		return nil, ErrEmptyQuery
+	}
+
+	found := make(map[int64]domain.Task)
+	for _, f := range a.Manifest().Files {
+		if !q.matches(f) {
+			continue
+		}
+		err := a.scan(f, func(task domain.Task) {
+			if len(q.IDs) == 0 || slices.Contains(q.IDs, task.ID) {
+				found[task.ID] = task
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tasks := make([]domain.Task, 0, len(found))
+	for _, id := range slices.Sorted(maps.Keys(found)) {
+		tasks = append(tasks, found[id])
+	}
+	return tasks, nil
+}
+
+// scan calls fn with every task of the file.
+func (a *Archive) scan(f File, fn func(domain.Task)) error {
+	file, err := os.Open(filepath.Join(a.dir, filepath.FromSlash(f.Path)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// the reader goes through every gzip member the writes appended, up to the
+	// size in the manifest: a member being appended right now isn't complete
+	zr, err := gzip.NewReader(io.LimitReader(file, f.Bytes))
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.Path, err)
+	}
+	defer zr.Close()
+
+	sc := bufio.NewScanner(zr)
+	sc.Buffer(make([]byte, 64<<10), maxLineBytes)
+	for sc.Scan() {
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+		fn(rec.Task())
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"errors"
+	"interview-task-worker-pool/internal/domain"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, 10, d, 12, 0, 0, 0, time.UTC)
+}
+
+func task(id int64, finished time.Time) domain.Task {
+	return domain.Task{
+		ID: id, Title: "t", Status: domain.StatusDone, Version: 3,
+		Metadata:  map[string]string{"k": "v"},
+		CreatedAt: finished.Add(-time.Minute), FinishedAt: finished,
+	}
+}
+
+func TestWrite_PartitionsByCompletionDate(t *testing.T) {
+	dir := t.TempDir()
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() err=%v, want nil", err)
+	}
+
+	if err := a.Write([]domain.Task{task(1, day(17)), task(2, day(18)), task(3, day(17))}); err != nil {
+		t.Fatalf("Write() err=%v, want nil", err)
+	}
+	if err := a.Write([]domain.Task{task(4, day(17))}); err != nil {
+		t.Fatalf("Write() err=%v, want nil", err)
+	}
+
+	files := a.Manifest().Files
+	if len(files) != 2 {
+		t.Fatalf("manifest files = %+v, want one per date", files)
+	}
+	if f := files[0]; f.Path != "2026-10-17/tasks-0001.ndjson.gz" || f.Tasks != 3 || f.MinID != 1 || f.MaxID != 4 {
+		t.Fatalf("manifest entry = %+v, want 3 tasks with ids 1..4 in 2026-10-17", f)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2026-10-18", "tasks-0001.ndjson.gz")); err != nil {
+		t.Fatalf("archive file of 2026-10-18: %v", err)
+	}
+
+	// both writes (two gzip members) of the same file are read back
+	got, err := a.Search(Query{From: day(17), To: day(17)})
+	if err != nil || len(got) != 3 || got[0].ID != 1 || got[2].ID != 4 {
+		t.Fatalf("Search(2026-10-17) = %+v err=%v, want tasks 1, 3 and 4", got, err)
+	}
+	if got[0].Metadata["k"] != "v" || got[0].Version != 3 || !got[0].FinishedAt.Equal(day(17)) {
+		t.Fatalf("Search() task = %+v, want it as written", got[0])
+	}
+}
+
+func TestWrite_FailedAppendIsCutOff(t *testing.T) {
+	dir := t.TempDir()
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() err=%v, want nil", err)
+	}
+	if err := a.Write([]domain.Task{task(1, day(17))}); err != nil {
+		t.Fatalf("Write() err=%v, want nil", err)
+	}
+	before := a.Manifest().Files[0]
+
+	// the second task can't be encoded, the member is left half written
+	broken := task(3, day(17))
+	broken.CreatedAt = time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := a.Write([]domain.Task{task(2, day(17)), broken}); err == nil {
+		t.Fatal("Write(unencodable) err=nil, want an error")
+	}
+	info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(before.Path)))
+	if err != nil || info.Size() != before.Bytes {
+		t.Fatalf("file size after a failed append = %v err=%v, want %d", info.Size(), err, before.Bytes)
+	}
+	if got := a.Manifest().Files[0]; got != before {
+		t.Fatalf("manifest entry = %+v, want %+v", got, before)
+	}
+
+	if err := a.Write([]domain.Task{task(4, day(17))}); err != nil {
+		t.Fatalf("Write() err=%v, want nil", err)
+	}
+	got, err := a.Search(Query{From: day(17), To: day(17)})
+	if err != nil || len(got) != 2 || got[0].ID != 1 || got[1].ID != 4 {
+		t.Fatalf("Search() = %+v err=%v, want tasks 1 and 4", got, err)
+	}
+}
+
+func TestWrite_RotatesAndReopens(t *testing.T) {
+	dir := t.TempDir()
+	a, _ := Open(dir, WithMaxFileBytes(1))
+
+	for id := int64(1); id <= 3; id++ {
+		if err := a.Write([]domain.Task{task(id, day(18))}); err != nil {
+			t.Fatalf("Write() err=%v, want nil", err)
+		}
+	}
+	if files := a.Manifest().Files; len(files) != 3 || files[2].Path != "2026-10-18/tasks-0003.ndjson.gz" {
+		t.Fatalf("manifest files = %+v, want a file per write", files)
+	}
+
+	// the manifest is read back by a new process
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() err=%v, want nil", err)
+	}
+	got, err := reopened.Search(Query{IDs: []int64{2, 9}})
+	if err != nil || len(got) != 1 || got[0].ID != 2 {
+		t.Fatalf("Search(ids 2, 9) = %+v err=%v, want task 2", got, err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	a, _ := Open(t.TempDir())
+	_ = a.Write([]domain.Task{task(1, day(16)), task(2, day(17)), task(3, day(18))})
+
+	// archived again, e.g. after a restore, the last copy wins
+	again := task(2, day(17))
+	again.Version = 5
+	_ = a.Write([]domain.Task{again})
+
+	got, _ := a.Search(Query{From: day(17)})
+	if len(got) != 2 || got[0].ID != 2 || got[0].Version != 5 || got[1].ID != 3 {
+		t.Fatalf("Search(from 2026-10-17) = %+v, want task 2 (version 5) and 3", got)
+	}
+	got, _ = a.Search(Query{IDs: []int64{1, 3}, To: day(17)})
+	if len(got) != 1 || got[0].ID != 1 {
+		t.Fatalf("Search(ids 1, 3 to 2026-10-17) = %+v, want task 1", got)
+	}
+	if _, err := a.Search(Query{}); !errors.Is(err, ErrEmptyQuery) {
+		t.Fatalf("Search(empty) err=%v, want %v", err, ErrEmptyQuery)
+	}
+}
@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"interview-task-worker-pool/internal/domain"
+	"time"
+)
+
+// Record is a task as stored in an archive file, one JSON object per line.
+type Record struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Status      string `json:"status"`
+
+	Priority int               `json:"priority,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Version  int64             `json:"version"`
+
+	Owner            string  `json:"owner,omitempty"`
+	DedupeKey        string  `json:"dedupe_key,omitempty"`
+	ConcurrencyKey   string  `json:"concurrency_key,omitempty"`
+	ConcurrencyLimit int     `json:"concurrency_limit,omitempty"`
+	DependsOn        []int64 `json:"depends_on,omitempty"`
+	BatchID          int64   `json:"batch_id,omitempty"`
+
+	TraceParent string `json:"trace_parent,omitempty"`
+	RequestID   string `json:"request_id,omitempty"`
+
+	CreatedAt    time.Time     `json:"created_at"`
+	FinishedAt   time.Time     `json:"finished_at,omitzero"`
+	RetainUntil  time.Time     `json:"retain_until,omitzero"`
+	WorkDuration time.Duration `json:"work_duration_ns,omitempty"`
+}
+
+func NewRecord(task domain.Task) Record {
+	return Record{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Error:       task.Error,
+		Status:      string(task.Status),
+
+		Priority: task.Priority,
+		Metadata: task.Metadata,
+		Version:  task.Version,
+
+		Owner:            task.Owner,
+		DedupeKey:        task.DedupeKey,
+		ConcurrencyKey:   task.ConcurrencyKey,
+		ConcurrencyLimit: task.ConcurrencyLimit,
+		DependsOn:        task.DependsOn,
+		BatchID:          task.BatchID,
+
+		TraceParent: task.TraceParent,
+		RequestID:   task.RequestID,
+
+		CreatedAt:    task.CreatedAt,
+		FinishedAt:   task.FinishedAt,
+		RetainUntil:  task.RetainUntil,
+		WorkDuration: task.WorkDuration,
+	}
+}
+
+func (r Record) Task() domain.Task {
+	return domain.Task{
+		ID:          r.ID,
+		Title:       r.Title,
+		Description: r.Description,
+		Error:       r.Error,
+		Status:      domain.TaskStatus(r.Status),
+
+		Priority: r.Priority,
+		Metadata: r.Metadata,
+		Version:  r.Version,
+
+		Owner:            r.Owner,
+		DedupeKey:        r.DedupeKey,
+		ConcurrencyKey:   r.ConcurrencyKey,
+		ConcurrencyLimit: r.ConcurrencyLimit,
+		DependsOn:        r.DependsOn,
+		BatchID:          r.BatchID,
+
+		TraceParent: r.TraceParent,
+		RequestID:   r.RequestID,
+
+		CreatedAt:    r.CreatedAt,
+		FinishedAt:   r.FinishedAt,
+		RetainUntil:  r.RetainUntil,
+		WorkDuration: r.WorkDuration,
+	}
+}
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyStore_AddSpecs(t *testing.T) {
+	ks := NewKeyStore()
+	if err := ks.AddSpecs("alice:a-key, ops:o-key:admin|tasks"); err != nil {
+		t.Fatalf("AddSpecs() err=%v, want nil", err)
+	}
+
+	p, err := ks.Authenticate("o-key")
+	if err != nil || p.ID != "ops" || !p.IsAdmin() || !p.Has("tasks") {
+		t.Fatalf("Authenticate() principal=%+v err=%v", p, err)
+	}
+	if p, _ := ks.Authenticate("a-key"); p.ID != "alice" || p.IsAdmin() {
+		t.Fatalf("Authenticate() principal=%+v, want alice without admin", p)
+	}
+	_ = ks.AddSpecs("bob:b-key:tasks:read|tasks:cancel")
+	if p, _ := ks.Authenticate("b-key"); !p.Has(ScopeTasksRead) || !p.Has(ScopeTasksCancel) || p.Has(ScopeTasksCreate) {
+		t.Fatalf("Authenticate() principal=%+v, want bob with the read and cancel scopes", p)
+	}
+	if _, err := ks.Authenticate("nope"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Authenticate() err=%v, want %v", err, ErrInvalidKey)
+	}
+
+	err = ks.AddSpecs("bob:b-key,missing-secret")
+	if !errors.Is(err, ErrInvalidSpec) || strings.Contains(err.Error(), "missing-secret") {
+		t.Fatalf("AddSpecs() err=%v, want %v without the spec", err, ErrInvalidSpec)
+	}
+}
+
+func TestKeyStore_CreateRevokeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	ks, err := LoadKeyFile(path)
+	if err != nil || ks.Len() != 0 {
+		t.Fatalf("LoadKeyFile(missing) len=%d err=%v, want empty store", ks.Len(), err)
+	}
+
+	secret, key, err := ks.Create("alice", []Scope{ScopeAdmin})
+	if err != nil {
+		t.Fatalf("Create() err=%v, want nil", err)
+	}
+	if !strings.HasPrefix(secret, keyPrefix) || key.Owner != "alice" {
+		t.Fatalf("Create() secret=%q key=%+v", secret, key)
+	}
+
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), secret) || !strings.Contains(string(raw), key.Hash) {
+		t.Fatalf("keys file=%s, want the hash and not the secret", raw)
+	}
+
+	reloaded, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyFile() err=%v, want nil", err)
+	}
+	if p, err := reloaded.Authenticate(secret); err != nil || p.ID != "alice" || !p.IsAdmin() {
+		t.Fatalf("Authenticate() after reload principal=%+v err=%v", p, err)
+	}
+
+	if err := reloaded.Revoke(key.ID); err != nil {
+		t.Fatalf("Revoke() err=%v, want nil", err)
+	}
+	if err := reloaded.Revoke(key.ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("Revoke() twice err=%v, want %v", err, ErrKeyNotFound)
+	}
+	if again, _ := LoadKeyFile(path); again.Len() != 0 {
+		t.Fatalf("keys after revoke=%d, want 0", again.Len())
+	}
+}
+
+func TestLoadKeyFile_PlainKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(`[{"owner":"bob","key":"b-key"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatalf("LoadKeyFile() err=%v, want nil", err)
+	}
+	if p, err := ks.Authenticate("b-key"); err != nil || p.ID != "bob" {
+		t.Fatalf("Authenticate() principal=%+v err=%v", p, err)
+	}
+}
+
+func TestKeyStore_AuthenticateRequest(t *testing.T) {
+	ks := NewKeyStore()
+	_ = ks.AddSpecs("alice:a-key")
+
+	req := httptest.NewRequest("GET", "/tasks", nil)
+	if _, err := ks.AuthenticateRequest(req); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("AuthenticateRequest() err=%v, want %v", err, ErrNoCredentials)
+	}
+
+	req.Header.Set("X-API-Key", "a-key")
+	if p, err := ks.AuthenticateRequest(req); err != nil || p.ID != "alice" {
+		t.Fatalf("AuthenticateRequest(X-API-Key) principal=%+v err=%v", p, err)
+	}
+
+	req = httptest.NewRequest("GET", "/tasks", nil)
+	req.Header.Set("Authorization", "Bearer a-key")
+	if p, err := ks.AuthenticateRequest(req); err != nil || p.ID != "alice" {
+		t.Fatalf("AuthenticateRequest(Bearer) principal=%+v err=%v", p, err)
+	}
+}
+
+func b64(v any) string {
+	raw, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func signHS256(secret []byte, header, claims map[string]any) string {
+	signed := b64(header) + "." + b64(claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, header, claims map[string]any) string {
+	t.Helper()
+	signed := b64(header) + "." + b64(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWT_HS256Claims(t *testing.T) {
+	secret := []byte("hs-secret")
+	jwks := NewJWKS()
+	jwks.AddHMAC("", secret)
+
+	a := NewJWTAuthenticator(JWTConfig{Issuer: "issuer", Audience: "tasks-api", Keys: jwks})
+	now := time.Unix(1_700_000_000, 0)
+	a.now = func() time.Time { return now }
+
+	hs := map[string]any{"alg": "HS256", "typ": "JWT"}
+	claims := func(extra map[string]any) map[string]any {
+		c := map[string]any{"sub": "svc-a", "iss": "issuer", "aud": []string{"other", "tasks-api"},
+			"exp": now.Add(time.Minute).Unix(), "scope": "tasks:read tasks:create"}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	p, err := a.Authenticate(signHS256(secret, hs, claims(nil)))
+	if err != nil || p.ID != "svc-a" || !p.Has(ScopeTasksCreate) || p.Has(ScopeTasksCancel) {
+		t.Fatalf("Authenticate() principal=%+v err=%v", p, err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"bad signature", signHS256([]byte("other"), hs, claims(nil))},
+		{"expired", signHS256(secret, hs, claims(map[string]any{"exp": now.Add(-time.Second).Unix()}))},
+		{"missing exp", signHS256(secret, hs, claims(map[string]any{"exp": nil}))},
+		{"not valid yet", signHS256(secret, hs, claims(map[string]any{"nbf": now.Add(time.Minute).Unix()}))},
+		{"wrong issuer", signHS256(secret, hs, claims(map[string]any{"iss": "someone"}))},
+		{"wrong audience", signHS256(secret, hs, claims(map[string]any{"aud": "other"}))},
+		{"missing sub", signHS256(secret, hs, claims(map[string]any{"sub": ""}))},
+		{"alg none", b64(map[string]any{"alg": "none"}) + "." + b64(claims(nil)) + "."},
+		{"unknown kid", signHS256(secret, map[string]any{"alg": "HS256", "kid": "x"}, claims(nil))},
+	}
+	for _, tt := range tests {
+		if _, err := a.Authenticate(tt.token); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("%s: Authenticate() err=%v, want %v", tt.name, err, ErrInvalidToken)
+		}
+	}
+}
+
+func TestJWT_RS256FromJWKSFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]any{"keys": []map[string]any{{
+		"kty": "RSA", "kid": "k1", "use": "sig",
+		"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	raw, _ := json.Marshal(set)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatalf("LoadJWKS() err=%v, want nil", err)
+	}
+	a := NewJWTAuthenticator(JWTConfig{Keys: jwks})
+
+	claims := map[string]any{"sub": "svc-b", "exp": time.Now().Add(time.Minute).Unix(), "scp": []string{"admin"}}
+	token := signRS256(t, key, map[string]any{"alg": "RS256", "kid": "k1"}, claims)
+
+	req := httptest.NewRequest("GET", "/tasks", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	p, err := a.AuthenticateRequest(req)
+	if err != nil || p.ID != "svc-b" || !p.IsAdmin() || !p.Has(ScopeTasksCancel) {
+		t.Fatalf("AuthenticateRequest() principal=%+v err=%v", p, err)
+	}
+
+	// the RSA key must not verify an HS256 token signed with its public bytes
+	forged := signHS256(key.N.Bytes(), map[string]any{"alg": "HS256", "kid": "k1"}, claims)
+	if _, err := a.Authenticate(forged); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Authenticate(alg switch) err=%v, want %v", err, ErrInvalidToken)
+	}
+
+	// an API key in the bearer header is left to the next authenticator
+	req.Header.Set("Authorization", "Bearer twp_abc")
+	if _, err := a.AuthenticateRequest(req); !errors.Is(err, ErrNoCredentials) {
+		t.Fatalf("AuthenticateRequest(api key) err=%v, want %v", err, ErrNoCredentials)
+	}
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWTConfig configures JWT validation. Issuer and Audience are only checked
+// when set.
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	Keys     *JWKS
+	Leeway   time.Duration // clock skew allowed on exp/nbf
+}
+
+// JWKS holds the verification keys by kid: RSA keys for RS256, symmetric
+// ("oct") keys for HS256.
+type JWKS struct {
+	rsa  map[string]*rsa.PublicKey
+	hmac map[string][]byte
+}
+
+func NewJWKS() *JWKS {
+	return &JWKS{rsa: make(map[string]*rsa.PublicKey), hmac: make(map[string][]byte)}
+}
+
+// AddHMAC adds an HS256 secret under kid, "" matches tokens without a kid.
+func (ks *JWKS) AddHMAC(kid string, secret []byte) {
+	ks.hmac[kid] = secret
+}
+
+// AddRSA adds an RS256 public key under kid, "" matches tokens without a kid.
+func (ks *JWKS) AddRSA(kid string, key *rsa.PublicKey) {
+	ks.rsa[kid] = key
+}
+
+func (ks *JWKS) Len() int {
+	return len(ks.rsa) + len(ks.hmac)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// LoadJWKS reads a JWK Set ({"keys":[...]}) from a local file. Keys with a
+// "use" other than "sig" are skipped.
+func LoadJWKS(path string) (*JWKS, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("jwks %s: %w", path, err)
+	}
+
+	ks := NewJWKS()
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("jwks %s: key %d: malformed RSA key", path, i)
+			}
+			ks.AddRSA(k.Kid, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("jwks %s: key %d: malformed oct key", path, i)
+			}
+			ks.AddHMAC(k.Kid, secret)
+		default:
+			return nil, fmt.Errorf("jwks %s: key %d: unsupported kty %q", path, i, k.Kty)
+		}
+	}
+	return ks, nil
+}
+
+// JWTAuthenticator accepts HS256 and RS256 bearer tokens. The subject becomes
+// the principal, the "scope" (space separated) or "scp" claim its scopes.
+type JWTAuthenticator struct {
+	cfg JWTConfig
+	now func() time.Time
+}
+
+func NewJWTAuthenticator(cfg JWTConfig) *JWTAuthenticator {
+	if cfg.Keys == nil {
+		cfg.Keys = NewJWKS()
+	}
+	return &JWTAuthenticator{cfg: cfg, now: time.Now}
+}
+
+// AuthenticateRequest only looks at bearer tokens shaped like a JWT, other
+// bearer tokens are left to the next authenticator (API keys).
+func (a *JWTAuthenticator) AuthenticateRequest(r *http.Request) (Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok || strings.Count(token, ".") != 2 {
+		return Principal{}, ErrNoCredentials
+	}
+	return a.Authenticate(token)
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	Scope     string          `json:"scope"`
+	Scp       []string        `json:"scp"`
+}
+
+// Authenticate verifies the signature and the claims of token.
+func (a *JWTAuthenticator) Authenticate(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	if err := a.verify(header, parts[0]+"."+parts[1], sig); err != nil {
+		return Principal{}, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := a.validate(claims); err != nil {
+		return Principal{}, err
+	}
+
+	p := Principal{ID: claims.Subject}
+	for _, s := range strings.Fields(claims.Scope) {
+		p.Scopes = append(p.Scopes, Scope(s))
+	}
+	for _, s := range claims.Scp {
+		p.Scopes = append(p.Scopes, Scope(s))
+	}
+	return p, nil
+}
+
+// verify checks the signature with a key of the type the alg calls for, an
+// RSA public key is never used as an HMAC secret.
+func (a *JWTAuthenticator) verify(header jwtHeader, signed string, sig []byte) error {
+	switch header.Alg {
+	case "HS256":
+		secret, ok := a.cfg.Keys.hmac[header.Kid]
+		if !ok {
+			return fmt.Errorf("%w: unknown key", ErrInvalidToken)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	case "RS256":
+		key, ok := a.cfg.Keys.rsa[header.Kid]
+		if !ok {
+			return fmt.Errorf("%w: unknown key", ErrInvalidToken)
+		}
+		digest := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, header.Alg)
+	}
+	return nil
+}
+
+func (a *JWTAuthenticator) validate(claims jwtClaims) error {
+	now := a.now()
+
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if !now.Before(time.Unix(*claims.ExpiresAt, 0).Add(a.cfg.Leeway)) {
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if claims.NotBefore != nil && now.Add(a.cfg.Leeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+	if a.cfg.Issuer != "" && claims.Issuer != a.cfg.Issuer {
+		return fmt.Errorf("%w: wrong issuer", ErrInvalidToken)
+	}
+	if a.cfg.Audience != "" && !hasAudience(claims.Audience, a.cfg.Audience) {
+		return fmt.Errorf("%w: wrong audience", ErrInvalidToken)
+	}
+	return nil
+}
+
+// hasAudience accepts aud as a single string or an array of strings.
+func hasAudience(raw json.RawMessage, want string) bool {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return one == want
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, aud := range many {
+			if aud == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
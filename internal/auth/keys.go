@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrKeyNotFound = errors.New("api key not found")
+	ErrInvalidKey  = errors.New("invalid api key")
+	ErrInvalidSpec = errors.New("invalid api key spec")
+)
+
+const keyPrefix = "twp_"
+
+// APIKey is a stored key. Only the SHA-256 of the secret is kept, the secret
+// itself is shown once, when the key is created.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	Scopes    []Scope   `json:"scopes,omitempty"`
+	Hash      string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// KeyStore holds the API keys by hash. With a path, changes are written back
+// to the keys file.
+type KeyStore struct {
+	path string
+
+	mu     sync.RWMutex
+	byHash map[string]APIKey
+}
+
+func NewKeyStore() *KeyStore {
+	return &KeyStore{byHash: make(map[string]APIKey)}
+}
+
+// keyFileEntry is a line of the keys file: either the hash of a key or, for
+// hand-written files, the plain key which is hashed on load.
+type keyFileEntry struct {
+	APIKey
+	Key string `json:"key,omitempty"`
+}
+
+// LoadKeyFile reads a JSON array of keys and keeps path for later writes.
+// A missing file is an empty store.
+func LoadKeyFile(path string) (*KeyStore, error) {
+	ks := NewKeyStore()
+	ks.path = path
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []keyFileEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("keys file %s: %w", path, err)
+	}
+	for i, e := range entries {
+		key := e.APIKey
+		if e.Key != "" {
+			key.Hash = hashKey(e.Key)
+		}
+		if key.Owner == "" || len(key.Hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("keys file %s: entry %d: %w", path, i, ErrInvalidSpec)
+		}
+		if key.ID == "" {
+			key.ID = key.Hash[:12]
+		}
+		ks.byHash[key.Hash] = key
+	}
+	return ks, nil
+}
+
+// AddSpecs adds keys written as "owner:key[:scope|scope]", comma separated,
+// e.g. from the API_KEYS variable.
+func (ks *KeyStore) AddSpecs(specs string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	for i, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		// the error names the position only, the spec holds a secret.
+		// scopes have colons themselves (tasks:read), the split stops before them
+		parts := strings.SplitN(spec, ":", 3)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("%w: entry %d", ErrInvalidSpec, i)
+		}
+
+		key := APIKey{Owner: parts[0], Hash: hashKey(parts[1]), CreatedAt: time.Now()}
+		key.ID = key.Hash[:12]
+		if len(parts) == 3 {
+			for _, s := range strings.Split(parts[2], "|") {
+				if s != "" {
+					key.Scopes = append(key.Scopes, Scope(s))
+				}
+			}
+		}
+		ks.byHash[key.Hash] = key
+	}
+	return nil
+}
+
+// Create makes a new key for owner and returns its secret, which isn't stored.
+func (ks *KeyStore) Create(owner string, scopes []Scope) (string, APIKey, error) {
+	owner = strings.TrimSpace(owner)
+	if owner == "" {
+		return "", APIKey{}, ErrInvalidSpec
+	}
+
+	var b [24]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", APIKey{}, err
+	}
+	secret := keyPrefix + hex.EncodeToString(b[:])
+
+	key := APIKey{Owner: owner, Scopes: scopes, Hash: hashKey(secret), CreatedAt: time.Now().UTC()}
+	key.ID = key.Hash[:12]
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.byHash[key.Hash] = key
+	if err := ks.save(); err != nil {
+		delete(ks.byHash, key.Hash)
+		return "", APIKey{}, err
+	}
+	return secret, key, nil
+}
+
+// Revoke deletes the key with the given id.
+func (ks *KeyStore) Revoke(id string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	for hash, key := range ks.byHash {
+		if key.ID == id {
+			delete(ks.byHash, hash)
+			if err := ks.save(); err != nil {
+				ks.byHash[hash] = key
+				return err
+			}
+			return nil
+		}
+	}
+	return ErrKeyNotFound
+}
+
+// List returns the keys by owner then id, without secrets.
+func (ks *KeyStore) List() []APIKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(ks.byHash))
+	for _, key := range ks.byHash {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Owner != keys[j].Owner {
+			return keys[i].Owner < keys[j].Owner
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+func (ks *KeyStore) Len() int {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return len(ks.byHash)
+}
+
+// AuthenticateRequest takes the key from X-API-Key or "Authorization: Bearer".
+func (ks *KeyStore) AuthenticateRequest(r *http.Request) (Principal, error) {
+	secret := r.Header.Get("X-API-Key")
+	if secret == "" {
+		secret, _ = bearerToken(r)
+	}
+	if secret == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	return ks.Authenticate(secret)
+}
+
+// Authenticate returns the principal the secret belongs to. The lookup is by
+// hash, so the secret is never compared directly.
+func (ks *KeyStore) Authenticate(secret string) (Principal, error) {
+	ks.mu.RLock()
+	key, ok := ks.byHash[hashKey(secret)]
+	ks.mu.RUnlock()
+
+	if !ok {
+		return Principal{}, ErrInvalidKey
+	}
+
+	scopes := key.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	return Principal{ID: key.Owner, Scopes: scopes}, nil
+}
+
+// save writes the keys file, only hashes end up on disk. Called with mu held.
+func (ks *KeyStore) save() error {
+	if ks.path == "" {
+		return nil
+	}
+
+	keys := make([]APIKey, 0, len(ks.byHash))
+	for _, key := range ks.byHash {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	raw, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// written next to the file and renamed, a crash never leaves half a file
+	tmp := ks.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ks.path)
+}
+
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrNoCredentials means the request carries nothing an Authenticator understands.
+var ErrNoCredentials = errors.New("missing credentials")
+
+// Authenticator checks the credentials of a request. It returns ErrNoCredentials
+// when there are none of its kind, so the next authenticator can be tried.
+type Authenticator interface {
+	AuthenticateRequest(r *http.Request) (Principal, error)
+}
+
+type Scope string
+
+const (
+	ScopeTasksCreate Scope = "tasks:create"
+	ScopeTasksRead   Scope = "tasks:read"
+	ScopeTasksCancel Scope = "tasks:cancel"
+
+	// ScopeAdmin grants every other scope, sees every client's tasks and
+	// manages API keys.
+	ScopeAdmin Scope = "admin"
+)
+
+// DefaultScopes are given to API keys created without scopes.
+var DefaultScopes = []Scope{ScopeTasksCreate, ScopeTasksRead, ScopeTasksCancel}
+
+// Principal is the authenticated caller. ID becomes the owner of the tasks it creates.
+type Principal struct {
+	ID     string
+	Scopes []Scope
+}
+
+func (p Principal) Has(scope Scope) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+func (p Principal) IsAdmin() bool {
+	return p.Has(ScopeAdmin)
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller, ok is false when the request wasn't
+// authenticated (auth turned off).
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return strings.TrimSpace(token), ok && strings.TrimSpace(token) != ""
+}
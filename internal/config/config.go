@@ -12,9 +12,59 @@ import (
 
 type Config struct {
 	HTTPPort        string
+	GRPCPort        string
 	Workers         int
 	PoolSize        int
+	TenantWeights   map[string]int // fair queue share per task owner, 1 by default
 	ShutdownTimeout time.Duration
+	IdempotencyTTL  time.Duration
+	DedupePolicy    string
+
+	Store      string // memory or sqlite
+	SQLitePath string // database file of the sqlite store
+
+	ServiceName   string
+	TraceExporter string // none, stdout, file or otlp
+	TraceFile     string
+	OTLPEndpoint  string
+
+	LogLevel  string // debug, info, warn or error
+	LogFormat string // json or text
+
+	TaskLogMaxLines int // per task execution log
+	TaskLogMaxBytes int
+
+	// /readyz fails when the queue is ReadyQueueThreshold full for ReadySaturationPeriod
+	ReadyQueueThreshold   float64
+	ReadySaturationPeriod time.Duration
+
+	AdminToken string // the /admin API is off when empty
+
+	// authentication is on when API keys or JWT keys are set
+	APIKeys     string // owner:key[:scope|scope],...
+	APIKeysFile string
+
+	JWTSecret   string // HS256 secret for tokens without a kid
+	JWTJWKSFile string // local JWK Set with RS256/HS256 keys
+	JWTIssuer   string // exp/nbf are always checked, iss/aud when set
+	JWTAudience string
+
+	// finished tasks are evicted after the TTL of their status (zero keeps them)
+	// or, past MaxTasks stored tasks, oldest first (zero is no cap)
+	RetainDone        time.Duration
+	RetainFailed      time.Duration
+	RetainCanceled    time.Duration
+	MaxTasks          int
+	RetentionInterval time.Duration
+	RetentionBatch    int // tasks looked at per store lock
+
+	// evicted tasks are archived to ArchiveDir first, off when empty
+	ArchiveDir          string
+	ArchiveMaxFileBytes int64 // an archive file is rotated at this size
+
+	// per client rate limit and pending task quota, off unless tiers are set
+	RateLimitTiers string // name=rate:burst:max_pending,...
+	ClientTiers    string // client=tier,...
 }
 
 func New() Config {
@@ -22,14 +72,42 @@ func New() Config {
 
 	cfg := Config{
 		HTTPPort:        ":8080",
+		GRPCPort:        ":9090",
 		Workers:         5,
 		PoolSize:        10,
 		ShutdownTimeout: time.Second * 10,
+		IdempotencyTTL:  time.Hour * 24,
+		DedupePolicy:    "return_existing",
+		Store:           "memory",
+		SQLitePath:      "tasks.db",
+		ServiceName:     "task-worker-pool",
+		TraceExporter:   "none",
+		TraceFile:       "traces.jsonl",
+		OTLPEndpoint:    "http://localhost:4318",
+		LogLevel:        "info",
+		LogFormat:       "json",
+		TaskLogMaxLines: 1000,
+		TaskLogMaxBytes: 256 << 10,
+
+		ReadyQueueThreshold:   0.9,
+		ReadySaturationPeriod: time.Second * 30,
+
+		RetainDone:        time.Hour * 24,
+		RetainFailed:      time.Hour * 24 * 7,
+		RetainCanceled:    time.Hour * 24,
+		MaxTasks:          100000,
+		RetentionInterval: time.Minute,
+		RetentionBatch:    100,
+
+		ArchiveMaxFileBytes: 64 << 20,
 	}
 
 	if v := strings.TrimSpace(os.Getenv("HTTP_PORT")); v != "" {
 		cfg.HTTPPort = fmt.Sprintf(":%s", v)
 	}
+	if v := strings.TrimSpace(os.Getenv("GRPC_PORT")); v != "" {
+		cfg.GRPCPort = fmt.Sprintf(":%s", v)
+	}
 	if v := strings.TrimSpace(os.Getenv("WORKERS")); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
 			cfg.Workers = n
@@ -40,12 +118,145 @@ func New() Config {
 			cfg.PoolSize = n
 		}
 	}
+	if v := strings.TrimSpace(os.Getenv("TENANT_WEIGHTS")); v != "" {
+		cfg.TenantWeights = parseWeights(v)
+	}
 	if v := strings.TrimSpace(os.Getenv("SHUTDOWN_TIMEOUT")); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
 			cfg.ShutdownTimeout = time.Duration(n) * time.Second
 		}
 	}
+	if v := strings.TrimSpace(os.Getenv("IDEMPOTENCY_TTL")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.IdempotencyTTL = time.Duration(n) * time.Second
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("DEDUPE_POLICY")); v != "" {
+		cfg.DedupePolicy = v
+	}
+	if v := strings.TrimSpace(os.Getenv("STORE")); v != "" {
+		cfg.Store = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("SQLITE_PATH")); v != "" {
+		cfg.SQLitePath = v
+	}
+	if v := strings.TrimSpace(os.Getenv("SERVICE_NAME")); v != "" {
+		cfg.ServiceName = v
+	}
+	if v := strings.TrimSpace(os.Getenv("TRACE_EXPORTER")); v != "" {
+		cfg.TraceExporter = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("TRACE_FILE")); v != "" {
+		cfg.TraceFile = v
+	}
+	if v := strings.TrimSpace(os.Getenv("OTLP_ENDPOINT")); v != "" {
+		cfg.OTLPEndpoint = v
+	}
+	if v := strings.TrimSpace(os.Getenv("LOG_LEVEL")); v != "" {
+		cfg.LogLevel = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("LOG_FORMAT")); v != "" {
+		cfg.LogFormat = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("TASK_LOG_MAX_LINES")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.TaskLogMaxLines = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("TASK_LOG_MAX_BYTES")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.TaskLogMaxBytes = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("READY_QUEUE_THRESHOLD")); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 && f <= 1 {
+			cfg.ReadyQueueThreshold = f
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("READY_SATURATION_PERIOD")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.ReadySaturationPeriod = time.Duration(n) * time.Second
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("ADMIN_TOKEN")); v != "" {
+		cfg.AdminToken = v
+	}
+	if v := strings.TrimSpace(os.Getenv("API_KEYS")); v != "" {
+		cfg.APIKeys = v
+	}
+	if v := strings.TrimSpace(os.Getenv("API_KEYS_FILE")); v != "" {
+		cfg.APIKeysFile = v
+	}
+	if v := strings.TrimSpace(os.Getenv("JWT_HS256_SECRET")); v != "" {
+		cfg.JWTSecret = v
+	}
+	if v := strings.TrimSpace(os.Getenv("JWT_JWKS_FILE")); v != "" {
+		cfg.JWTJWKSFile = v
+	}
+	if v := strings.TrimSpace(os.Getenv("JWT_ISSUER")); v != "" {
+		cfg.JWTIssuer = v
+	}
+	if v := strings.TrimSpace(os.Getenv("JWT_AUDIENCE")); v != "" {
+		cfg.JWTAudience = v
+	}
+	if v := strings.TrimSpace(os.Getenv("RETAIN_DONE")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.RetainDone = time.Duration(n) * time.Second
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("RETAIN_FAILED")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.RetainFailed = time.Duration(n) * time.Second
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("RETAIN_CANCELED")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.RetainCanceled = time.Duration(n) * time.Second
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("MAX_TASKS")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.MaxTasks = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("RETENTION_INTERVAL")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.RetentionInterval = time.Duration(n) * time.Second
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("RETENTION_BATCH")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.RetentionBatch = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("ARCHIVE_DIR")); v != "" {
+		cfg.ArchiveDir = v
+	}
+	if v := strings.TrimSpace(os.Getenv("ARCHIVE_MAX_FILE_BYTES")); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			cfg.ArchiveMaxFileBytes = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("RATE_LIMIT_TIERS")); v != "" {
+		cfg.RateLimitTiers = v
+	}
+	if v := strings.TrimSpace(os.Getenv("CLIENT_TIERS")); v != "" {
+		cfg.ClientTiers = v
+	}
 
 	return cfg
 
 }
+
+// parseWeights reads "tenant=weight" pairs, comma separated. Malformed pairs and
+// weights below 1 are skipped, those tenants keep the default weight.
+func parseWeights(v string) map[string]int {
+	weights := make(map[string]int)
+	for _, pair := range strings.Split(v, ",") {
+		tenant, weight, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if n, err := strconv.Atoi(weight); ok && tenant != "" && err == nil && n > 0 {
+			weights[tenant] = n
+		}
+	}
+	return weights
+}
@@ -0,0 +1,28 @@
+package dag
+
+import (
+	"errors"
+	"interview-task-worker-pool/internal/domain"
+)
+
+var (
+	ErrUnknownDependency = errors.New("unknown dependency")
+	ErrDependencyFailed  = errors.New("dependency failed or was canceled")
+	ErrHasDependents     = errors.New("blocked tasks depend on the task")
+)
+
+// Check validates the dependencies of a task about to be created: each one must
+// exist and still be able to succeed. Ids only go up and depends_on can only name
+// tasks that already exist, so the new edges can't close a cycle.
+func (r *Resolver) Check(deps []int64) error {
+	for _, id := range deps {
+		task, ok := r.store.Get(id)
+		if !ok {
+			return ErrUnknownDependency
+		}
+		if task.Status == domain.StatusFailed || task.Status == domain.StatusCanceled {
+			return ErrDependencyFailed
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package dag
+
+import (
+	"fmt"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/store"
+	"interview-task-worker-pool/internal/workerpool"
+	"slices"
+	"sync"
+)
+
+type Store interface {
+	Get(id int64) (domain.Task, bool)
+	Find(filter store.Filter) ([]domain.Task, error)
+	UpdateStatus(id int64, status domain.TaskStatus) (domain.Task, error)
+	Fail(id int64, reason string) (domain.Task, error)
+	Cancel(id int64, version int64, reason string) (domain.Task, error)
+	Delete(id int64, version int64) (domain.Task, error)
+}
+
+// Resolver keeps blocked tasks out of the pool until all of their parents are done.
+// A parent that fails or gets canceled takes its blocked descendants down with it.
+type Resolver struct {
+	mu       sync.Mutex
+	store    Store
+	pool     workerpool.TaskPool
+	children map[int64][]int64 // parent id -> blocked tasks waiting on it
+}
+
+func NewResolver(store Store, pool workerpool.TaskPool) *Resolver {
+	return &Resolver{
+		store:    store,
+		pool:     pool,
+		children: make(map[int64][]int64),
+	}
+}
+
+// Register starts tracking a task created as blocked. Parents may have finished
+// since the task was validated, so they are checked again under the lock: the task
+// is released right away when all of them are done, or failed/canceled when one of
+// them is. An immediate release is enqueued on pool, e.g. a batch's reservation,
+// the returned error is its enqueue error.
+func (r *Resolver) Register(task domain.Task, pool workerpool.TaskPool) (domain.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ready := true
+	for _, parentID := range task.DependsOn {
+		parent, ok := r.store.Get(parentID)
+		if !ok {
+			return r.settle(task.ID, domain.StatusFailed, fmt.Sprintf("dependency %d not found", parentID))
+		}
+		switch parent.Status {
+		case domain.StatusDone:
+			continue
+		case domain.StatusFailed, domain.StatusCanceled:
+			return r.settle(task.ID, parent.Status, fmt.Sprintf("dependency %d %s", parentID, parent.Status))
+		}
+		ready = false
+		r.children[parentID] = append(r.children[parentID], task.ID)
+	}
+
+	if !ready {
+		return task, nil
+	}
+	return r.release(task.ID, pool)
+}
+
+// Resolve is called once a task reached a terminal status.
+func (r *Resolver) Resolve(id int64, status domain.TaskStatus) {
+	if !status.Terminal() {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resolve(id, status)
+}
+
+// Cancel cancels a task that hasn't finished yet, its blocked descendants are
+// canceled with it. A non-zero version must be the task's current one.
+func (r *Resolver) Cancel(id int64, version int64, reason string) (domain.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	task, err := r.store.Cancel(id, version, reason)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	r.resolve(id, domain.StatusCanceled)
+	return task, nil
+}
+
+// Delete deletes a finished task unless blocked tasks still wait on it, they
+// would never see it done (ErrHasDependents). Under the lock a task registering
+// meanwhile either is found here or finds the task gone.
+func (r *Resolver) Delete(id int64, version int64) (domain.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	blocked, err := r.store.Find(store.Filter{Status: domain.StatusBlocked})
+	if err != nil {
+		return domain.Task{}, err
+	}
+	for _, task := range blocked {
+		if slices.Contains(task.DependsOn, id) {
+			return domain.Task{}, ErrHasDependents
+		}
+	}
+	return r.store.Delete(id, version)
+}
+
+// caller must hold r.mu
+func (r *Resolver) resolve(id int64, status domain.TaskStatus) {
+	children := r.children[id]
+	delete(r.children, id)
+
+	for _, childID := range children {
+		child, ok := r.store.Get(childID)
+		if !ok || child.Status != domain.StatusBlocked {
+			continue
+		}
+
+		if status != domain.StatusDone {
+			_, _ = r.settle(childID, status, fmt.Sprintf("dependency %d %s", id, status))
+			continue
+		}
+		if r.parentsDone(child) {
+			_, _ = r.release(childID, r.pool)
+		}
+	}
+}
+
+// parentsDone reports whether every parent of task is done. A parent that's gone
+// counts as done: a failed or canceled one already canceled its blocked
+// children, so only a done one can have been evicted under a blocked child.
+func (r *Resolver) parentsDone(task domain.Task) bool {
+	for _, parentID := range task.DependsOn {
+		parent, ok := r.store.Get(parentID)
+		if ok && parent.Status != domain.StatusDone {
+			return false
+		}
+	}
+	return true
+}
+
+// release moves a blocked task to pending and hands it to the pool.
+// caller must hold r.mu
+func (r *Resolver) release(id int64, pool workerpool.TaskPool) (domain.Task, error) {
+	task, err := r.store.UpdateStatus(id, domain.StatusPending)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	if err := pool.Enqueue(id); err != nil {
+		failed, _ := r.settle(id, domain.StatusFailed, err.Error())
+		return failed, err
+	}
+	return task, nil
+}
+
+// settle fails or cancels a task that will never run and cascades to its children.
+// caller must hold r.mu
+func (r *Resolver) settle(id int64, status domain.TaskStatus, reason string) (domain.Task, error) {
+	var (
+		task domain.Task
+		err  error
+	)
+	if status == domain.StatusCanceled {
+		task, err = r.store.Cancel(id, 0, reason)
+	} else {
+		status = domain.StatusFailed
+		task, err = r.store.Fail(id, reason)
+	}
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	r.resolve(id, status)
+	return task, nil
+}
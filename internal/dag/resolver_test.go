@@ -0,0 +1,161 @@
+package dag
+
+import (
+	"errors"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/store/memory"
+	"testing"
+)
+
+type fakePool struct {
+	enqueued []int64
+	err      error
+}
+
+func (p *fakePool) Enqueue(id int64) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.enqueued = append(p.enqueued, id)
+	return nil
+}
+
+func TestResolver_ReleasesChildWhenParentsDone(t *testing.T) {
+	store := memory.New()
+	pool := &fakePool{}
+	r := NewResolver(store, pool)
+
+	a, _ := store.Create(domain.Task{Title: "a"})
+	b, _ := store.Create(domain.Task{Title: "b"})
+	c, _ := store.Create(domain.Task{Title: "c", Status: domain.StatusBlocked, DependsOn: []int64{a.ID, b.ID}})
+
+	got, err := r.Register(c, r.pool)
+	if err != nil {
+		t.Fatalf("Register() err = %v, want nil", err)
+	}
+	if got.Status != domain.StatusBlocked {
+		t.Fatalf("Register() status = %s, want %s", got.Status, domain.StatusBlocked)
+	}
+
+	_, _ = store.UpdateStatus(a.ID, domain.StatusDone)
+	r.Resolve(a.ID, domain.StatusDone)
+	if len(pool.enqueued) != 0 {
+		t.Fatalf("enqueued = %v after one parent, want none", pool.enqueued)
+	}
+
+	_, _ = store.UpdateStatus(b.ID, domain.StatusDone)
+	r.Resolve(b.ID, domain.StatusDone)
+	if len(pool.enqueued) != 1 || pool.enqueued[0] != c.ID {
+		t.Fatalf("enqueued = %v, want [%d]", pool.enqueued, c.ID)
+	}
+	if task, _ := store.Get(c.ID); task.Status != domain.StatusPending {
+		t.Fatalf("child status = %s, want %s", task.Status, domain.StatusPending)
+	}
+}
+
+func TestResolver_RegisterWithDoneParents_ReleasesImmediately(t *testing.T) {
+	store := memory.New()
+	pool := &fakePool{}
+	r := NewResolver(store, pool)
+
+	a, _ := store.Create(domain.Task{Title: "a"})
+	_, _ = store.UpdateStatus(a.ID, domain.StatusDone)
+	b, _ := store.Create(domain.Task{Title: "b", Status: domain.StatusBlocked, DependsOn: []int64{a.ID}})
+
+	got, err := r.Register(b, r.pool)
+	if err != nil {
+		t.Fatalf("Register() err = %v, want nil", err)
+	}
+	if got.Status != domain.StatusPending || len(pool.enqueued) != 1 {
+		t.Fatalf("Register() status = %s enqueued = %v, want pending and enqueued", got.Status, pool.enqueued)
+	}
+}
+
+func TestResolver_EvictedParentCountsAsDone(t *testing.T) {
+	store := memory.New()
+	pool := &fakePool{}
+	r := NewResolver(store, pool)
+
+	a, _ := store.Create(domain.Task{Title: "a"})
+	b, _ := store.Create(domain.Task{Title: "b"})
+	c, _ := store.Create(domain.Task{Title: "c", Status: domain.StatusBlocked, DependsOn: []int64{a.ID, b.ID}})
+	if _, err := r.Register(c, r.pool); err != nil {
+		t.Fatalf("Register() err = %v, want nil", err)
+	}
+
+	// the retention sweep evicts a done parent while the other still runs
+	_, _ = store.UpdateStatus(a.ID, domain.StatusDone)
+	r.Resolve(a.ID, domain.StatusDone)
+	_, _ = store.Delete(a.ID, 0)
+
+	_, _ = store.UpdateStatus(b.ID, domain.StatusDone)
+	r.Resolve(b.ID, domain.StatusDone)
+	if len(pool.enqueued) != 1 || pool.enqueued[0] != c.ID {
+		t.Fatalf("enqueued = %v, want [%d]", pool.enqueued, c.ID)
+	}
+}
+
+func TestResolver_Delete_KeepsParentOfBlocked(t *testing.T) {
+	store := memory.New()
+	r := NewResolver(store, &fakePool{})
+
+	a, _ := store.Create(domain.Task{Title: "a"})
+	b, _ := store.Create(domain.Task{Title: "b"})
+	c, _ := store.Create(domain.Task{Title: "c", Status: domain.StatusBlocked, DependsOn: []int64{a.ID, b.ID}})
+	d, _ := store.Create(domain.Task{Title: "d"})
+	if _, err := r.Register(c, r.pool); err != nil {
+		t.Fatalf("Register() err = %v, want nil", err)
+	}
+	_, _ = store.UpdateStatus(a.ID, domain.StatusDone)
+	r.Resolve(a.ID, domain.StatusDone)
+	_, _ = store.UpdateStatus(d.ID, domain.StatusDone)
+
+	if _, err := r.Delete(a.ID, 0); !errors.Is(err, ErrHasDependents) {
+		t.Fatalf("Delete(parent) err = %v, want %v", err, ErrHasDependents)
+	}
+	if _, ok := store.Get(a.ID); !ok {
+		t.Fatal("parent deleted, want kept")
+	}
+	if _, err := r.Delete(d.ID, 0); err != nil {
+		t.Fatalf("Delete(unrelated) err = %v, want nil", err)
+	}
+}
+
+func TestResolver_CascadesFailure(t *testing.T) {
+	store := memory.New()
+	r := NewResolver(store, &fakePool{})
+
+	a, _ := store.Create(domain.Task{Title: "a"})
+	b, _ := store.Create(domain.Task{Title: "b", Status: domain.StatusBlocked, DependsOn: []int64{a.ID}})
+	c, _ := store.Create(domain.Task{Title: "c", Status: domain.StatusBlocked, DependsOn: []int64{b.ID}})
+	_, _ = r.Register(b, r.pool)
+	_, _ = r.Register(c, r.pool)
+
+	_, _ = store.Cancel(a.ID, 0, "stop")
+	r.Resolve(a.ID, domain.StatusCanceled)
+
+	for _, id := range []int64{b.ID, c.ID} {
+		if task, _ := store.Get(id); task.Status != domain.StatusCanceled || task.Error == "" {
+			t.Fatalf("task %d = %+v, want canceled with a reason", id, task)
+		}
+	}
+}
+
+func TestResolver_Check(t *testing.T) {
+	store := memory.New()
+	r := NewResolver(store, &fakePool{})
+
+	a, _ := store.Create(domain.Task{Title: "a"})
+	failed, _ := store.Create(domain.Task{Title: "f"})
+	_, _ = store.Fail(failed.ID, "x")
+
+	if err := r.Check([]int64{a.ID}); err != nil {
+		t.Fatalf("Check() err = %v, want nil", err)
+	}
+	if err := r.Check([]int64{999}); !errors.Is(err, ErrUnknownDependency) {
+		t.Fatalf("Check() err = %v, want %v", err, ErrUnknownDependency)
+	}
+	if err := r.Check([]int64{failed.ID}); !errors.Is(err, ErrDependencyFailed) {
+		t.Fatalf("Check() err = %v, want %v", err, ErrDependencyFailed)
+	}
+}
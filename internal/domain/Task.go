@@ -5,12 +5,19 @@ import "time"
 type TaskStatus string
 
 const (
-	StatusPending TaskStatus = "pending"
-	StatusRunning TaskStatus = "running"
-	StatusDone    TaskStatus = "done"
-	StatusFailed  TaskStatus = "failed"
+	StatusBlocked  TaskStatus = "blocked" // waiting on DependsOn
+	StatusPending  TaskStatus = "pending"
+	StatusRunning  TaskStatus = "running"
+	StatusDone     TaskStatus = "done"
+	StatusFailed   TaskStatus = "failed"
+	StatusCanceled TaskStatus = "canceled"
 )
 
+// Terminal reports whether a task in this status will never change again.
+func (s TaskStatus) Terminal() bool {
+	return s == StatusDone || s == StatusFailed || s == StatusCanceled
+}
+
 type Task struct {
 	ID          int64
 	Title       string
@@ -19,6 +26,39 @@ type Task struct {
 
 	Status TaskStatus
 
-	CreatedAt    time.Time
+	// set by the client and editable until the task starts, the pool doesn't look at them
+	Priority int
+	Metadata map[string]string
+
+	// bumped by every edit, an edit made against an older version is refused
+	Version int64
+
+	// principal that created the task, empty when auth is off
+	Owner string
+
+	// DedupeKey is unique among non-terminal tasks (optional)
+	DedupeKey string
+
+	// at most ConcurrencyLimit tasks sharing ConcurrencyKey run at once (optional)
+	ConcurrencyKey   string
+	ConcurrencyLimit int
+
+	// the task is blocked until every task in DependsOn is done
+	DependsOn []int64
+
+	BatchID int64 // set when submitted through a batch
+
+	// W3C traceparent of the request that created the task, the worker continues it
+	TraceParent string
+	// ID of the HTTP request that created the task, for correlating worker logs
+	RequestID string
+
+	CreatedAt  time.Time
+	FinishedAt time.Time // when the task reached a terminal status
+
+	// kept at least until then and evicted once it passed, instead of the
+	// retention TTL of its status (optional)
+	RetainUntil time.Time
+
 	WorkDuration time.Duration // internal simulation (e.g. 1-5s)
 }
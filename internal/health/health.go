@@ -0,0 +1,137 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Checker is one readiness check. Check returns nil when the subsystem can serve.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type funcChecker struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c funcChecker) Name() string                    { return c.name }
+func (c funcChecker) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// Func turns fn into a Checker.
+func Func(name string, fn func(ctx context.Context) error) Checker {
+	return funcChecker{name: name, fn: fn}
+}
+
+const DefaultTimeout = 2 * time.Second
+
+// Checks holds the registered readiness checks and serves the probes.
+type Checks struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checkers []Checker
+}
+
+func New(timeout time.Duration) *Checks {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Checks{timeout: timeout}
+}
+
+// Register adds checks to /readyz, names should be unique.
+func (c *Checks) Register(checkers ...Checker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkers = append(c.checkers, checkers...)
+}
+
+type Result struct {
+	Status   string `json:"status"` // ok | fail
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status string            `json:"status"` // ok | unavailable
+	Checks map[string]Result `json:"checks"`
+}
+
+var errTimeout = errors.New("check timed out")
+
+// Run runs every check concurrently, each bounded by the timeout.
+func (c *Checks) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checkers := append([]Checker(nil), c.checkers...)
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type named struct {
+		name   string
+		result Result
+	}
+	results := make(chan named, len(checkers))
+	for _, checker := range checkers {
+		go func() {
+			start := time.Now()
+
+			done := make(chan error, 1)
+			go func() { done <- checker.Check(ctx) }()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-ctx.Done():
+				err = errTimeout
+			}
+
+			r := Result{Status: "ok", Duration: time.Since(start).String()}
+			if err != nil {
+				r.Status = "fail"
+				r.Error = err.Error()
+			}
+			results <- named{checker.Name(), r}
+		}()
+	}
+
+	report := Report{Status: "ok", Checks: make(map[string]Result, len(checkers))}
+	for range checkers {
+		n := <-results
+		report.Checks[n.name] = n.result
+		if n.result.Status != "ok" {
+			report.Status = "unavailable"
+		}
+	}
+	return report
+}
+
+// Liveness answers GET /healthz: the process is up and serving HTTP.
+func (c *Checks) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness answers GET /readyz with the result of every check, 503 if one fails.
+func (c *Checks) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,119 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakePool struct {
+	closed          bool
+	depth, capacity int
+}
+
+func (p *fakePool) Closed() bool       { return p.closed }
+func (p *fakePool) QueueDepth() int    { return p.depth }
+func (p *fakePool) QueueCapacity() int { return p.capacity }
+
+func readyz(t *testing.T, c *Checks) (int, Report) {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	c.Readiness(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatalf("decode err=%v", err)
+	}
+	return rr.Code, report
+}
+
+func TestReadiness_ReportsEveryCheck(t *testing.T) {
+	pool := &fakePool{capacity: 10}
+	c := New(time.Second)
+	c.Register(
+		PoolOpen(pool),
+		Func("store", func(context.Context) error { return errors.New("connection refused") }),
+	)
+
+	code, report := readyz(t, c)
+	if code != http.StatusServiceUnavailable || report.Status != "unavailable" {
+		t.Fatalf("code=%d status=%q, want 503 unavailable", code, report.Status)
+	}
+	if report.Checks["pool"].Status != "ok" {
+		t.Fatalf("pool=%+v, want ok", report.Checks["pool"])
+	}
+	if got := report.Checks["store"]; got.Status != "fail" || got.Error != "connection refused" {
+		t.Fatalf("store=%+v, want fail with the error", got)
+	}
+}
+
+func TestReadiness_PoolClosed(t *testing.T) {
+	pool := &fakePool{capacity: 10}
+	c := New(time.Second)
+	c.Register(PoolOpen(pool))
+
+	if code, _ := readyz(t, c); code != http.StatusOK {
+		t.Fatalf("open pool code=%d, want 200", code)
+	}
+
+	pool.closed = true
+	code, report := readyz(t, c)
+	if code != http.StatusServiceUnavailable || report.Checks["pool"].Error != ErrPoolClosed.Error() {
+		t.Fatalf("closed pool code=%d report=%+v", code, report)
+	}
+}
+
+func TestReadiness_SlowCheckTimesOut(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	c := New(20 * time.Millisecond)
+	c.Register(Func("slow", func(context.Context) error { <-block; return nil }))
+
+	code, report := readyz(t, c)
+	if code != http.StatusServiceUnavailable || report.Checks["slow"].Error != errTimeout.Error() {
+		t.Fatalf("code=%d report=%+v, want timeout", code, report)
+	}
+}
+
+func TestQueueSaturation_Sustained(t *testing.T) {
+	pool := &fakePool{depth: 9, capacity: 10}
+	q := NewQueueSaturation(pool, 0.9, 30*time.Second)
+
+	now := time.Now()
+	q.now = func() time.Time { return now }
+
+	if err := q.Check(context.Background()); err != nil {
+		t.Fatalf("just saturated err=%v, want nil", err)
+	}
+
+	now = now.Add(31 * time.Second)
+	if err := q.Check(context.Background()); err == nil {
+		t.Fatalf("saturated for 31s err=nil, want error")
+	}
+
+	// a dip resets the period
+	pool.depth = 1
+	q.Observe()
+	pool.depth = 10
+	now = now.Add(10 * time.Second)
+	if err := q.Check(context.Background()); err != nil {
+		t.Fatalf("after a dip err=%v, want nil", err)
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	c := New(time.Second)
+	c.Register(Func("broken", func(context.Context) error { return errors.New("x") }))
+
+	rr := httptest.NewRecorder()
+	c.Liveness(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("code=%d, want 200 regardless of checks", rr.Code)
+	}
+}
@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrPoolClosed = errors.New("worker pool is shut down")
+
+type PoolState interface {
+	Closed() bool
+	QueueDepth() int
+	QueueCapacity() int
+}
+
+// PoolOpen fails once Pool.Shutdown has been called.
+func PoolOpen(pool PoolState) Checker {
+	return Func("pool", func(context.Context) error {
+		if pool.Closed() {
+			return ErrPoolClosed
+		}
+		return nil
+	})
+}
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Store fails when the store backend can't be reached.
+func Store(store Pinger) Checker {
+	return Func("store", store.Ping)
+}
+
+// QueueSaturation fails when the queue has been at least threshold full
+// (0 < threshold <= 1) for period or longer. A short burst doesn't fail it.
+type QueueSaturation struct {
+	pool      PoolState
+	threshold float64
+	period    time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	since time.Time // zero while the queue is below the threshold
+}
+
+func NewQueueSaturation(pool PoolState, threshold float64, period time.Duration) *QueueSaturation {
+	if threshold <= 0 || threshold > 1 {
+		threshold = 1
+	}
+	return &QueueSaturation{pool: pool, threshold: threshold, period: period, now: time.Now}
+}
+
+func (q *QueueSaturation) Name() string { return "queue" }
+
+// Observe samples the queue. Check samples too, so probes alone are enough,
+// Run adds samples in between so a dip between two probes resets the period.
+func (q *QueueSaturation) Observe() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.observe()
+}
+
+func (q *QueueSaturation) observe() bool {
+	capacity := q.pool.QueueCapacity()
+	saturated := capacity == 0 || float64(q.pool.QueueDepth())/float64(capacity) >= q.threshold
+
+	switch {
+	case !saturated:
+		q.since = time.Time{}
+	case q.since.IsZero():
+		q.since = q.now()
+	}
+	return saturated
+}
+
+// Run samples every interval until ctx is done.
+func (q *QueueSaturation) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.Observe()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (q *QueueSaturation) Check(context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.observe() {
+		return nil
+	}
+	if d := q.now().Sub(q.since); d >= q.period {
+		return fmt.Errorf("queue %d/%d saturated for %s", q.pool.QueueDepth(), q.pool.QueueCapacity(), d.Truncate(time.Second))
+	}
+	return nil
+}
@@ -1,20 +1,194 @@
 package dto
 
+import "time"
+
+// openapi tags add schema constraints, see internal/http/openapi
 type CreateTaskRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	Title       string `json:"title" openapi:"minLength=1"`
+	Description string `json:"description" openapi:"optional"`
+	DedupeKey   string `json:"dedupe_key,omitempty" openapi:"maxLength=255"`
+
+	ConcurrencyKey   string `json:"concurrency_key,omitempty" openapi:"maxLength=255"`
+	ConcurrencyLimit int    `json:"concurrency_limit,omitempty" openapi:"minimum=0"`
+
+	DependsOn []int64 `json:"depends_on,omitempty" openapi:"maxItems=100"`
+
+	Priority int               `json:"priority,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// keeps the finished task until then instead of the retention TTL of its status
+	RetainUntil *time.Time `json:"retain_until,omitempty"`
+}
+
+// UpdateTaskRequest is a PATCH body, absent fields are kept.
+type UpdateTaskRequest struct {
+	Title       *string           `json:"title,omitempty" openapi:"minLength=1"`
+	Description *string           `json:"description,omitempty"`
+	Priority    *int              `json:"priority,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`                    // replaces the whole metadata
+	Version     int64             `json:"version,omitempty" openapi:"minimum=1"` // the version edited, checked when set
 }
 
 type TaskResponse struct {
 	ID          int64  `json:"id"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
-	Status      string `json:"status"`
+	Status      string `json:"status" openapi:"enum=blocked|pending|running|done|failed|canceled"`
 	Error       string `json:"error,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	DedupeKey   string `json:"dedupe_key,omitempty"`
+
+	ConcurrencyKey   string `json:"concurrency_key,omitempty"`
+	ConcurrencyLimit int    `json:"concurrency_limit,omitempty"`
+
+	DependsOn []int64 `json:"depends_on,omitempty"`
+
+	Priority int               `json:"priority,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Version  int64             `json:"version"`
+
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	RetainUntil *time.Time `json:"retain_until,omitempty"`
 }
 
 type TaskSummaryResponse struct {
 	ID     int64  `json:"id"`
 	Title  string `json:"title"`
-	Status string `json:"status"`
+	Status string `json:"status" openapi:"enum=blocked|pending|running|done|failed|canceled"`
+	Owner  string `json:"owner,omitempty"`
+}
+
+type ConcurrencyKeyResponse struct {
+	Owner   string `json:"owner,omitempty"`
+	Key     string `json:"key"`
+	Running int    `json:"running"`
+	Waiting int    `json:"waiting"`
+}
+
+type TaskGraphNode struct {
+	ID        int64   `json:"id"`
+	Title     string  `json:"title"`
+	Status    string  `json:"status" openapi:"enum=blocked|pending|running|done|failed|canceled"`
+	DependsOn []int64 `json:"depends_on"`
+}
+
+type TaskGraphResponse struct {
+	Root  int64           `json:"root"`
+	Nodes []TaskGraphNode `json:"nodes"`
+}
+
+type CreateBatchRequest struct {
+	Mode  string              `json:"mode" openapi:"optional,enum=all_or_nothing|best_effort"` // best_effort by default
+	Tasks []CreateTaskRequest `json:"tasks" openapi:"minItems=1,maxItems=1000"`
+}
+
+type BatchItemResponse struct {
+	Index  int           `json:"index"`
+	Status int           `json:"status"`
+	Task   *TaskResponse `json:"task,omitempty"`
+	Error  string        `json:"error,omitempty"`
+	Code   string        `json:"code,omitempty"` // same codes as the /v1 problem responses
+}
+
+type CreateBatchResponse struct {
+	BatchID int64               `json:"batch_id,omitempty"`
+	Mode    string              `json:"mode"`
+	Items   []BatchItemResponse `json:"items"`
+}
+
+type BatchResponse struct {
+	ID        int64          `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	Total     int            `json:"total"`
+	Counts    map[string]int `json:"counts"`
+}
+
+type TaskLogEntry struct {
+	Seq       int64          `json:"seq"`
+	Time      time.Time      `json:"time"`
+	Level     string         `json:"level"`
+	Message   string         `json:"message"`
+	Attrs     map[string]any `json:"attrs,omitempty"`
+	Truncated bool           `json:"truncated,omitempty"`
+}
+
+type TaskLogsResponse struct {
+	TaskID  int64          `json:"task_id"`
+	Status  string         `json:"status"`
+	Dropped int            `json:"dropped"` // oldest lines dropped to stay within the size caps
+	Lines   []TaskLogEntry `json:"lines"`
+}
+
+type AdminWorker struct {
+	ID     int        `json:"id"`
+	TaskID int64      `json:"task_id,omitempty"` // absent when idle
+	Since  *time.Time `json:"since,omitempty"`
+}
+
+type AdminPoolResponse struct {
+	Workers       []AdminWorker `json:"workers"`
+	BusyWorkers   int           `json:"busy_workers"`
+	QueueDepth    int           `json:"queue_depth"`
+	QueueCapacity int           `json:"queue_capacity"`
+	Paused        bool          `json:"paused"`
+	Closed        bool          `json:"closed"`
+}
+
+type AdminTenantQueue struct {
+	Tenant string `json:"tenant"` // empty for tasks without owner
+	Queued int    `json:"queued"`
+	Weight int    `json:"weight"`
+}
+
+type AdminQueueResponse struct {
+	TaskIDs []int64            `json:"task_ids"`
+	Tenants []AdminTenantQueue `json:"tenants"`
+}
+
+// ArchiveRestoreRequest selects archived tasks by id and/or completion date.
+type ArchiveRestoreRequest struct {
+	IDs  []int64 `json:"ids,omitempty" openapi:"maxItems=1000"`
+	From string  `json:"from,omitempty"` // YYYY-MM-DD, inclusive
+	To   string  `json:"to,omitempty"`
+
+	// seconds the restored tasks are kept before the janitor archives them
+	// again, 86400 when absent
+	RetainFor int `json:"retain_for,omitempty" openapi:"minimum=0"`
+}
+
+type ArchiveRestoreResponse struct {
+	Restored []int64 `json:"restored"`
+	Skipped  []int64 `json:"skipped"` // already in the live store
+}
+
+type CreateAPIKeyRequest struct {
+	Owner  string   `json:"owner" openapi:"minLength=1"`
+	Scopes []string `json:"scopes" openapi:"optional"`
+}
+
+type APIKeyResponse struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	Key       string    `json:"key,omitempty"` // only in the create response
+}
+
+// Problem is the RFC 7807 error body of the /v1 routes (application/problem+json).
+// Code is stable for clients to switch on, Detail is for humans.
+type Problem struct {
+	Type      string        `json:"type"`
+	Title     string        `json:"title"`
+	Status    int           `json:"status"`
+	Detail    string        `json:"detail,omitempty"`
+	Instance  string        `json:"instance,omitempty"`
+	Code      string        `json:"code"`
+	RequestID string        `json:"request_id,omitempty"`
+	Errors    []FieldError  `json:"errors,omitempty"`
+	Task      *TaskResponse `json:"task,omitempty"` // the failed task of a pool_full/pool_closed create
+}
+
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, e.g. tasks[1].title
+	Message string `json:"message"`
 }
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/workerpool"
+	"net/http"
+	"strings"
+)
+
+type AdminPool interface {
+	WorkerStates() []workerpool.WorkerState
+	QueuedIDs() []int64
+	TenantQueues() []workerpool.TenantStats
+	QueueDepth() int
+	QueueCapacity() int
+	Paused() bool
+	Closed() bool
+	Pause() error
+	Resume()
+	Drain()
+}
+
+type AdminHandler struct {
+	pool AdminPool
+}
+
+func NewAdminHandler(pool AdminPool) *AdminHandler {
+	return &AdminHandler{pool: pool}
+}
+
+// GET /admin/pool
+func (h *AdminHandler) Pool(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.poolResponse())
+}
+
+// POST /admin/pool/pause
+func (h *AdminHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	if err := h.pool.Pause(); err != nil {
+		if errors.Is(err, workerpool.ErrPoolClosed) {
+			writeError(w, r, http.StatusConflict, codePoolClosed, err.Error())
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "failed pausing pool")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.poolResponse())
+}
+
+// POST /admin/pool/resume
+func (h *AdminHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	h.pool.Resume()
+
+	writeJSON(w, http.StatusOK, h.poolResponse())
+}
+
+// POST /admin/pool/drain
+//
+// Closes the pool and returns right away, the queue keeps being worked off.
+// Progress can be watched on GET /admin/pool.
+func (h *AdminHandler) Drain(w http.ResponseWriter, r *http.Request) {
+	h.pool.Drain()
+
+	writeJSON(w, http.StatusAccepted, h.poolResponse())
+}
+
+// GET /admin/queue
+//
+// Task ids come in the order the fair queue hands them to workers.
+func (h *AdminHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	tenants := h.pool.TenantQueues()
+
+	response := dto.AdminQueueResponse{
+		TaskIDs: h.pool.QueuedIDs(),
+		Tenants: make([]dto.AdminTenantQueue, 0, len(tenants)),
+	}
+	for _, t := range tenants {
+		response.Tenants = append(response.Tenants, dto.AdminTenantQueue{Tenant: t.Tenant, Queued: t.Queued, Weight: t.Weight})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *AdminHandler) poolResponse() dto.AdminPoolResponse {
+	states := h.pool.WorkerStates()
+
+	response := dto.AdminPoolResponse{
+		Workers:       make([]dto.AdminWorker, 0, len(states)),
+		QueueDepth:    h.pool.QueueDepth(),
+		QueueCapacity: h.pool.QueueCapacity(),
+		Paused:        h.pool.Paused(),
+		Closed:        h.pool.Closed(),
+	}
+	for _, s := range states {
+		worker := dto.AdminWorker{ID: s.ID, TaskID: s.TaskID}
+		if s.TaskID != 0 {
+			since := s.Since
+			worker.Since = &since
+			response.BusyWorkers++
+		}
+		response.Workers = append(response.Workers, worker)
+	}
+	return response
+}
+
+// RequireToken lets a request through only with the admin token, sent as
+// "Authorization: Bearer <token>" or "X-Admin-Token: <token>".
+func RequireToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	want := sha256.Sum256([]byte(token))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("X-Admin-Token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			got = bearer
+		}
+
+		// hashed so the comparison doesn't leak the token length either
+		sum := sha256.Sum256([]byte(got))
+		if got == "" || subtle.ConstantTimeCompare(sum[:], want[:]) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "invalid admin token")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"interview-task-worker-pool/internal/domain"
+	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+func TestAdmin_TokenPauseQueueResume(t *testing.T) {
+	store := memory.New()
+	noop := func(context.Context, domain.Task, *slog.Logger) error { return nil }
+	pool := workerpool.New(10, store, workerpool.WithExecutor(noop))
+	pool.Start(1)
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	svc, err := service.New(store, pool)
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+	app := approuter.New(handlers.New(svc), approuter.WithAdmin(handlers.NewAdminHandler(pool), "s3cret"))
+
+	admin := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		app.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := admin(http.MethodGet, "/admin/pool", ""); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("no token status=%d, want 401", rr.Code)
+	}
+	if rr := admin(http.MethodGet, "/admin/pool", "wrong"); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token status=%d, want 401", rr.Code)
+	}
+
+	rr := admin(http.MethodPost, "/admin/pool/pause", "s3cret")
+	var state dto.AdminPoolResponse
+	_ = json.NewDecoder(rr.Body).Decode(&state)
+	if rr.Code != http.StatusOK || !state.Paused || len(state.Workers) != 1 {
+		t.Fatalf("pause status=%d state=%+v", rr.Code, state)
+	}
+
+	var ids []int64
+	for i := 0; i < 2; i++ {
+		created := doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "t"})
+		var task dto.TaskResponse
+		_ = json.NewDecoder(created.Body).Decode(&task)
+		ids = append(ids, task.ID)
+	}
+
+	rr = admin(http.MethodGet, "/admin/queue", "s3cret")
+	var queue dto.AdminQueueResponse
+	_ = json.NewDecoder(rr.Body).Decode(&queue)
+	if len(queue.TaskIDs) != 2 || queue.TaskIDs[0] != ids[0] || queue.TaskIDs[1] != ids[1] {
+		t.Fatalf("queue=%v, want %v", queue.TaskIDs, ids)
+	}
+	if len(queue.Tenants) != 1 || queue.Tenants[0].Queued != 2 || queue.Tenants[0].Weight != 1 {
+		t.Fatalf("tenants=%+v, want one tenant with 2 queued", queue.Tenants)
+	}
+
+	rr = admin(http.MethodPost, "/admin/pool/resume", "s3cret")
+	state = dto.AdminPoolResponse{}
+	_ = json.NewDecoder(rr.Body).Decode(&state)
+	if rr.Code != http.StatusOK || state.Paused {
+		t.Fatalf("resume status=%d state=%+v", rr.Code, state)
+	}
+
+	rr = admin(http.MethodPost, "/admin/pool/drain", "s3cret")
+	state = dto.AdminPoolResponse{}
+	_ = json.NewDecoder(rr.Body).Decode(&state)
+	if rr.Code != http.StatusAccepted || !state.Closed {
+		t.Fatalf("drain status=%d state=%+v", rr.Code, state)
+	}
+	if rr := admin(http.MethodPost, "/admin/pool/pause", "s3cret"); rr.Code != http.StatusConflict {
+		t.Fatalf("pause after drain status=%d, want 409", rr.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"interview-task-worker-pool/internal/archive"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/store"
+	"net/http"
+	"time"
+)
+
+const defaultRetainFor = 24 * time.Hour
+
+type ArchiveSearcher interface {
+	Search(q archive.Query) ([]domain.Task, error)
+}
+
+type ArchiveStore interface {
+	Put(task domain.Task, replace bool) (domain.Task, error)
+}
+
+type ArchiveHandler struct {
+	archive ArchiveSearcher
+	store   ArchiveStore
+}
+
+func NewArchiveHandler(archive ArchiveSearcher, store ArchiveStore) *ArchiveHandler {
+	return &ArchiveHandler{archive: archive, store: store}
+}
+
+// POST /admin/archive/restore
+//
+// Restored tasks are pinned (retain_until) for a while, else the next
+// retention sweep would archive them right away again.
+func (h *ArchiveHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	var req dto.ArchiveRestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
+
+		return
+	}
+
+	q := archive.Query{IDs: req.IDs}
+	for _, d := range []struct {
+		field, value string
+		into         *time.Time
+	}{{"from", req.From, &q.From}, {"to", req.To, &q.To}} {
+		if d.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, d.value)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, codeInvalidInput, d.field+": not a YYYY-MM-DD date")
+			return
+		}
+		*d.into = t
+	}
+	if req.RetainFor < 0 {
+		writeError(w, r, http.StatusBadRequest, codeInvalidInput, "retain_for: is negative")
+		return
+	}
+	retainFor := defaultRetainFor
+	if req.RetainFor > 0 {
+		retainFor = time.Duration(req.RetainFor) * time.Second
+	}
+
+	tasks, err := h.archive.Search(q)
+	if err != nil {
+		if errors.Is(err, archive.ErrEmptyQuery) {
+			writeError(w, r, http.StatusBadRequest, codeInvalidInput, err.Error())
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "failed searching archive")
+		return
+	}
+
+	response := dto.ArchiveRestoreResponse{Restored: []int64{}, Skipped: []int64{}}
+	retainUntil := time.Now().Add(retainFor)
+	for _, task := range tasks {
+		if task.RetainUntil.Before(retainUntil) {
+			task.RetainUntil = retainUntil
+		}
+		if _, err := h.store.Put(task, false); err != nil {
+			if errors.Is(err, store.ErrExists) {
+				response.Skipped = append(response.Skipped, task.ID)
+				continue
+			}
+			writeError(w, r, http.StatusInternalServerError, codeInternal, "failed restoring task")
+			return
+		}
+		response.Restored = append(response.Restored, task.ID)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
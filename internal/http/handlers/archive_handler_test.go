@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"interview-task-worker-pool/internal/archive"
+	"interview-task-worker-pool/internal/domain"
+	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/retention"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+func TestAdmin_ArchiveRestore(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store)
+	svc, err := service.New(store, pool)
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+	tasksArchive, err := archive.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("archive.Open err=%v", err)
+	}
+	app := approuter.New(handlers.New(svc),
+		approuter.WithArchive(handlers.NewArchiveHandler(tasksArchive, store), "s3cret"))
+
+	created, _ := store.Create(domain.Task{Title: "t"})
+	done, _ := store.UpdateStatus(created.ID, domain.StatusDone)
+
+	janitor := retention.NewJanitor(store, retention.Policy{DoneTTL: time.Hour}, retention.WithArchive(tasksArchive))
+	if evicted, err := janitor.Sweep(time.Now().Add(2 * time.Hour)); err != nil || evicted.Total() != 1 {
+		t.Fatalf("Sweep() = %v err=%v, want the task archived and evicted", evicted, err)
+	}
+	if rr := doJSON(t, app, http.MethodGet, "/v1/tasks/"+strconv.FormatInt(done.ID, 10), nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("GET archived task status=%d, want 404", rr.Code)
+	}
+
+	restore := func(body any) *httptest.ResponseRecorder {
+		raw, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/v1/admin/archive/restore", bytes.NewReader(raw))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rr := httptest.NewRecorder()
+		app.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := restore(map[string]any{}); rr.Code != http.StatusBadRequest {
+		t.Fatalf("restore(no query) status=%d, want 400", rr.Code)
+	}
+	if rr := restore(map[string]any{"from": "18.10.2026"}); rr.Code != http.StatusBadRequest {
+		t.Fatalf("restore(bad date) status=%d, want 400", rr.Code)
+	}
+
+	today := time.Now().UTC().Format(time.DateOnly)
+	rr := restore(map[string]any{"from": today, "to": today})
+	var res dto.ArchiveRestoreResponse
+	_ = json.NewDecoder(rr.Body).Decode(&res)
+	if rr.Code != http.StatusOK || len(res.Restored) != 1 || res.Restored[0] != done.ID {
+		t.Fatalf("restore status=%d body=%+v, want task %d restored", rr.Code, res, done.ID)
+	}
+
+	rr = doJSON(t, app, http.MethodGet, "/v1/tasks/"+strconv.FormatInt(done.ID, 10), nil)
+	var task dto.TaskResponse
+	_ = json.NewDecoder(rr.Body).Decode(&task)
+	if rr.Code != http.StatusOK || task.Status != "done" || task.Version != done.Version || task.RetainUntil == nil {
+		t.Fatalf("GET restored task status=%d body=%+v, want it back with its version and pinned", rr.Code, task)
+	}
+
+	// restored tasks are pinned, the next sweep keeps them
+	if evicted, _ := janitor.Sweep(time.Now().Add(2 * time.Hour)); evicted.Total() != 0 {
+		t.Fatalf("Sweep() after restore = %v, want nothing evicted", evicted)
+	}
+
+	rr = restore(map[string]any{"ids": []int64{done.ID}})
+	res = dto.ArchiveRestoreResponse{}
+	_ = json.NewDecoder(rr.Body).Decode(&res)
+	if len(res.Restored) != 0 || len(res.Skipped) != 1 {
+		t.Fatalf("restore(live task) body=%+v, want it skipped", res)
+	}
+}
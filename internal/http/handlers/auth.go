@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"interview-task-worker-pool/internal/auth"
+	"net/http"
+)
+
+// Authenticate puts the caller's principal on the request context. The first
+// authenticator that finds credentials decides, a request with none is 401.
+// The error says why the credentials were refused (e.g. "invalid token: expired").
+func Authenticate(authenticators ...auth.Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, a := range authenticators {
+				principal, err := a.AuthenticateRequest(r)
+				if errors.Is(err, auth.ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					unauthorized(w, r, err.Error())
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(auth.ContextWithPrincipal(r.Context(), principal)))
+				return
+			}
+			unauthorized(w, r, auth.ErrNoCredentials.Error())
+		})
+	}
+}
+
+// RequireScope answers 403 unless the authenticated caller has scope.
+func RequireScope(scope auth.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			unauthorized(w, r, auth.ErrNoCredentials.Error())
+			return
+		}
+		if !principal.Has(scope) {
+			writeError(w, r, http.StatusForbidden, codeMissingScope, "missing scope "+string(scope))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="tasks"`)
+	writeError(w, r, http.StatusUnauthorized, codeUnauthorized, msg)
+}
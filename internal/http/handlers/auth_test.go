@@ -0,0 +1,190 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"interview-task-worker-pool/internal/auth"
+	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+func TestAuth_APIKeysAndOwnership(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store)
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	svc, err := service.New(store, pool)
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+
+	keys := auth.NewKeyStore()
+	if err := keys.AddSpecs("alice:a-key,bob:b-key,ops:o-key:admin"); err != nil {
+		t.Fatalf("AddSpecs err=%v", err)
+	}
+	app := approuter.New(handlers.New(svc),
+		approuter.WithAuth(handlers.Authenticate(keys)),
+		approuter.WithAPIKeys(handlers.NewKeyHandler(keys)),
+	)
+
+	do := func(method, path, key string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		app.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := do(http.MethodGet, "/tasks", "", nil); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("no key status=%d, want 401", rr.Code)
+	}
+	if rr := do(http.MethodGet, "/tasks", "wrong", nil); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong key status=%d, want 401", rr.Code)
+	}
+
+	rr := do(http.MethodPost, "/tasks", "a-key", map[string]any{"title": "alice's"})
+	var task dto.TaskResponse
+	_ = json.NewDecoder(rr.Body).Decode(&task)
+	if rr.Code != http.StatusCreated || task.Owner != "alice" {
+		t.Fatalf("create status=%d task=%+v, want 201 owned by alice", rr.Code, task)
+	}
+	_ = do(http.MethodPost, "/tasks", "b-key", map[string]any{"title": "bob's"})
+
+	path := "/tasks/" + strconv.FormatInt(task.ID, 10)
+	if rr := do(http.MethodGet, path, "b-key", nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("other owner get status=%d, want 404", rr.Code)
+	}
+	if rr := do(http.MethodGet, path, "o-key", nil); rr.Code != http.StatusOK {
+		t.Fatalf("admin get status=%d, want 200", rr.Code)
+	}
+
+	list := func(path, key string) []dto.TaskSummaryResponse {
+		var tasks []dto.TaskSummaryResponse
+		_ = json.NewDecoder(do(http.MethodGet, path, key, nil).Body).Decode(&tasks)
+		return tasks
+	}
+	if tasks := list("/tasks", "b-key"); len(tasks) != 1 || tasks[0].Owner != "bob" {
+		t.Fatalf("bob's list=%+v, want only his task", tasks)
+	}
+	if tasks := list("/tasks", "o-key"); len(tasks) != 2 {
+		t.Fatalf("admin list=%+v, want both tasks", tasks)
+	}
+	if tasks := list("/tasks?owner=alice", "o-key"); len(tasks) != 1 || tasks[0].Owner != "alice" {
+		t.Fatalf("admin list owner=alice=%+v, want alice's task", tasks)
+	}
+
+	// key management needs the admin scope
+	if rr := do(http.MethodGet, "/api-keys", "a-key", nil); rr.Code != http.StatusForbidden {
+		t.Fatalf("non-admin list keys status=%d, want 403", rr.Code)
+	}
+	rr = do(http.MethodPost, "/api-keys", "o-key", map[string]any{"owner": "carol"})
+	var created dto.APIKeyResponse
+	_ = json.NewDecoder(rr.Body).Decode(&created)
+	if rr.Code != http.StatusCreated || created.Key == "" {
+		t.Fatalf("create key status=%d key=%+v", rr.Code, created)
+	}
+	if rr := do(http.MethodGet, "/tasks", created.Key, nil); rr.Code != http.StatusOK {
+		t.Fatalf("new key status=%d, want 200", rr.Code)
+	}
+	if rr := do(http.MethodDelete, "/api-keys/"+created.ID, "o-key", nil); rr.Code != http.StatusNoContent {
+		t.Fatalf("revoke status=%d, want 204", rr.Code)
+	}
+	if rr := do(http.MethodGet, "/tasks", created.Key, nil); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked key status=%d, want 401", rr.Code)
+	}
+	if rr := do(http.MethodDelete, "/api-keys/"+created.ID, "o-key", nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("revoke twice status=%d, want 404", rr.Code)
+	}
+}
+
+func hs256Token(secret string, claims map[string]any) string {
+	enc := func(v any) string {
+		raw, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	signed := enc(map[string]any{"alg": "HS256", "typ": "JWT"}) + "." + enc(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuth_JWTScopes(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store)
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	svc, err := service.New(store, pool)
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+
+	jwks := auth.NewJWKS()
+	jwks.AddHMAC("", []byte("hs-secret"))
+	keys := auth.NewKeyStore()
+	_ = keys.AddSpecs("alice:a-key")
+
+	app := approuter.New(handlers.New(svc),
+		approuter.WithAuth(handlers.Authenticate(auth.NewJWTAuthenticator(auth.JWTConfig{Keys: jwks}), keys)),
+	)
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(`{"title":"t"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		app.ServeHTTP(rr, req)
+		return rr
+	}
+	exp := time.Now().Add(time.Minute).Unix()
+
+	writer := hs256Token("hs-secret", map[string]any{"sub": "svc-a", "exp": exp, "scope": "tasks:create tasks:read"})
+	rr := do(http.MethodPost, "/tasks", writer)
+	var task dto.TaskResponse
+	_ = json.NewDecoder(rr.Body).Decode(&task)
+	if rr.Code != http.StatusCreated || task.Owner != "svc-a" {
+		t.Fatalf("create status=%d task=%+v, want 201 owned by the subject", rr.Code, task)
+	}
+
+	reader := hs256Token("hs-secret", map[string]any{"sub": "svc-a", "exp": exp, "scope": "tasks:read"})
+	if rr := do(http.MethodGet, "/tasks/"+strconv.FormatInt(task.ID, 10), reader); rr.Code != http.StatusOK {
+		t.Fatalf("read status=%d, want 200", rr.Code)
+	}
+	rr = do(http.MethodPost, "/tasks", reader)
+	var body map[string]string
+	_ = json.NewDecoder(rr.Body).Decode(&body)
+	if rr.Code != http.StatusForbidden || body["error"] != "missing scope tasks:create" {
+		t.Fatalf("create without scope status=%d body=%v, want 403", rr.Code, body)
+	}
+
+	expired := hs256Token("hs-secret", map[string]any{"sub": "svc-a", "exp": time.Now().Add(-time.Hour).Unix()})
+	rr = do(http.MethodGet, "/tasks", expired)
+	body = nil
+	_ = json.NewDecoder(rr.Body).Decode(&body)
+	if rr.Code != http.StatusUnauthorized || body["error"] != "invalid token: expired" {
+		t.Fatalf("expired status=%d body=%v, want 401", rr.Code, body)
+	}
+
+	// API keys still work as bearer tokens behind the JWT authenticator
+	if rr := do(http.MethodGet, "/tasks", "a-key"); rr.Code != http.StatusOK {
+		t.Fatalf("api key status=%d, want 200", rr.Code)
+	}
+}
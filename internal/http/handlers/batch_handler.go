@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/workerpool"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// BatchSize is the number of tasks in a POST /tasks/batch body, the rate limit
+// takes a token per task. A body that isn't a batch counts as one, the handler
+// rejects it.
+func BatchSize(r *http.Request) int {
+	raw, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	// the rest of an oversized body stays for the handler to refuse
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(raw), r.Body), r.Body}
+	if err != nil {
+		return 1
+	}
+
+	var req struct {
+		Tasks []json.RawMessage `json:"tasks"`
+	}
+	if json.Unmarshal(raw, &req) != nil {
+		return 1
+	}
+	return max(len(req.Tasks), 1)
+}
+
+// POST /tasks/batch
+func (h *TaskHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
+
+		return
+	}
+
+	inputs := make([]service.CreateTaskInput, 0, len(req.Tasks))
+	for _, t := range req.Tasks {
+		inputs = append(inputs, toCreateInput(t))
+	}
+
+	mode := service.BatchMode(req.Mode)
+	if mode == "" {
+		mode = service.BatchBestEffort
+	}
+
+	res, err := h.taskService.CreateBatch(r.Context(), inputs, mode)
+	if err != nil && !errors.Is(err, service.ErrBatchRejected) {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			invalidInput(w, r, err)
+			return
+		default:
+			writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
+			return
+		}
+	}
+
+	response := dto.CreateBatchResponse{
+		BatchID: res.BatchID,
+		Mode:    string(mode),
+		Items:   make([]dto.BatchItemResponse, 0, len(res.Items)),
+	}
+	for i, item := range res.Items {
+		status, code := batchItemStatus(item)
+		out := dto.BatchItemResponse{Index: i, Status: status, Code: code}
+		if item.Err != nil {
+			out.Error = item.Err.Error()
+		}
+		// failed enqueues still return the stored (failed) task
+		if item.Task.ID != 0 {
+			task := toTaskResponse(item.Task)
+			out.Task = &task
+		}
+		response.Items = append(response.Items, out)
+	}
+
+	if errors.Is(err, service.ErrBatchRejected) {
+		writeJSON(w, http.StatusUnprocessableEntity, response)
+		return
+	}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// batchItemStatus is the status code and error code the item would have got
+// from POST /tasks.
+func batchItemStatus(item service.BatchItemResult) (int, string) {
+	switch {
+	case item.Err == nil && item.Attached:
+		return http.StatusOK, ""
+	case item.Err == nil:
+		return http.StatusCreated, ""
+	case errors.Is(item.Err, service.ErrInvalidInput):
+		return http.StatusBadRequest, inputCode(item.Err)
+	case errors.Is(item.Err, service.ErrDuplicate):
+		return http.StatusConflict, codeDuplicateTask
+	case errors.Is(item.Err, service.ErrInvalidDependency):
+		return http.StatusUnprocessableEntity, codeInvalidDependency
+	case errors.Is(item.Err, service.ErrQuotaExceeded):
+		return http.StatusTooManyRequests, codeQuotaExceeded
+	case errors.Is(item.Err, service.ErrBatchRejected):
+		return http.StatusFailedDependency, codeBatchRejected
+	case errors.Is(item.Err, workerpool.ErrPoolFull):
+		return http.StatusServiceUnavailable, codePoolFull
+	case errors.Is(item.Err, workerpool.ErrPoolClosed):
+		return http.StatusServiceUnavailable, codePoolClosed
+	default:
+		return http.StatusInternalServerError, codeInternal
+	}
+}
+
+// GET /batches/{id}
+func (h *TaskHandler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, http.StatusBadRequest, codeInvalidID, service.ErrInvalidID.Error())
+
+		return
+	}
+
+	progress, err := h.taskService.GetBatch(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidID):
+			writeError(w, r, http.StatusBadRequest, codeInvalidID, service.ErrInvalidID.Error())
+			return
+		case errors.Is(err, service.ErrBatchNotFound):
+			writeError(w, r, http.StatusNotFound, codeBatchNotFound, service.ErrBatchNotFound.Error())
+			return
+		default:
+			writeError(w, r, http.StatusInternalServerError, codeInternal, "failed getting batch")
+			return
+		}
+	}
+
+	counts := make(map[string]int, len(progress.Counts))
+	for status, n := range progress.Counts {
+		counts[string(status)] = n
+	}
+
+	writeJSON(w, http.StatusOK, dto.BatchResponse{
+		ID:        progress.ID,
+		CreatedAt: progress.CreatedAt,
+		Total:     progress.Total,
+		Counts:    counts,
+	})
+}
@@ -0,0 +1,68 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/http/dto"
+)
+
+func TestPOST_TasksBatch_BestEffortAndProgress(t *testing.T) {
+	app, cleanup := newApp(t, 10, 0)
+	defer cleanup()
+
+	rr := doJSON(t, app, http.MethodPost, "/tasks/batch", map[string]any{
+		"mode":  "best_effort",
+		"tasks": []map[string]any{{"title": "A"}, {"title": ""}, {"title": "C"}},
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status=%d, want %d body=%s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+
+	var out dto.CreateBatchResponse
+	if err := json.NewDecoder(rr.Body).Decode(&out); err != nil {
+		t.Fatalf("decode err=%v", err)
+	}
+	if out.BatchID <= 0 || len(out.Items) != 3 {
+		t.Fatalf("response=%+v, want a batch id and 3 items", out)
+	}
+	if out.Items[0].Status != http.StatusCreated || out.Items[1].Status != http.StatusBadRequest || out.Items[2].Status != http.StatusCreated {
+		t.Fatalf("item statuses=%d,%d,%d, want 201,400,201", out.Items[0].Status, out.Items[1].Status, out.Items[2].Status)
+	}
+
+	progress := httptest.NewRecorder()
+	app.ServeHTTP(progress, httptest.NewRequest(http.MethodGet, "/batches/"+strconv.FormatInt(out.BatchID, 10), nil))
+	if progress.Code != http.StatusOK {
+		t.Fatalf("progress status=%d body=%s", progress.Code, progress.Body.String())
+	}
+	var batch dto.BatchResponse
+	_ = json.NewDecoder(progress.Body).Decode(&batch)
+	if batch.Total != 2 || batch.Counts[string(domain.StatusPending)] != 2 {
+		t.Fatalf("batch=%+v, want total=2 pending=2", batch)
+	}
+}
+
+func TestPOST_TasksBatch_AllOrNothing_422(t *testing.T) {
+	app, cleanup := newApp(t, 10, 0)
+	defer cleanup()
+
+	rr := doJSON(t, app, http.MethodPost, "/tasks/batch", map[string]any{
+		"mode":  "all_or_nothing",
+		"tasks": []map[string]any{{"title": "A"}, {"title": ""}},
+	})
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status=%d, want %d body=%s", rr.Code, http.StatusUnprocessableEntity, rr.Body.String())
+	}
+
+	list := httptest.NewRecorder()
+	app.ServeHTTP(list, httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	var tasks []dto.TaskSummaryResponse
+	_ = json.NewDecoder(list.Body).Decode(&tasks)
+	if len(tasks) != 0 {
+		t.Fatalf("len=%d, want 0 tasks after a rejected batch", len(tasks))
+	}
+}
@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"interview-task-worker-pool/internal/domain"
+	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+func TestTask_ETagsAndPreconditions(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store) // not started, tasks stay pending
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	svc, err := service.New(store, pool)
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+	app := approuter.New(handlers.New(svc))
+
+	do := func(method, path string, body any, header, value string) *httptest.ResponseRecorder {
+		t.Helper()
+		var buf bytes.Buffer
+		if body != nil {
+			_ = json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rr := httptest.NewRecorder()
+		app.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := doJSON(t, app, http.MethodPost, "/v1/tasks", map[string]any{"title": "t"})
+	var created dto.TaskResponse
+	_ = json.NewDecoder(rr.Body).Decode(&created)
+	if rr.Header().Get("ETag") != `"1"` {
+		t.Fatalf("create ETag=%q, want \"1\"", rr.Header().Get("ETag"))
+	}
+	path := "/v1/tasks/" + strconv.FormatInt(created.ID, 10)
+
+	rr = do(http.MethodGet, path, nil, "If-None-Match", `W/"1"`)
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 || rr.Header().Get("ETag") != `"1"` {
+		t.Fatalf("GET If-None-Match status=%d body=%q etag=%q, want empty 304", rr.Code, rr.Body.String(), rr.Header().Get("ETag"))
+	}
+
+	rr = do(http.MethodPatch, path, map[string]any{"title": "renamed"}, "If-Match", `"1"`)
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PATCH If-Match status=%d etag=%q, want 200 with \"2\"", rr.Code, rr.Header().Get("ETag"))
+	}
+	if rr := do(http.MethodGet, path, nil, "If-None-Match", `"1"`); rr.Code != http.StatusOK {
+		t.Fatalf("GET with an old tag status=%d, want 200", rr.Code)
+	}
+
+	// another client still holds version 1
+	rr = do(http.MethodPatch, path, map[string]any{"title": "lost"}, "If-Match", `"1"`)
+	var p dto.Problem
+	_ = json.NewDecoder(rr.Body).Decode(&p)
+	if rr.Code != http.StatusPreconditionFailed || p.Code != "precondition_failed" {
+		t.Fatalf("stale PATCH status=%d problem=%+v, want 412 precondition_failed", rr.Code, p)
+	}
+	if rr := do(http.MethodDelete, path, nil, "If-Match", `"1"`); rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale DELETE status=%d, want 412", rr.Code)
+	}
+	if rr := do(http.MethodDelete, path, nil, "If-Match", `W/"2"`); rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("weak If-Match DELETE status=%d, want 412", rr.Code)
+	}
+	if task, _ := store.Get(created.ID); task.Status != domain.StatusPending || task.Title != "renamed" {
+		t.Fatalf("task after refused writes=%+v, want pending and renamed", task)
+	}
+	if rr := do(http.MethodDelete, path, nil, "If-Match", `"2"`); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE If-Match status=%d, want 204 body=%s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"interview-task-worker-pool/internal/auth"
+	"interview-task-worker-pool/internal/http/dto"
+	"net/http"
+)
+
+type KeyManager interface {
+	Create(owner string, scopes []auth.Scope) (string, auth.APIKey, error)
+	Revoke(id string) error
+	List() []auth.APIKey
+}
+
+type KeyHandler struct {
+	keys KeyManager
+}
+
+func NewKeyHandler(keys KeyManager) *KeyHandler {
+	return &KeyHandler{keys: keys}
+}
+
+// POST /api-keys
+//
+// The secret is only part of this response, the store keeps its hash.
+func (h *KeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "invalid json")
+
+		return
+	}
+
+	scopes := make([]auth.Scope, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		scopes = append(scopes, auth.Scope(s))
+	}
+
+	secret, key, err := h.keys.Create(req.Owner, scopes)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidSpec) {
+			writeProblem(w, r, dto.Problem{
+				Status: http.StatusBadRequest,
+				Code:   "invalid_owner",
+				Detail: "owner is required",
+				Errors: []dto.FieldError{{Field: "owner", Message: "is required"}},
+			})
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "failed creating api key")
+		return
+	}
+
+	response := toKeyResponse(key)
+	response.Key = secret
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// GET /api-keys
+func (h *KeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	keys := h.keys.List()
+
+	response := make([]dto.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, toKeyResponse(key))
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// DELETE /api-keys/{id}
+func (h *KeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := h.keys.Revoke(r.PathValue("id")); err != nil {
+		if errors.Is(err, auth.ErrKeyNotFound) {
+			writeError(w, r, http.StatusNotFound, codeAPIKeyNotFound, err.Error())
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "failed revoking api key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toKeyResponse(key auth.APIKey) dto.APIKeyResponse {
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, s := range key.Scopes {
+		scopes = append(scopes, string(s))
+	}
+	return dto.APIKeyResponse{
+		ID:        key.ID,
+		Owner:     key.Owner,
+		Scopes:    scopes,
+		CreatedAt: key.CreatedAt,
+	}
+}
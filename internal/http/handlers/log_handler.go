@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/tasklog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type TaskLogs interface {
+	Buffer(id int64) *tasklog.Buffer
+	Lookup(id int64) (*tasklog.Buffer, bool)
+}
+
+type TaskGetter interface {
+	GetTask(ctx context.Context, id int64) (domain.Task, error)
+}
+
+type LogHandler struct {
+	tasks TaskGetter
+	logs  TaskLogs
+
+	// how often a follower re-checks a task that may end without running
+	pollInterval time.Duration
+}
+
+func NewLogHandler(tasks TaskGetter, logs TaskLogs) *LogHandler {
+	return &LogHandler{tasks: tasks, logs: logs, pollInterval: time.Second}
+}
+
+// GET /tasks/{id}/logs
+//
+// Returns the execution log captured so far. With ?follow=1 the log of a task
+// that has not finished yet is streamed as NDJSON, one line per entry, until the
+// execution ends or the client goes away.
+func (h *LogHandler) Logs(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, http.StatusBadRequest, codeInvalidID, service.ErrInvalidID.Error())
+
+		return
+	}
+
+	task, err := h.tasks.GetTask(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			writeError(w, r, http.StatusNotFound, codeTaskNotFound, service.ErrNotFound.Error())
+		default:
+			writeError(w, r, http.StatusInternalServerError, codeInternal, "failed getting task")
+		}
+		return
+	}
+
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+	if follow && !task.Status.Terminal() {
+		h.follow(w, r, task)
+		return
+	}
+
+	response := dto.TaskLogsResponse{TaskID: id, Status: string(task.Status), Lines: []dto.TaskLogEntry{}}
+	if buf, ok := h.logs.Lookup(id); ok {
+		snap, _ := buf.Since(0)
+		response.Dropped = snap.Dropped
+		for _, e := range snap.Entries {
+			response.Lines = append(response.Lines, toLogEntry(e))
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *LogHandler) follow(w http.ResponseWriter, r *http.Request, task domain.Task) {
+	buf := h.logs.Buffer(task.ID)
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+
+	enc := json.NewEncoder(w)
+	var seq int64
+
+	// write sends the lines after seq and reports whether the log is complete
+	write := func() (bool, error) {
+		snap, _ := buf.Since(seq)
+		for _, e := range snap.Entries {
+			if err := enc.Encode(toLogEntry(e)); err != nil {
+				return false, err
+			}
+			seq = e.Seq
+		}
+		if len(snap.Entries) > 0 {
+			_ = rc.Flush()
+		}
+		return snap.Closed, nil
+	}
+
+	for {
+		_, changed := buf.Since(seq)
+		if closed, err := write(); closed || err != nil {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			// a task failed or canceled before it ran never gets its log closed
+			if t, err := h.tasks.GetTask(r.Context(), task.ID); err != nil || t.Status.Terminal() {
+				_, _ = write()
+				return
+			}
+		}
+	}
+}
+
+func toLogEntry(e tasklog.Entry) dto.TaskLogEntry {
+	return dto.TaskLogEntry{
+		Seq:       e.Seq,
+		Time:      e.Time,
+		Level:     e.Level,
+		Message:   e.Message,
+		Attrs:     e.Attrs,
+		Truncated: e.Truncated,
+	}
+}
@@ -0,0 +1,127 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"interview-task-worker-pool/internal/domain"
+	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/tasklog"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+// newLogApp runs tasks with exec and serves their logs.
+func newLogApp(t *testing.T, exec workerpool.Executor) (http.Handler, func()) {
+	t.Helper()
+
+	store := memory.New()
+	logs := tasklog.NewStore(tasklog.Limits{})
+	pool := workerpool.New(10, store, workerpool.WithExecutor(exec), workerpool.WithTaskLogs(logs))
+	pool.Start(1)
+
+	svc, err := service.New(store, pool)
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+
+	router := approuter.New(handlers.New(svc), approuter.WithTaskLogs(handlers.NewLogHandler(svc, logs)))
+
+	cleanup := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = pool.Shutdown(ctx)
+	}
+	return router, cleanup
+}
+
+func TestGET_TaskLogs(t *testing.T) {
+	exec := func(_ context.Context, _ domain.Task, logger *slog.Logger) error {
+		logger.Info("step", "n", 1)
+		return errors.New("boom")
+	}
+	app, cleanup := newLogApp(t, exec)
+	defer cleanup()
+
+	created := doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "a"})
+	var task dto.TaskResponse
+	_ = json.NewDecoder(created.Body).Decode(&task)
+	path := "/tasks/" + strconv.FormatInt(task.ID, 10)
+
+	deadline := time.Now().Add(2 * time.Second)
+	var out dto.TaskLogsResponse
+	for {
+		rr := doJSON(t, app, http.MethodGet, path+"/logs", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("status=%d, want 200 body=%s", rr.Code, rr.Body.String())
+		}
+		_ = json.NewDecoder(rr.Body).Decode(&out)
+		if out.Status == string(domain.StatusFailed) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if out.Status != string(domain.StatusFailed) || len(out.Lines) != 1 || out.Lines[0].Message != "step" {
+		t.Fatalf("logs=%+v, want failed task with one line", out)
+	}
+
+	if rr := doJSON(t, app, http.MethodGet, "/tasks/999/logs", nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("unknown task status=%d, want 404", rr.Code)
+	}
+}
+
+func TestGET_TaskLogs_Follow(t *testing.T) {
+	release := make(chan struct{})
+	exec := func(_ context.Context, _ domain.Task, logger *slog.Logger) error {
+		logger.Info("first")
+		<-release
+		logger.Info("second")
+		return nil
+	}
+	app, cleanup := newLogApp(t, exec)
+	defer cleanup()
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	created := doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "a"})
+	var task dto.TaskResponse
+	_ = json.NewDecoder(created.Body).Decode(&task)
+
+	resp, err := http.Get(srv.URL + "/tasks/" + strconv.FormatInt(task.ID, 10) + "/logs?follow=1")
+	if err != nil {
+		t.Fatalf("GET err=%v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("Content-Type=%q, want application/x-ndjson", ct)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	var line dto.TaskLogEntry
+	if err := dec.Decode(&line); err != nil || line.Message != "first" {
+		t.Fatalf("first line=%+v err=%v", line, err)
+	}
+
+	// the stream is live: the second line only exists after release
+	close(release)
+	if err := dec.Decode(&line); err != nil || line.Message != "second" || line.Seq != 2 {
+		t.Fatalf("second line=%+v err=%v", line, err)
+	}
+	// and ends with the execution
+	if err := dec.Decode(&line); err != io.EOF {
+		t.Fatalf("after last line err=%v, want EOF", err)
+	}
+}
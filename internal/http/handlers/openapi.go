@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/http/openapi"
+	"io"
+	"net/http"
+)
+
+// maxBodyBytes bounds the request bodies read for validation.
+const maxBodyBytes = 4 << 20
+
+type OpenAPIHandler struct {
+	doc  *openapi.Document
+	spec []byte
+}
+
+func NewOpenAPIHandler(doc *openapi.Document) (*OpenAPIHandler, error) {
+	spec, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{doc: doc, spec: spec}, nil
+}
+
+// GET /openapi.json
+func (h *OpenAPIHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(h.spec)
+}
+
+// GET /docs
+func (h *OpenAPIHandler) Docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(openapi.DocsPage)
+}
+
+// ValidateBody checks the JSON body of the route pattern against its request
+// schema before next sees it, routes without one are left as they are. Bodies
+// that aren't JSON at all are passed on, the handler reports those the way it
+// always did.
+func (h *OpenAPIHandler) ValidateBody(pattern string, next http.Handler) http.Handler {
+	schema, ok := h.doc.RequestSchema(pattern)
+	if !ok {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, r, http.StatusRequestEntityTooLarge, codeRequestTooLarge, "request body too large")
+				return
+			}
+			writeError(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(raw))
+
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var body any
+		if err := dec.Decode(&body); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := h.doc.Validate(schema, body); err != nil {
+			p := dto.Problem{Status: http.StatusBadRequest, Code: codeInvalidRequestBody, Detail: "invalid request body: " + err.Error()}
+			var verr *openapi.ValidationError
+			if errors.As(err, &verr) {
+				p.Errors = []dto.FieldError{{Field: verr.Path, Message: verr.Reason}}
+			}
+			writeProblem(w, r, p)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,107 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"interview-task-worker-pool/internal/dag"
+	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/http/openapi"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+func TestOpenAPI_SpecDocsAndValidation(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store) // not started, tasks stay pending
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	svc, err := service.New(store, pool)
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+	openAPIHandler, err := handlers.NewOpenAPIHandler(openapi.New())
+	if err != nil {
+		t.Fatalf("NewOpenAPIHandler err=%v", err)
+	}
+	app := approuter.New(handlers.New(svc), approuter.WithOpenAPI(openAPIHandler))
+
+	rr := doJSON(t, app, http.MethodGet, "/openapi.json", nil)
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&spec); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json status=%d err=%v", rr.Code, err)
+	}
+	if spec.OpenAPI != "3.1.0" || spec.Paths["/tasks/{id}"]["get"] == nil {
+		t.Fatalf("spec openapi=%q paths=%v, want 3.1.0 with /tasks/{id}", spec.OpenAPI, spec.Paths)
+	}
+	if rr := doJSON(t, app, http.MethodGet, "/docs", nil); rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("GET /docs status=%d content-type=%q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	rr = doRaw(t, app, http.MethodPost, "/tasks", `{"title":"t","concurrency_limit":-1}`)
+	var body map[string]string
+	_ = json.NewDecoder(rr.Body).Decode(&body)
+	if rr.Code != http.StatusBadRequest || body["error"] != "invalid request body: concurrency_limit: must be at least 0" {
+		t.Fatalf("invalid body status=%d body=%v, want 400 naming concurrency_limit", rr.Code, body)
+	}
+	rr = doRaw(t, app, http.MethodPost, "/tasks/batch", `{"tasks":[{"title":"a"},{"title":7}]}`)
+	body = nil
+	_ = json.NewDecoder(rr.Body).Decode(&body)
+	if rr.Code != http.StatusBadRequest || body["error"] != "invalid request body: tasks[1].title: must be a string" {
+		t.Fatalf("invalid batch status=%d body=%v, want 400 naming tasks[1].title", rr.Code, body)
+	}
+
+	// valid bodies reach the handler untouched
+	if rr := doRaw(t, app, http.MethodPost, "/tasks", `{"title":"t","description":"d"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("valid body status=%d, want 201", rr.Code)
+	}
+	if rr := doRaw(t, app, http.MethodPost, "/tasks", `{"title":`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("malformed body status=%d, want 400", rr.Code)
+	}
+}
+
+func TestOpenAPI_NullOptionalFields(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store) // not started, tasks stay pending
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	svc, err := service.New(store, pool, service.WithResolver(dag.NewResolver(store, pool)))
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+	openAPIHandler, err := handlers.NewOpenAPIHandler(openapi.New())
+	if err != nil {
+		t.Fatalf("NewOpenAPIHandler err=%v", err)
+	}
+	app := approuter.New(handlers.New(svc), approuter.WithOpenAPI(openAPIHandler))
+
+	// null on an optional field is the field left out, versioned or not
+	for _, prefix := range []string{"", "/v1"} {
+		rr := doRaw(t, app, http.MethodPost, prefix+"/tasks",
+			`{"title":"t","description":null,"depends_on":null,"retain_until":null,"metadata":null}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("POST %s/tasks with nulls status=%d, want 201 body=%s", prefix, rr.Code, rr.Body.String())
+		}
+		var created dto.TaskResponse
+		_ = json.NewDecoder(rr.Body).Decode(&created)
+
+		rr = doRaw(t, app, http.MethodPatch, prefix+"/tasks/"+strconv.FormatInt(created.ID, 10), `{"title":"renamed","description":null}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("PATCH %s/tasks/{id} with null status=%d, want 200 body=%s", prefix, rr.Code, rr.Body.String())
+		}
+	}
+
+	// a required field is still required
+	if rr := doRaw(t, app, http.MethodPost, "/v1/tasks", `{"title":null}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("POST with a null title status=%d, want 400", rr.Code)
+	}
+}
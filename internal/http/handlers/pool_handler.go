@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"interview-task-worker-pool/internal/auth"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/workerpool"
+	"net/http"
+)
+
+type PoolStats interface {
+	ConcurrencyStats() []workerpool.KeyStats
+}
+
+type PoolHandler struct {
+	pool PoolStats
+}
+
+func NewPoolHandler(pool PoolStats) *PoolHandler {
+	return &PoolHandler{pool: pool}
+}
+
+// GET /pool/concurrency
+func (h *PoolHandler) Concurrency(w http.ResponseWriter, r *http.Request) {
+	stats := h.pool.ConcurrencyStats()
+	// keys are per owner, a client only sees its own
+	p, scoped := auth.PrincipalFromContext(r.Context())
+	scoped = scoped && !p.IsAdmin()
+
+	response := make([]dto.ConcurrencyKeyResponse, 0, len(stats))
+	for _, s := range stats {
+		if scoped && s.Owner != p.ID {
+			continue
+		}
+		response = append(response, dto.ConcurrencyKeyResponse{
+			Owner:   s.Owner,
+			Key:     s.Key,
+			Running: s.Running,
+			Waiting: s.Waiting,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
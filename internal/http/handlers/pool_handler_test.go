@@ -0,0 +1,108 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"interview-task-worker-pool/internal/auth"
+	"interview-task-worker-pool/internal/health"
+	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+func TestGET_PoolConcurrency_OK(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store)
+	pool.Start(0)
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	svc, _ := service.New(store, pool)
+	app := approuter.New(handlers.New(svc), approuter.WithPool(handlers.NewPoolHandler(pool)))
+
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/pool/concurrency", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status=%d, want %d body=%s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var out []dto.ConcurrencyKeyResponse
+	if err := json.NewDecoder(rr.Body).Decode(&out); err != nil {
+		t.Fatalf("decode err=%v", err)
+	}
+}
+
+type poolStats []workerpool.KeyStats
+
+func (s poolStats) ConcurrencyStats() []workerpool.KeyStats { return s }
+
+func TestGET_PoolConcurrency_OwnKeysOnly(t *testing.T) {
+	keys := auth.NewKeyStore()
+	_ = keys.AddSpecs("alice:a-key,ops:o-key:admin")
+	stats := poolStats{{Owner: "alice", Key: "printer", Running: 1}, {Owner: "bob", Key: "printer", Running: 1, Waiting: 2}}
+	app := approuter.New(handlers.New(nil),
+		approuter.WithAuth(handlers.Authenticate(keys)),
+		approuter.WithPool(handlers.NewPoolHandler(stats)),
+	)
+
+	get := func(key string) []dto.ConcurrencyKeyResponse {
+		req := httptest.NewRequest(http.MethodGet, "/pool/concurrency", nil)
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		app.ServeHTTP(rr, req)
+
+		var out []dto.ConcurrencyKeyResponse
+		_ = json.NewDecoder(rr.Body).Decode(&out)
+		return out
+	}
+	if out := get("a-key"); len(out) != 1 || out[0].Owner != "alice" {
+		t.Fatalf("alice's keys=%+v, want only her printer", out)
+	}
+	if out := get("o-key"); len(out) != 2 {
+		t.Fatalf("admin keys=%+v, want both owners", out)
+	}
+}
+
+func TestGET_Readyz_FailsAfterPoolShutdown(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store)
+	pool.Start(1)
+
+	svc, err := service.New(store, pool)
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+
+	checks := health.New(time.Second)
+	checks.Register(health.PoolOpen(pool), health.Store(store), health.NewQueueSaturation(pool, 0.9, time.Minute))
+	app := approuter.New(handlers.New(svc), approuter.WithHealth(checks))
+
+	if rr := doJSON(t, app, http.MethodGet, "/readyz", nil); rr.Code != http.StatusOK {
+		t.Fatalf("ready status=%d, want 200 body=%s", rr.Code, rr.Body.String())
+	}
+
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() err=%v", err)
+	}
+
+	rr := doJSON(t, app, http.MethodGet, "/readyz", nil)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status=%d, want 503 body=%s", rr.Code, rr.Body.String())
+	}
+	var report health.Report
+	_ = json.NewDecoder(rr.Body).Decode(&report)
+	if report.Checks["pool"].Status != "fail" || report.Checks["store"].Status != "ok" {
+		t.Fatalf("report=%+v, want pool fail and store ok", report)
+	}
+
+	if rr := doJSON(t, app, http.MethodGet, "/healthz", nil); rr.Code != http.StatusOK {
+		t.Fatalf("healthz status=%d, want 200", rr.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/logging"
+	"interview-task-worker-pool/internal/service"
+	"net/http"
+)
+
+// error codes of the /v1 problem responses, clients switch on them so they
+// don't change once released
+const (
+	codeInvalidJSON        = "invalid_json"
+	codeInvalidRequestBody = "invalid_request_body"
+	codeInvalidInput       = "invalid_input" // invalid_<field> when the field is known, e.g. invalid_title
+	codeInvalidID          = "invalid_id"
+	codeRequestTooLarge    = "request_too_large"
+	codeBatchTooLarge      = "batch_too_large"
+	codeUnauthorized       = "unauthorized"
+	codeMissingScope       = "missing_scope"
+	codeTaskNotFound       = "task_not_found"
+	codeBatchNotFound      = "batch_not_found"
+	codeAPIKeyNotFound     = "api_key_not_found"
+	codeIdempotencyReused  = "idempotency_key_reused"
+	codeDuplicateTask      = "duplicate_task"
+	codeInvalidDependency  = "invalid_dependency"
+	codeTaskNotPending     = "task_not_pending"
+	codeHasDependents      = "task_has_dependents"
+	codeVersionConflict    = "version_conflict"
+	codePreconditionFailed = "precondition_failed"
+	codeBatchRejected      = "batch_rejected"
+	codeQuotaExceeded      = "quota_exceeded"
+	codeRateLimited        = "rate_limited"
+	codePoolFull           = "pool_full"
+	codePoolClosed         = "pool_closed"
+	codeInternal           = "internal_error"
+)
+
+type versionKey struct{}
+
+// V1 marks the requests of the /v1 routes, their errors are written as
+// application/problem+json.
+func V1(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), versionKey{}, 1)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Deprecated marks the unversioned alias of a /v1 route. Its responses keep the
+// {"error": "..."} body and point at the /v1 route.
+func Deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "</v1"+r.URL.Path+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isV1(r *http.Request) bool {
+	v, _ := r.Context().Value(versionKey{}).(int)
+	return v == 1
+}
+
+// writeError answers with code and detail, as a problem on /v1 and as
+// {"error": detail} on the unversioned routes.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	writeProblem(w, r, dto.Problem{Status: status, Code: code, Detail: detail})
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, p dto.Problem) {
+	if !isV1(r) {
+		writeJSON(w, p.Status, map[string]string{"error": p.Detail})
+		return
+	}
+
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	p.Instance = r.URL.Path
+	p.RequestID = logging.RequestIDFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// invalidInput answers 400 for a service.ErrInvalidInput, naming the field
+// when the service did.
+func invalidInput(w http.ResponseWriter, r *http.Request, err error) {
+	p := dto.Problem{Status: http.StatusBadRequest, Code: inputCode(err), Detail: service.ErrInvalidInput.Error()}
+
+	var inputErr *service.InputError
+	if errors.As(err, &inputErr) {
+		p.Errors = []dto.FieldError{{Field: inputErr.Field, Message: inputErr.Reason}}
+	}
+	writeProblem(w, r, p)
+}
+
+// inputCode is the code invalidInput would answer err with.
+func inputCode(err error) string {
+	var inputErr *service.InputError
+	if errors.As(err, &inputErr) {
+		return "invalid_" + inputErr.Field
+	}
+	return codeInvalidInput
+}
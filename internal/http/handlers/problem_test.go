@@ -0,0 +1,84 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/http/openapi"
+	"interview-task-worker-pool/internal/logging"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+func TestV1_ProblemResponsesAndDeprecatedAliases(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store) // not started, tasks stay pending
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	svc, err := service.New(store, pool)
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+	openAPIHandler, err := handlers.NewOpenAPIHandler(openapi.New())
+	if err != nil {
+		t.Fatalf("NewOpenAPIHandler err=%v", err)
+	}
+	app := approuter.New(handlers.New(svc),
+		approuter.WithOpenAPI(openAPIHandler),
+		approuter.WithMiddleware(logging.Middleware(slog.New(slog.NewTextHandler(io.Discard, nil)))),
+	)
+
+	decodeProblem := func(rr *httptest.ResponseRecorder) dto.Problem {
+		t.Helper()
+		if ct := rr.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Fatalf("content-type=%q, want application/problem+json body=%s", ct, rr.Body.String())
+		}
+		var p dto.Problem
+		if err := json.NewDecoder(rr.Body).Decode(&p); err != nil {
+			t.Fatalf("decode problem err=%v", err)
+		}
+		return p
+	}
+
+	rr := doJSON(t, app, http.MethodPost, "/v1/tasks", map[string]any{"title": " "})
+	p := decodeProblem(rr)
+	if rr.Code != http.StatusBadRequest || p.Status != http.StatusBadRequest || p.Code != "invalid_title" || p.Instance != "/v1/tasks" {
+		t.Fatalf("empty title status=%d problem=%+v, want 400 invalid_title", rr.Code, p)
+	}
+	if len(p.Errors) != 1 || p.Errors[0].Field != "title" || p.RequestID == "" || p.RequestID != rr.Header().Get(logging.RequestIDHeader) {
+		t.Fatalf("empty title problem=%+v, want a title field error and the request id", p)
+	}
+
+	rr = doRaw(t, app, http.MethodPost, "/v1/tasks/batch", `{"tasks":[{"title":"a"},{"title":7}]}`)
+	if p := decodeProblem(rr); p.Code != "invalid_request_body" || len(p.Errors) != 1 || p.Errors[0].Field != "tasks[1].title" {
+		t.Fatalf("invalid batch problem=%+v, want invalid_request_body on tasks[1].title", p)
+	}
+	if p := decodeProblem(doJSON(t, app, http.MethodGet, "/v1/tasks/99", nil)); p.Status != http.StatusNotFound || p.Code != "task_not_found" {
+		t.Fatalf("missing task problem=%+v, want 404 task_not_found", p)
+	}
+
+	rr = doJSON(t, app, http.MethodPost, "/v1/tasks", map[string]any{"title": "t"})
+	if rr.Code != http.StatusCreated || rr.Header().Get("Deprecation") != "" {
+		t.Fatalf("v1 create status=%d deprecation=%q, want 201 and not deprecated", rr.Code, rr.Header().Get("Deprecation"))
+	}
+
+	// the unversioned alias keeps the old error body
+	rr = doJSON(t, app, http.MethodGet, "/tasks/99", nil)
+	var body map[string]string
+	_ = json.NewDecoder(rr.Body).Decode(&body)
+	if rr.Code != http.StatusNotFound || body["error"] != service.ErrNotFound.Error() {
+		t.Fatalf("legacy status=%d body=%v, want 404 {\"error\": %q}", rr.Code, body, service.ErrNotFound.Error())
+	}
+	if rr.Header().Get("Deprecation") != "true" || rr.Header().Get("Link") != `</v1/tasks/99>; rel="successor-version"` {
+		t.Fatalf("legacy headers deprecation=%q link=%q", rr.Header().Get("Deprecation"), rr.Header().Get("Link"))
+	}
+}
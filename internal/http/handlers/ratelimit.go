@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"interview-task-worker-pool/internal/auth"
+	"interview-task-worker-pool/internal/ratelimit"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type rateCostKey struct{}
+
+// RateCost makes the rate limit take cost(r) tokens for a request instead of one.
+func RateCost(cost func(r *http.Request) int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), rateCostKey{}, cost(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RateLimit limits requests per client with the client's tier: the
+// authenticated principal, or the remote IP when auth is off. The bucket state
+// goes out as X-RateLimit-* headers, a refused request is 429 with Retry-After.
+// A request costs one token, or what RateCost set for it. One costing more than
+// the bucket holds can never pass and is 413 instead.
+func RateLimit(limiter *ratelimit.Limiter, policy *ratelimit.Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var client string
+			if p, ok := auth.PrincipalFromContext(r.Context()); ok {
+				client = p.ID
+			}
+			key := ratelimit.Key(client, remoteIP(r))
+
+			n := 1
+			if cost, ok := r.Context().Value(rateCostKey{}).(int); ok && cost > 1 {
+				n = cost
+			}
+
+			d := limiter.AllowN(key, policy.For(client), n)
+			if d.Limit == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+			if d.TooLarge {
+				writeError(w, r, http.StatusRequestEntityTooLarge, codeBatchTooLarge,
+					fmt.Sprintf("batch of %d tasks, the rate limit allows at most %d at once", n, d.Limit))
+				return
+			}
+			if !d.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(d.RetryAfter)))
+				writeError(w, r, http.StatusTooManyRequests, codeRateLimited, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"interview-task-worker-pool/internal/auth"
+	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/http/openapi"
+	"interview-task-worker-pool/internal/ratelimit"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+func TestPOST_Tasks_RateLimitAndQuota_429(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store) // not started, tasks stay pending
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	tiers, err := ratelimit.ParsePolicy("default=0.001:2:0,small=0:0:1", "bob=small")
+	if err != nil {
+		t.Fatalf("ParsePolicy err=%v", err)
+	}
+	svc, err := service.New(store, pool, service.WithQuota(tiers.MaxPending))
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+	keys := auth.NewKeyStore()
+	_ = keys.AddSpecs("alice:a-key,bob:b-key")
+
+	app := approuter.New(handlers.New(svc),
+		approuter.WithAuth(handlers.Authenticate(keys)),
+		approuter.WithRateLimit(handlers.RateLimit(ratelimit.NewLimiter(), tiers)),
+	)
+	create := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/tasks", bytes.NewBufferString(`{"title":"t"}`))
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		app.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		rr := create("a-key")
+		if rr.Code != http.StatusCreated || rr.Header().Get("X-RateLimit-Limit") != "2" || rr.Header().Get("X-RateLimit-Remaining") != strconv.Itoa(1-i) {
+			t.Fatalf("create #%d status=%d headers=%v", i, rr.Code, rr.Header())
+		}
+	}
+	rr := create("a-key")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("over rate status=%d headers=%v, want 429 with Retry-After", rr.Code, rr.Header())
+	}
+
+	// bob's tier has no rate limit but one pending task at most
+	if rr := create("b-key"); rr.Code != http.StatusCreated || rr.Header().Get("X-RateLimit-Limit") != "" {
+		t.Fatalf("bob status=%d headers=%v, want 201 without rate headers", rr.Code, rr.Header())
+	}
+	rr = create("b-key")
+	var body map[string]string
+	_ = json.NewDecoder(rr.Body).Decode(&body)
+	if rr.Code != http.StatusTooManyRequests || body["error"] != service.ErrQuotaExceeded.Error() {
+		t.Fatalf("over quota status=%d body=%v, want 429", rr.Code, body)
+	}
+
+	// reads aren't rate limited
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	req.Header.Set("X-API-Key", "a-key")
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list status=%d, want 200", rr.Code)
+	}
+}
+
+func TestPOST_TasksBatch_RateLimitPerTask(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store) // not started, tasks stay pending
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	tiers, _ := ratelimit.ParsePolicy("default=0.001:3:0", "")
+	svc, _ := service.New(store, pool)
+	openAPIHandler, err := handlers.NewOpenAPIHandler(openapi.New())
+	if err != nil {
+		t.Fatalf("NewOpenAPIHandler err=%v", err)
+	}
+	app := approuter.New(handlers.New(svc),
+		approuter.WithOpenAPI(openAPIHandler),
+		approuter.WithRateLimit(handlers.RateLimit(ratelimit.NewLimiter(), tiers)),
+	)
+
+	batchTo := func(path string, n int) *httptest.ResponseRecorder {
+		tasks := make([]map[string]any, n)
+		for i := range tasks {
+			tasks[i] = map[string]any{"title": "t"}
+		}
+		return doJSON(t, app, http.MethodPost, path, map[string]any{"tasks": tasks})
+	}
+	batch := func(n int) *httptest.ResponseRecorder { return batchTo("/tasks/batch", n) }
+
+	// a batch takes a token per task, the body still reaches the handler
+	if rr := batch(2); rr.Code != http.StatusCreated || rr.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("batch of 2 status=%d headers=%v body=%s, want 201 with 1 token left", rr.Code, rr.Header(), rr.Body.String())
+	}
+	if rr := batch(2); rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("batch of 2 status=%d headers=%v, want 429 with Retry-After", rr.Code, rr.Header())
+	}
+	// more than the burst never fits, it's not a retry later
+	if rr := batch(4); rr.Code != http.StatusRequestEntityTooLarge || rr.Header().Get("Retry-After") != "" ||
+		!strings.Contains(rr.Body.String(), "at most 3") {
+		t.Fatalf("batch of 4 status=%d headers=%v body=%s, want 413 without Retry-After", rr.Code, rr.Header(), rr.Body.String())
+	}
+	rr := batchTo("/v1/tasks/batch", 4)
+	var p dto.Problem
+	_ = json.NewDecoder(rr.Body).Decode(&p)
+	if rr.Code != http.StatusRequestEntityTooLarge || p.Code != "batch_too_large" {
+		t.Fatalf("/v1 batch of 4 status=%d problem=%+v, want 413 batch_too_large", rr.Code, p)
+	}
+	if rr := batch(1); rr.Code != http.StatusCreated {
+		t.Fatalf("batch of 1 status=%d body=%s, want 201", rr.Code, rr.Body.String())
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"interview-task-worker-pool/internal/domain"
@@ -9,12 +10,18 @@ import (
 	"interview-task-worker-pool/internal/workerpool"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type TaskService interface {
-	CreateTask(title, description string) (domain.Task, error)
-	GetTask(id int64) (domain.Task, error)
-	ListTasks() ([]domain.Task, error)
+	CreateTask(ctx context.Context, in service.CreateTaskInput) (service.CreateTaskResult, error)
+	GetTask(ctx context.Context, id int64) (domain.Task, error)
+	ListTasks(ctx context.Context, filter service.TaskFilter) ([]domain.Task, error)
+	TaskGraph(ctx context.Context, id int64) ([]domain.Task, error)
+	CreateBatch(ctx context.Context, inputs []service.CreateTaskInput, mode service.BatchMode) (service.CreateBatchResult, error)
+	GetBatch(ctx context.Context, id int64) (service.BatchProgress, error)
+	UpdateTask(ctx context.Context, id int64, in service.UpdateTaskInput) (domain.Task, error)
+	DeleteTask(ctx context.Context, id int64, version int64) error
 }
 
 type TaskHandler struct {
@@ -29,50 +36,51 @@ func New(taskService TaskService) *TaskHandler {
 func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req dto.CreateTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
 
 		return
 	}
 
-	task, err := h.taskService.CreateTask(req.Title, req.Description)
+	in := toCreateInput(req)
+	in.IdempotencyKey = r.Header.Get("Idempotency-Key")
+
+	res, err := h.taskService.CreateTask(r.Context(), in)
+	if res.Replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrInvalidInput):
-			writeError(w, http.StatusBadRequest, service.ErrInvalidInput.Error())
-			return
-		case errors.Is(err, workerpool.ErrPoolFull):
-			writeJSON(w, http.StatusServiceUnavailable, dto.TaskResponse{
-				ID:          task.ID,
-				Title:       task.Title,
-				Description: task.Description,
-				Status:      string(task.Status),
-				Error:       task.Error,
-			})
-			return
-		case errors.Is(err, workerpool.ErrPoolClosed):
-			writeJSON(w, http.StatusServiceUnavailable, dto.TaskResponse{
-				ID:          task.ID,
-				Title:       task.Title,
-				Description: task.Description,
-				Status:      string(task.Status),
-				Error:       task.Error,
-			})
+			invalidInput(w, r, err)
+			return
+		case errors.Is(err, service.ErrIdempotencyConflict):
+			writeError(w, r, http.StatusUnprocessableEntity, codeIdempotencyReused, service.ErrIdempotencyConflict.Error())
+			return
+		case errors.Is(err, service.ErrDuplicate):
+			writeError(w, r, http.StatusConflict, codeDuplicateTask, service.ErrDuplicate.Error())
+			return
+		case errors.Is(err, service.ErrInvalidDependency):
+			writeError(w, r, http.StatusUnprocessableEntity, codeInvalidDependency, err.Error())
+			return
+		case errors.Is(err, service.ErrQuotaExceeded):
+			writeError(w, r, http.StatusTooManyRequests, codeQuotaExceeded, service.ErrQuotaExceeded.Error())
+			return
+		case errors.Is(err, workerpool.ErrPoolFull), errors.Is(err, workerpool.ErrPoolClosed):
+			poolUnavailable(w, r, err, res.Task)
 			return
 		default:
-			writeError(w, http.StatusInternalServerError, "internal server error")
+			writeError(w, r, http.StatusInternalServerError, codeInternal, "internal server error")
 			return
 		}
 	}
 
-	response := dto.TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		Status:      string(task.Status),
-		Error:       task.Error,
+	// attached to an in-flight task with the same dedupe key, nothing was created
+	if res.Attached {
+		writeTask(w, http.StatusOK, res.Task)
+		return
 	}
 
-	writeJSON(w, http.StatusCreated, response)
+	writeTask(w, http.StatusCreated, res.Task)
 }
 
 // GET /tasks/{id}
@@ -80,42 +88,187 @@ func (h *TaskHandler) Get(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		writeError(w, http.StatusBadRequest, service.ErrInvalidID.Error())
+		writeError(w, r, http.StatusBadRequest, codeInvalidID, service.ErrInvalidID.Error())
 
 		return
 	}
 
-	task, err := h.taskService.GetTask(id)
+	task, err := h.taskService.GetTask(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrInvalidID):
-			writeError(w, http.StatusBadRequest, service.ErrInvalidID.Error())
+			writeError(w, r, http.StatusBadRequest, codeInvalidID, service.ErrInvalidID.Error())
 			return
 		case errors.Is(err, service.ErrNotFound):
-			writeError(w, http.StatusNotFound, service.ErrNotFound.Error())
+			writeError(w, r, http.StatusNotFound, codeTaskNotFound, service.ErrNotFound.Error())
 			return
 		default:
-			writeError(w, http.StatusInternalServerError, "failed getting task")
+			writeError(w, r, http.StatusInternalServerError, codeInternal, "failed getting task")
 			return
 		}
 	}
 
-	response := dto.TaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		Status:      string(task.Status),
-		Error:       task.Error,
+	// polling clients send the tag they have and get an empty 304 until it changes
+	if noneMatch(r, etag(task.Version)) {
+		w.Header().Set("ETag", etag(task.Version))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeTask(w, http.StatusOK, task)
+}
+
+// PATCH /tasks/{id}
+//
+// Edits title, description, priority or metadata until the task starts. The
+// version edited comes from If-Match, or the body for clients that can't set it.
+func (h *TaskHandler) Update(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, http.StatusBadRequest, codeInvalidID, service.ErrInvalidID.Error())
+
+		return
+	}
+
+	var req dto.UpdateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidJSON, err.Error())
+
+		return
+	}
+
+	version, ok := ifMatch(r)
+	if !ok {
+		preconditionFailed(w, r)
+		return
+	}
+	conditional := r.Header.Get("If-Match") != ""
+	if !conditional {
+		version = req.Version
+	}
+
+	task, err := h.taskService.UpdateTask(r.Context(), id, service.UpdateTaskInput{
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		Metadata:    req.Metadata,
+		Version:     version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			invalidInput(w, r, err)
+			return
+		case errors.Is(err, service.ErrNotFound):
+			writeError(w, r, http.StatusNotFound, codeTaskNotFound, service.ErrNotFound.Error())
+			return
+		case errors.Is(err, service.ErrNotPending):
+			writeError(w, r, http.StatusConflict, codeTaskNotPending, service.ErrNotPending.Error())
+			return
+		case errors.Is(err, service.ErrVersionConflict) && conditional:
+			preconditionFailed(w, r)
+			return
+		case errors.Is(err, service.ErrVersionConflict):
+			writeError(w, r, http.StatusConflict, codeVersionConflict, service.ErrVersionConflict.Error())
+			return
+		default:
+			writeError(w, r, http.StatusInternalServerError, codeInternal, "failed updating task")
+			return
+		}
+	}
+
+	writeTask(w, http.StatusOK, task)
+}
+
+// DELETE /tasks/{id}
+//
+// Removes a finished task, unfinished ones are canceled first.
+func (h *TaskHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, http.StatusBadRequest, codeInvalidID, service.ErrInvalidID.Error())
+
+		return
+	}
+
+	version, ok := ifMatch(r)
+	if !ok {
+		preconditionFailed(w, r)
+		return
+	}
+
+	if err := h.taskService.DeleteTask(r.Context(), id, version); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			writeError(w, r, http.StatusNotFound, codeTaskNotFound, service.ErrNotFound.Error())
+			return
+		case errors.Is(err, service.ErrVersionConflict):
+			preconditionFailed(w, r)
+			return
+		case errors.Is(err, service.ErrHasDependents):
+			writeError(w, r, http.StatusConflict, codeHasDependents, service.ErrHasDependents.Error())
+			return
+		default:
+			writeError(w, r, http.StatusInternalServerError, codeInternal, "failed deleting task")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /tasks/{id}/graph
+func (h *TaskHandler) Graph(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, r, http.StatusBadRequest, codeInvalidID, service.ErrInvalidID.Error())
+
+		return
+	}
+
+	tasks, err := h.taskService.TaskGraph(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidID):
+			writeError(w, r, http.StatusBadRequest, codeInvalidID, service.ErrInvalidID.Error())
+			return
+		case errors.Is(err, service.ErrNotFound):
+			writeError(w, r, http.StatusNotFound, codeTaskNotFound, service.ErrNotFound.Error())
+			return
+		default:
+			writeError(w, r, http.StatusInternalServerError, codeInternal, "failed getting task graph")
+			return
+		}
+	}
+
+	response := dto.TaskGraphResponse{Root: id, Nodes: make([]dto.TaskGraphNode, 0, len(tasks))}
+	for _, task := range tasks {
+		dependsOn := task.DependsOn
+		if dependsOn == nil {
+			dependsOn = []int64{}
+		}
+		response.Nodes = append(response.Nodes, dto.TaskGraphNode{
+			ID:        task.ID,
+			Title:     task.Title,
+			Status:    string(task.Status),
+			DependsOn: dependsOn,
+		})
 	}
 
 	writeJSON(w, http.StatusOK, response)
 }
 
 // GET /tasks
+//
+// Lists the caller's tasks, admins see everyone's and can narrow it with ?owner=.
 func (h *TaskHandler) List(w http.ResponseWriter, r *http.Request) {
-	tasks, err := h.taskService.ListTasks()
+	filter := service.TaskFilter{Owner: r.URL.Query().Get("owner")}
+
+	tasks, err := h.taskService.ListTasks(r.Context(), filter)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed getting tasks")
+		writeError(w, r, http.StatusInternalServerError, codeInternal, "failed getting tasks")
 
 		return
 	}
@@ -126,8 +279,87 @@ func (h *TaskHandler) List(w http.ResponseWriter, r *http.Request) {
 			ID:     task.ID,
 			Title:  task.Title,
 			Status: string(task.Status),
+			Owner:  task.Owner,
 		})
 	}
 
 	writeJSON(w, http.StatusOK, response)
 }
+
+// writeTask answers with the task and its ETag.
+func writeTask(w http.ResponseWriter, status int, task domain.Task) {
+	w.Header().Set("ETag", etag(task.Version))
+	writeJSON(w, status, toTaskResponse(task))
+}
+
+func preconditionFailed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusPreconditionFailed, codePreconditionFailed, "task does not match If-Match")
+}
+
+// poolUnavailable answers 503 for a task stored as failed because the pool
+// refused it, the body carries the failed task.
+func poolUnavailable(w http.ResponseWriter, r *http.Request, err error, task domain.Task) {
+	response := toTaskResponse(task)
+	if !isV1(r) {
+		writeJSON(w, http.StatusServiceUnavailable, response)
+		return
+	}
+
+	code := codePoolFull
+	if errors.Is(err, workerpool.ErrPoolClosed) {
+		code = codePoolClosed
+	}
+	writeProblem(w, r, dto.Problem{Status: http.StatusServiceUnavailable, Code: code, Detail: err.Error(), Task: &response})
+}
+
+func toTaskResponse(task domain.Task) dto.TaskResponse {
+	return dto.TaskResponse{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      string(task.Status),
+		Error:       task.Error,
+		Owner:       task.Owner,
+		DedupeKey:   task.DedupeKey,
+
+		ConcurrencyKey:   task.ConcurrencyKey,
+		ConcurrencyLimit: task.ConcurrencyLimit,
+
+		DependsOn: task.DependsOn,
+
+		Priority: task.Priority,
+		Metadata: task.Metadata,
+		Version:  task.Version,
+
+		FinishedAt:  optionalTime(task.FinishedAt),
+		RetainUntil: optionalTime(task.RetainUntil),
+	}
+}
+
+// optionalTime leaves zero times out of the response.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func toCreateInput(req dto.CreateTaskRequest) service.CreateTaskInput {
+	in := service.CreateTaskInput{
+		Title:       req.Title,
+		Description: req.Description,
+		DedupeKey:   req.DedupeKey,
+
+		ConcurrencyKey:   req.ConcurrencyKey,
+		ConcurrencyLimit: req.ConcurrencyLimit,
+
+		DependsOn: req.DependsOn,
+
+		Priority: req.Priority,
+		Metadata: req.Metadata,
+	}
+	if req.RetainUntil != nil {
+		in.RetainUntil = *req.RetainUntil
+	}
+	return in
+}
@@ -4,15 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"interview-task-worker-pool/internal/domain"
-	"interview-task-worker-pool/internal/http/dto"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"interview-task-worker-pool/internal/dag"
+	"interview-task-worker-pool/internal/domain"
 	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/dto"
 	"interview-task-worker-pool/internal/http/handlers"
 	"interview-task-worker-pool/internal/service"
 	"interview-task-worker-pool/internal/store/memory"
@@ -24,9 +26,12 @@ func newApp(t *testing.T, poolSize int, workers int) (http.Handler, func()) {
 
 	store := memory.New()
 	pool := workerpool.New(poolSize, store)
+
+	resolver := dag.NewResolver(store, pool)
+	pool.OnFinish(resolver.Resolve)
 	pool.Start(workers)
 
-	svc, err := service.New(store, pool)
+	svc, err := service.New(store, pool, service.WithResolver(resolver))
 	if err != nil {
 		t.Fatalf("service.New err=%v", err)
 	}
@@ -269,3 +274,226 @@ func TestPOST_Tasks_PoolClosed_503(t *testing.T) {
 		t.Fatalf("status=%d, want %d body=%s", rr.Code, http.StatusServiceUnavailable, rr.Body.String())
 	}
 }
+
+func doWithKey(t *testing.T, h http.Handler, key string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		t.Fatalf("encode body err=%v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/tasks", &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	rr := httptest.NewRecorder()
+
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPOST_Tasks_IdempotencyKey_Replay(t *testing.T) {
+	app, cleanup := newApp(t, 10, 0)
+	defer cleanup()
+
+	body := map[string]any{"title": "T", "description": "D"}
+
+	first := doWithKey(t, app, "retry-1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status=%d body=%s", first.Code, first.Body.String())
+	}
+	second := doWithKey(t, app, "retry-1", body)
+	if second.Code != http.StatusCreated {
+		t.Fatalf("second status=%d, want %d body=%s", second.Code, http.StatusCreated, second.Body.String())
+	}
+
+	var a, b dto.TaskResponse
+	_ = json.NewDecoder(first.Body).Decode(&a)
+	_ = json.NewDecoder(second.Body).Decode(&b)
+	if a.ID != b.ID {
+		t.Fatalf("replay id=%d, want %d", b.ID, a.ID)
+	}
+}
+
+func TestPOST_Tasks_IdempotencyKey_PoolFullReplay_503(t *testing.T) {
+	app, cleanup := newApp(t, 1, 0)
+	defer cleanup()
+
+	_ = doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "fill"})
+
+	body := map[string]any{"title": "T"}
+	first := doWithKey(t, app, "retry-2", body)
+	second := doWithKey(t, app, "retry-2", body)
+	if first.Code != http.StatusServiceUnavailable || second.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status first=%d second=%d, want %d", first.Code, second.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestPOST_Tasks_IdempotencyKey_AttachedReplay_200(t *testing.T) {
+	app, cleanup := newApp(t, 10, 0)
+	defer cleanup()
+
+	_ = doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "A", "dedupe_key": "job-1"})
+
+	// the original call attached to the in-flight task, its retry answers the same
+	body := map[string]any{"title": "A", "dedupe_key": "job-1"}
+	first := doWithKey(t, app, "retry-4", body)
+	second := doWithKey(t, app, "retry-4", body)
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("status first=%d second=%d, want %d", first.Code, second.Code, http.StatusOK)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("Idempotent-Replayed=%q, want true", second.Header().Get("Idempotent-Replayed"))
+	}
+}
+
+func TestPOST_Tasks_IdempotencyKey_DifferentBody_422(t *testing.T) {
+	app, cleanup := newApp(t, 10, 0)
+	defer cleanup()
+
+	_ = doWithKey(t, app, "retry-3", map[string]any{"title": "A"})
+	rr := doWithKey(t, app, "retry-3", map[string]any{"title": "B"})
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status=%d, want %d body=%s", rr.Code, http.StatusUnprocessableEntity, rr.Body.String())
+	}
+}
+
+func TestPOST_Tasks_DedupeKey_AttachesToInFlight(t *testing.T) {
+	app, cleanup := newApp(t, 10, 0)
+	defer cleanup()
+
+	first := doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "A", "dedupe_key": "job-1"})
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status=%d body=%s", first.Code, first.Body.String())
+	}
+	second := doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "A", "dedupe_key": "job-1"})
+	if second.Code != http.StatusOK {
+		t.Fatalf("second status=%d, want %d body=%s", second.Code, http.StatusOK, second.Body.String())
+	}
+
+	var a, b dto.TaskResponse
+	_ = json.NewDecoder(first.Body).Decode(&a)
+	_ = json.NewDecoder(second.Body).Decode(&b)
+	if a.ID != b.ID {
+		t.Fatalf("attached id=%d, want %d", b.ID, a.ID)
+	}
+}
+
+func TestPOST_Tasks_DependsOn_BlockedAndGraph(t *testing.T) {
+	app, cleanup := newApp(t, 10, 0)
+	defer cleanup()
+
+	parent := doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "A"})
+	var a dto.TaskResponse
+	_ = json.NewDecoder(parent.Body).Decode(&a)
+
+	child := doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "B", "depends_on": []int64{a.ID}})
+	if child.Code != http.StatusCreated {
+		t.Fatalf("status=%d, want %d body=%s", child.Code, http.StatusCreated, child.Body.String())
+	}
+	var b dto.TaskResponse
+	_ = json.NewDecoder(child.Body).Decode(&b)
+	if b.Status != string(domain.StatusBlocked) {
+		t.Fatalf("status=%q, want %q", b.Status, domain.StatusBlocked)
+	}
+
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/tasks/"+strconv.FormatInt(a.ID, 10)+"/graph", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("graph status=%d, want %d body=%s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var graph dto.TaskGraphResponse
+	if err := json.NewDecoder(rr.Body).Decode(&graph); err != nil {
+		t.Fatalf("decode err=%v", err)
+	}
+	if len(graph.Nodes) != 2 || graph.Nodes[1].ID != b.ID || graph.Nodes[1].DependsOn[0] != a.ID {
+		t.Fatalf("graph=%+v, want A and B with B depending on A", graph)
+	}
+}
+
+func TestPOST_Tasks_DependsOn_Unknown_422(t *testing.T) {
+	app, cleanup := newApp(t, 10, 0)
+	defer cleanup()
+
+	rr := doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "B", "depends_on": []int64{4242}})
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status=%d, want %d body=%s", rr.Code, http.StatusUnprocessableEntity, rr.Body.String())
+	}
+}
+
+func TestPATCH_DELETE_Task(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store) // not started, tasks stay pending
+	defer func() { _ = pool.Shutdown(context.Background()) }()
+
+	svc, err := service.New(store, pool)
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+	app := approuter.New(handlers.New(svc))
+
+	var created dto.TaskResponse
+	_ = json.NewDecoder(doJSON(t, app, http.MethodPost, "/v1/tasks", map[string]any{"title": "t", "priority": 1}).Body).Decode(&created)
+	if created.Version != 1 || created.Priority != 1 {
+		t.Fatalf("created=%+v, want version 1 priority 1", created)
+	}
+	path := "/v1/tasks/" + strconv.FormatInt(created.ID, 10)
+
+	rr := doJSON(t, app, http.MethodPatch, path, map[string]any{"title": "renamed", "metadata": map[string]string{"team": "a"}, "version": 1})
+	var updated dto.TaskResponse
+	_ = json.NewDecoder(rr.Body).Decode(&updated)
+	if rr.Code != http.StatusOK || updated.Title != "renamed" || updated.Metadata["team"] != "a" || updated.Priority != 1 || updated.Version != 2 {
+		t.Fatalf("PATCH status=%d task=%+v, want renamed with metadata, version 2", rr.Code, updated)
+	}
+
+	// edited against version 1, which is gone
+	rr = doJSON(t, app, http.MethodPatch, path, map[string]any{"title": "again", "version": 1})
+	var p dto.Problem
+	_ = json.NewDecoder(rr.Body).Decode(&p)
+	if rr.Code != http.StatusConflict || p.Code != "version_conflict" {
+		t.Fatalf("stale PATCH status=%d problem=%+v, want 409 version_conflict", rr.Code, p)
+	}
+	if rr := doJSON(t, app, http.MethodPatch, path, map[string]any{"title": " "}); rr.Code != http.StatusBadRequest {
+		t.Fatalf("empty title PATCH status=%d, want 400", rr.Code)
+	}
+
+	if rr := doJSON(t, app, http.MethodDelete, path, nil); rr.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status=%d, want 204 body=%s", rr.Code, rr.Body.String())
+	}
+	if rr := doJSON(t, app, http.MethodGet, path, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("GET after DELETE status=%d, want 404", rr.Code)
+	}
+	if rr := doJSON(t, app, http.MethodDelete, path, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("second DELETE status=%d, want 404", rr.Code)
+	}
+}
+
+func TestDELETE_Task_ParentOfBlocked_409(t *testing.T) {
+	store := memory.New()
+	pool := workerpool.New(10, store)
+	resolver := dag.NewResolver(store, pool)
+	svc, err := service.New(store, pool, service.WithResolver(resolver))
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+	app := approuter.New(handlers.New(svc))
+
+	var a, b dto.TaskResponse
+	_ = json.NewDecoder(doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "A"}).Body).Decode(&a)
+	_ = json.NewDecoder(doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "B"}).Body).Decode(&b)
+	child := doJSON(t, app, http.MethodPost, "/tasks", map[string]any{"title": "C", "depends_on": []int64{a.ID, b.ID}})
+	if child.Code != http.StatusCreated {
+		t.Fatalf("status=%d, want %d body=%s", child.Code, http.StatusCreated, child.Body.String())
+	}
+	_, _ = store.UpdateStatus(a.ID, domain.StatusDone)
+	resolver.Resolve(a.ID, domain.StatusDone)
+
+	rr := doJSON(t, app, http.MethodDelete, "/v1/tasks/"+strconv.FormatInt(a.ID, 10), nil)
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "task_has_dependents") {
+		t.Fatalf("status=%d body=%s, want 409 task_has_dependents", rr.Code, rr.Body.String())
+	}
+	if _, ok := store.Get(a.ID); !ok {
+		t.Fatal("parent deleted, want kept for its blocked child")
+	}
+}
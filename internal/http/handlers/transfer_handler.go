@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"interview-task-worker-pool/internal/transfer"
+	"net/http"
+	"strconv"
+)
+
+type TransferStore interface {
+	transfer.Source
+	transfer.Target
+}
+
+type TransferHandler struct {
+	store TransferStore
+}
+
+func NewTransferHandler(store TransferStore) *TransferHandler {
+	return &TransferHandler{store: store}
+}
+
+// GET /admin/export
+//
+// Streams the store as NDJSON, a failure halfway just ends the stream early.
+func (h *TransferHandler) Export(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="tasks.ndjson"`)
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = transfer.Export(w, h.store)
+}
+
+// POST /admin/import?policy=skip|overwrite|renumber&dry_run=true
+func (h *TransferHandler) Import(w http.ResponseWriter, r *http.Request) {
+	opts := transfer.Options{Policy: transfer.Policy(r.URL.Query().Get("policy"))}
+	if opts.Policy != "" && !opts.Policy.Valid() {
+		writeError(w, r, http.StatusBadRequest, codeInvalidInput, "policy: must be skip, overwrite or renumber")
+		return
+	}
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, codeInvalidInput, "dry_run: must be true or false")
+			return
+		}
+		opts.DryRun = dryRun
+	}
+
+	report, err := transfer.Import(h.store, r.Body, opts)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeInvalidInput, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
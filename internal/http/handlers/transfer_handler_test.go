@@ -0,0 +1,75 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"interview-task-worker-pool/internal/domain"
+	approuter "interview-task-worker-pool/internal/http"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/transfer"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+func TestAdmin_ExportImport(t *testing.T) {
+	newApp := func() (*memory.TaskStore, http.Handler) {
+		store := memory.New()
+		pool := workerpool.New(10, store)
+		svc, err := service.New(store, pool)
+		if err != nil {
+			t.Fatalf("service.New err=%v", err)
+		}
+		return store, approuter.New(handlers.New(svc),
+			approuter.WithTransfer(handlers.NewTransferHandler(store), "s3cret"))
+	}
+	do := func(app http.Handler, method, target string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, body)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rr := httptest.NewRecorder()
+		app.ServeHTTP(rr, req)
+		return rr
+	}
+
+	src, from := newApp()
+	for i := 0; i < 3; i++ {
+		created, _ := src.Create(domain.Task{Title: "t"})
+		_, _ = src.UpdateStatus(created.ID, domain.StatusDone)
+	}
+	_, _ = src.Delete(3, 0)
+
+	exported := do(from, http.MethodGet, "/v1/admin/export", nil)
+	if exported.Code != http.StatusOK || exported.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("export status=%d content-type=%q, want 200 NDJSON", exported.Code, exported.Header().Get("Content-Type"))
+	}
+	stream := exported.Body.String()
+
+	dst, to := newApp()
+	if rr := do(to, http.MethodPost, "/v1/admin/import?policy=merge", strings.NewReader(stream)); rr.Code != http.StatusBadRequest {
+		t.Fatalf("import(policy=merge) status=%d, want 400", rr.Code)
+	}
+
+	var report transfer.Report
+	rr := do(to, http.MethodPost, "/v1/admin/import?dry_run=true", strings.NewReader(stream))
+	_ = json.NewDecoder(rr.Body).Decode(&report)
+	if rr.Code != http.StatusOK || !report.DryRun || report.Created != 2 {
+		t.Fatalf("import(dry run) status=%d body=%+v, want 2 would be created", rr.Code, report)
+	}
+	if n, _ := dst.Count(); n != 0 {
+		t.Fatalf("Count() after dry run = %d, want 0", n)
+	}
+
+	rr = do(to, http.MethodPost, "/v1/admin/import", strings.NewReader(stream))
+	_ = json.NewDecoder(rr.Body).Decode(&report)
+	if rr.Code != http.StatusOK || report.Created != 2 || report.LastID != 3 {
+		t.Fatalf("import status=%d body=%+v, want 2 created and last_id 3", rr.Code, report)
+	}
+	if created, _ := dst.Create(domain.Task{Title: "new"}); created.ID != 4 {
+		t.Fatalf("Create() id = %d after import, want 4", created.ID)
+	}
+}
@@ -0,0 +1,187 @@
+package openapi
+
+import (
+	"interview-task-worker-pool/internal/health"
+	"interview-task-worker-pool/internal/http/dto"
+	"interview-task-worker-pool/internal/transfer"
+	"net/http"
+	"reflect"
+)
+
+// New returns the document of every route internal/http.New can register.
+func New() *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:   "Task Worker Pool API",
+			Version: "1.0.0",
+			Description: "Submit tasks to a bounded worker pool and follow them. Routes live under /v1 and fail with " +
+				"application/problem+json carrying a stable code, the unversioned aliases are deprecated and fail with {\"error\": \"...\"}.",
+		},
+		Paths: make(map[string]PathItem),
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Error": {Type: "object", Properties: map[string]*Schema{"error": {Type: "string"}}, Required: []string{"error"}},
+			},
+			SecuritySchemes: map[string]SecurityScheme{
+				"apiKey":     {Type: "apiKey", In: "header", Name: "X-API-Key"},
+				"bearerAuth": {Type: "http", Scheme: "bearer", Description: "HS256/RS256 JWT or API key"},
+				"adminToken": {Type: "http", Scheme: "bearer", Description: "ADMIN_TOKEN, also accepted in X-Admin-Token"},
+			},
+		},
+	}
+	b := &builder{doc: doc, r: &reflector{components: doc.Components.Schemas}}
+	b.r.schemaOf(reflect.TypeOf(dto.Problem{}))
+
+	// tasks
+	b.versioned("POST /tasks", "createTask", "Create a task and enqueue it",
+		tags("tasks"), scope("tasks:create"), body(dto.CreateTaskRequest{}),
+		header("Idempotency-Key", "Retries with the same key return the original task"),
+		returns(http.StatusCreated, "Task created", dto.TaskResponse{}),
+		returns(http.StatusOK, "Attached to the in-flight task with the same dedupe_key", dto.TaskResponse{}),
+		etag(http.StatusCreated, http.StatusOK),
+		failsOr(http.StatusServiceUnavailable, "Pool full or closed, the task is stored as failed", dto.TaskResponse{}),
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity, http.StatusTooManyRequests),
+	)
+	b.versioned("POST /tasks/batch", "createBatch", "Create tasks in one batch",
+		tags("tasks"), scope("tasks:create"), body(dto.CreateBatchRequest{}),
+		returns(http.StatusCreated, "Batch created, per item statuses", dto.CreateBatchResponse{}),
+		returns(http.StatusUnprocessableEntity, "all_or_nothing batch rejected, nothing created", dto.CreateBatchResponse{}),
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests),
+	)
+	b.versioned("GET /tasks", "listTasks", "List the caller's tasks",
+		tags("tasks"), scope("tasks:read"),
+		query("owner", "Only tasks of this owner (admins see every owner)", &Schema{Type: "string"}),
+		returns(http.StatusOK, "Tasks", []dto.TaskSummaryResponse{}),
+		fails(http.StatusUnauthorized, http.StatusForbidden),
+	)
+	b.versioned("GET /tasks/{id}", "getTask", "Get a task",
+		tags("tasks"), scope("tasks:read"),
+		header("If-None-Match", "ETag the client has, answered with 304 while it is current"),
+		returns(http.StatusOK, "Task", dto.TaskResponse{}),
+		returns(http.StatusNotModified, "The If-None-Match tag is current", nil),
+		etag(http.StatusOK, http.StatusNotModified),
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+	)
+	b.versioned("PATCH /tasks/{id}", "updateTask", "Edit a task that hasn't started yet",
+		tags("tasks"), scope("tasks:create"), body(dto.UpdateTaskRequest{}),
+		header("If-Match", "ETag of the version edited, takes precedence over the body's version"),
+		returns(http.StatusOK, "Task updated", dto.TaskResponse{}),
+		etag(http.StatusOK),
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusPreconditionFailed),
+	)
+	b.versioned("DELETE /tasks/{id}", "deleteTask", "Delete a task, canceling it first if it hasn't finished",
+		tags("tasks"), scope("tasks:cancel"),
+		header("If-Match", "ETag of the version to delete"),
+		returns(http.StatusNoContent, "Deleted", nil),
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed),
+	)
+	b.versioned("GET /tasks/{id}/graph", "getTaskGraph", "Get the dependency graph around a task",
+		tags("tasks"), scope("tasks:read"),
+		returns(http.StatusOK, "Graph", dto.TaskGraphResponse{}),
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+	)
+	b.versioned("GET /tasks/{id}/logs", "getTaskLogs", "Get the execution log of a task",
+		tags("tasks"), scope("tasks:read"),
+		query("follow", "Stream new lines as NDJSON until the task ends", &Schema{Type: "boolean"}),
+		returns(http.StatusOK, "Log lines so far, or a stream of lines with follow", dto.TaskLogsResponse{}),
+		also(http.StatusOK, "application/x-ndjson", dto.TaskLogEntry{}),
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+	)
+	b.versioned("GET /batches/{id}", "getBatch", "Get the progress of a batch",
+		tags("tasks"), scope("tasks:read"),
+		returns(http.StatusOK, "Batch progress", dto.BatchResponse{}),
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+	)
+	b.versioned("GET /pool/concurrency", "getConcurrency", "Running and waiting tasks per concurrency key",
+		tags("tasks"), scope("tasks:read"),
+		returns(http.StatusOK, "Busy concurrency keys", []dto.ConcurrencyKeyResponse{}),
+		fails(http.StatusUnauthorized, http.StatusForbidden),
+	)
+
+	// api keys, served with authentication on
+	b.versioned("POST /api-keys", "createAPIKey", "Create an API key, the key is only returned here",
+		tags("api-keys"), scope("admin"), body(dto.CreateAPIKeyRequest{}),
+		returns(http.StatusCreated, "Key created", dto.APIKeyResponse{}),
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestEntityTooLarge),
+	)
+	b.versioned("GET /api-keys", "listAPIKeys", "List API keys without their secrets",
+		tags("api-keys"), scope("admin"),
+		returns(http.StatusOK, "Keys", []dto.APIKeyResponse{}),
+		fails(http.StatusUnauthorized, http.StatusForbidden),
+	)
+	b.versioned("DELETE /api-keys/{id}", "revokeAPIKey", "Revoke an API key",
+		tags("api-keys"), scope("admin"), stringID("id"),
+		returns(http.StatusNoContent, "Revoked", nil),
+		fails(http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound),
+	)
+
+	// admin, served when ADMIN_TOKEN is set
+	b.versioned("GET /admin/pool", "getPool", "Workers, queue and pause state",
+		tags("admin"), adminToken(),
+		returns(http.StatusOK, "Pool state", dto.AdminPoolResponse{}),
+		fails(http.StatusUnauthorized),
+	)
+	b.versioned("POST /admin/pool/pause", "pausePool", "Stop workers from taking queued tasks",
+		tags("admin"), adminToken(),
+		returns(http.StatusOK, "Pool state", dto.AdminPoolResponse{}),
+		fails(http.StatusUnauthorized, http.StatusConflict),
+	)
+	b.versioned("POST /admin/pool/resume", "resumePool", "Let workers take queued tasks again",
+		tags("admin"), adminToken(),
+		returns(http.StatusOK, "Pool state", dto.AdminPoolResponse{}),
+		fails(http.StatusUnauthorized),
+	)
+	b.versioned("POST /admin/pool/drain", "drainPool", "Close the pool and work off the queue",
+		tags("admin"), adminToken(),
+		returns(http.StatusAccepted, "Pool state", dto.AdminPoolResponse{}),
+		fails(http.StatusUnauthorized),
+	)
+	b.versioned("GET /admin/queue", "getQueue", "Queued task ids and depth per tenant",
+		tags("admin"), adminToken(),
+		returns(http.StatusOK, "Queue", dto.AdminQueueResponse{}),
+		fails(http.StatusUnauthorized),
+	)
+
+	b.versioned("GET /admin/export", "exportTasks", "Every task as NDJSON, after a header line with the id state",
+		tags("admin"), adminToken(),
+		text(http.StatusOK, "A header line (format, last_id), then one task per line by id", "application/x-ndjson"),
+		fails(http.StatusUnauthorized),
+	)
+	b.versioned("POST /admin/import", "importTasks", "Load an export (or archive file) into the store",
+		tags("admin"), adminToken(),
+		stream("application/x-ndjson", "An export of GET /admin/export or an archive file, decompressed"),
+		query("policy", "What to do with a task whose id is taken, skip by default", &Schema{Type: "string", Enum: []string{"skip", "overwrite", "renumber"}}),
+		query("dry_run", "Only validate and report what would happen", &Schema{Type: "boolean"}),
+		returns(http.StatusOK, "What was imported, or would be on a dry run", transfer.Report{}),
+		fails(http.StatusBadRequest, http.StatusUnauthorized),
+	)
+
+	// served when ARCHIVE_DIR is set too
+	b.versioned("POST /admin/archive/restore", "restoreArchived", "Put archived tasks back into the live store",
+		tags("admin"), adminToken(), body(dto.ArchiveRestoreRequest{}),
+		returns(http.StatusOK, "Restored and skipped ids", dto.ArchiveRestoreResponse{}),
+		fails(http.StatusBadRequest, http.StatusUnauthorized),
+	)
+
+	// operations
+	b.add("GET /healthz", "liveness", "Liveness probe",
+		tags("ops"), returns(http.StatusOK, "Alive", health.Report{}),
+	)
+	b.add("GET /readyz", "readiness", "Readiness probe",
+		tags("ops"),
+		returns(http.StatusOK, "Ready", health.Report{}),
+		returns(http.StatusServiceUnavailable, "A check failed", health.Report{}),
+	)
+	b.add("GET /metrics", "metrics", "Prometheus metrics",
+		tags("ops"), text(http.StatusOK, "Text exposition format", "text/plain"),
+	)
+	b.add("GET /openapi.json", "openapi", "This document",
+		tags("ops"), returns(http.StatusOK, "OpenAPI 3.1 document", map[string]any{}),
+	)
+	b.add("GET /docs", "docs", "API documentation page",
+		tags("ops"), text(http.StatusOK, "HTML page rendering this document", "text/html"),
+	)
+
+	return doc
+}
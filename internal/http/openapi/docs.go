@@ -0,0 +1,9 @@
+package openapi
+
+import _ "embed"
+
+// DocsPage renders the document served at /openapi.json. It is self-contained,
+// no scripts or styles are loaded from elsewhere.
+//
+//go:embed docs.html
+var DocsPage []byte
@@ -0,0 +1,154 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, body string) any {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode(%s) err=%v, want nil", body, err)
+	}
+	return v
+}
+
+func TestNew_SchemasFollowDTOs(t *testing.T) {
+	doc := New()
+
+	create, ok := doc.Components.Schemas["CreateTaskRequest"]
+	if !ok {
+		t.Fatalf("components=%v, want CreateTaskRequest", doc.Components.Schemas)
+	}
+	if !slices.Equal(create.Required, []string{"title"}) {
+		t.Fatalf("CreateTaskRequest required=%v, want [title]", create.Required)
+	}
+	if id := doc.Components.Schemas["TaskResponse"].Properties["id"]; id.Type != "integer" || id.Format != "int64" {
+		t.Fatalf("TaskResponse.id=%+v, want integer int64", id)
+	}
+	if status := doc.Components.Schemas["TaskResponse"].Properties["status"]; !slices.Contains(status.Enum, "pending") {
+		t.Fatalf("TaskResponse.status enum=%v, want the task statuses", status.Enum)
+	}
+	if _, ok := doc.RequestSchema("POST /tasks"); !ok {
+		t.Fatalf("RequestSchema(POST /tasks) ok=false, want true")
+	}
+	if _, ok := doc.RequestSchema("GET /tasks"); ok {
+		t.Fatalf("RequestSchema(GET /tasks) ok=true, want false")
+	}
+}
+
+func TestNew_VersionedRoutes(t *testing.T) {
+	doc := New()
+
+	v1, ok := doc.Operation("GET /v1/tasks/{id}")
+	if !ok || v1.Deprecated || v1.OperationID != "getTask" {
+		t.Fatalf("Operation(GET /v1/tasks/{id})=%+v ok=%v, want getTask not deprecated", v1, ok)
+	}
+	if _, ok := v1.Responses["404"].Content["application/problem+json"]; !ok {
+		t.Fatalf("v1 404 content=%v, want application/problem+json", v1.Responses["404"].Content)
+	}
+
+	legacy, ok := doc.Operation("GET /tasks/{id}")
+	if !ok || !legacy.Deprecated || legacy.OperationID != "getTaskLegacy" {
+		t.Fatalf("Operation(GET /tasks/{id})=%+v ok=%v, want getTaskLegacy deprecated", legacy, ok)
+	}
+	if _, ok := legacy.Responses["404"].Content["application/json"]; !ok {
+		t.Fatalf("legacy 404 content=%v, want application/json", legacy.Responses["404"].Content)
+	}
+
+	if op, ok := doc.Operation("GET /healthz"); !ok || op.Deprecated {
+		t.Fatalf("Operation(GET /healthz)=%+v ok=%v, want it unversioned", op, ok)
+	}
+	if _, ok := doc.Operation("GET /v1/healthz"); ok {
+		t.Fatalf("Operation(GET /v1/healthz) ok=true, want probes unversioned")
+	}
+}
+
+func TestNew_RefsResolveAndDocumentIs31(t *testing.T) {
+	raw, err := json.Marshal(New())
+	if err != nil {
+		t.Fatalf("Marshal() err=%v, want nil", err)
+	}
+
+	var out struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("Unmarshal() err=%v, want nil", err)
+	}
+	if out.OpenAPI != "3.1.0" {
+		t.Fatalf("openapi=%q, want 3.1.0", out.OpenAPI)
+	}
+
+	for _, ref := range bytes.Split(raw, []byte(`"$ref":"`))[1:] {
+		name := strings.TrimPrefix(string(ref[:bytes.IndexByte(ref, '"')]), "#/components/schemas/")
+		if _, ok := out.Components.Schemas[name]; !ok {
+			t.Fatalf("$ref %q doesn't resolve", name)
+		}
+	}
+}
+
+func TestValidate_CreateTaskRequest(t *testing.T) {
+	doc := New()
+	schema, _ := doc.RequestSchema("POST /tasks")
+
+	tests := []struct {
+		body  string
+		valid bool
+		path  string
+	}{
+		{body: `{"title":"a"}`, valid: true},
+		{body: `{"title":"a","depends_on":[1,2],"unknown":true}`, valid: true},
+		{body: `{"title":"a","description":null,"depends_on":null,"retain_until":null}`, valid: true},
+		{body: `{"title":null}`, path: "title"},
+		{body: `{}`, path: "title"},
+		{body: `{"title":""}`, path: "title"},
+		{body: `{"title":3}`, path: "title"},
+		{body: `{"title":"a","concurrency_limit":-1}`, path: "concurrency_limit"},
+		{body: `{"title":"a","concurrency_limit":1.5}`, path: "concurrency_limit"},
+		{body: `{"title":"a","depends_on":[1,"2"]}`, path: "depends_on[1]"},
+		{body: `[]`, path: ""},
+	}
+	for _, tt := range tests {
+		err := doc.Validate(schema, decode(t, tt.body))
+		var verr *ValidationError
+		switch {
+		case tt.valid:
+			if err != nil {
+				t.Fatalf("Validate(%s) err=%v, want nil", tt.body, err)
+			}
+		case !errors.As(err, &verr) || verr.Path != tt.path:
+			t.Fatalf("Validate(%s) err=%v, want a validation error at %q", tt.body, err, tt.path)
+		}
+	}
+}
+
+func TestValidate_BatchRequest(t *testing.T) {
+	doc := New()
+	schema, _ := doc.RequestSchema("POST /tasks/batch")
+
+	if err := doc.Validate(schema, decode(t, `{"mode":"all_or_nothing","tasks":[{"title":"a"}]}`)); err != nil {
+		t.Fatalf("Validate() err=%v, want nil", err)
+	}
+	err := doc.Validate(schema, decode(t, `{"mode":"sometimes","tasks":[{"title":"a"}]}`))
+	if err == nil || err.Error() != "mode: must be one of all_or_nothing, best_effort" {
+		t.Fatalf("Validate(bad mode) err=%v, want the mode enum", err)
+	}
+	err = doc.Validate(schema, decode(t, `{"tasks":[{"title":"a"},{"description":"b"}]}`))
+	if err == nil || err.Error() != "tasks[1].title: is required" {
+		t.Fatalf("Validate(item without title) err=%v, want tasks[1].title required", err)
+	}
+	if err := doc.Validate(schema, decode(t, `{"tasks":[]}`)); err == nil {
+		t.Fatalf("Validate(empty batch) err=nil, want minItems error")
+	}
+}
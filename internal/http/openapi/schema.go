@@ -0,0 +1,134 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema (2020-12, as used by OpenAPI 3.1) the
+// spec and the request validation need.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// reflector builds schemas from Go types. Named structs become components and
+// are referenced by $ref, so the spec follows the dto types as they change.
+//
+// Fields follow their json tags: a field without omitempty is required. The
+// openapi tag adds constraints, comma separated: optional, minLength=1,
+// maxLength=255, minimum=0, minItems=1, maxItems=100 and enum=a|b.
+type reflector struct {
+	components map[string]*Schema
+}
+
+func (r *reflector) schemaOf(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: r.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaOf(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		if _, ok := r.components[t.Name()]; !ok {
+			r.components[t.Name()] = nil // taken before recursing, for self references
+			r.components[t.Name()] = r.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	return &Schema{}
+}
+
+func (r *reflector) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := r.schemaOf(f.Type)
+		required := !strings.Contains(opts, "omitempty")
+		if tag := f.Tag.Get("openapi"); tag != "" {
+			required = applyTag(prop, tag) && required
+		}
+
+		s.Properties[name] = prop
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// applyTag adds the constraints of an openapi tag, it reports false for optional.
+func applyTag(s *Schema, tag string) bool {
+	required := true
+	for _, opt := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(opt, "=")
+		n, _ := strconv.Atoi(value)
+		switch key {
+		case "optional":
+			required = false
+		case "minLength":
+			s.MinLength = &n
+		case "maxLength":
+			s.MaxLength = &n
+		case "minItems":
+			s.MinItems = &n
+		case "maxItems":
+			s.MaxItems = &n
+		case "minimum":
+			f := float64(n)
+			s.Minimum = &f
+		case "enum":
+			s.Enum = strings.Split(value, "|")
+		}
+	}
+	return required
+}
@@ -0,0 +1,310 @@
+// Package openapi builds the OpenAPI 3.1 document of the HTTP API and validates
+// request bodies against it. Schemas are reflected from the dto types.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case methods to their operation.
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query or header
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Operation returns the operation of a route pattern like "GET /tasks/{id}".
+func (d *Document) Operation(pattern string) (*Operation, bool) {
+	method, path, _ := strings.Cut(pattern, " ")
+	op, ok := d.Paths[path][strings.ToLower(method)]
+	return op, ok
+}
+
+// RequestSchema is the JSON body schema of a route, if it takes one.
+func (d *Document) RequestSchema(pattern string) (*Schema, bool) {
+	op, ok := d.Operation(pattern)
+	if !ok || op.RequestBody == nil {
+		return nil, false
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	return media.Schema, ok
+}
+
+// Patterns lists the documented routes as net/http patterns, sorted.
+func (d *Document) Patterns() []string {
+	var patterns []string
+	for path, item := range d.Paths {
+		for method := range item {
+			patterns = append(patterns, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// builder adds operations to a document, reflecting the Go types they use.
+type builder struct {
+	doc *Document
+	r   *reflector
+
+	problems bool // fails documents problem+json bodies instead of {"error": "..."}
+}
+
+type opOption func(b *builder, op *Operation)
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// add documents the route pattern ("METHOD /path"). Path parameters are int64
+// ids unless an option says otherwise.
+func (b *builder) add(pattern, id, summary string, opts ...opOption) {
+	method, path, _ := strings.Cut(pattern, " ")
+
+	op := &Operation{OperationID: id, Summary: summary, Responses: make(map[string]*Response)}
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: m[1], In: "path", Required: true,
+			Schema: &Schema{Type: "integer", Format: "int64"},
+		})
+	}
+	for _, opt := range opts {
+		opt(b, op)
+	}
+
+	if b.doc.Paths[path] == nil {
+		b.doc.Paths[path] = make(PathItem)
+	}
+	b.doc.Paths[path][strings.ToLower(method)] = op
+}
+
+// versioned documents the route under /v1, failing with problem+json, and its
+// deprecated unversioned alias with the {"error": "..."} body.
+func (b *builder) versioned(pattern, id, summary string, opts ...opOption) {
+	method, path, _ := strings.Cut(pattern, " ")
+
+	b.problems = true
+	b.add(method+" /v1"+path, id, summary, opts...)
+	b.problems = false
+
+	b.add(pattern, id+"Legacy", summary+" (deprecated, use /v1"+path+")", append(opts, deprecated())...)
+}
+
+func deprecated() opOption {
+	return func(_ *builder, op *Operation) {
+		op.Deprecated = true
+	}
+}
+
+func tags(t ...string) opOption {
+	return func(_ *builder, op *Operation) {
+		op.Tags = append(op.Tags, t...)
+	}
+}
+
+// body is a required JSON request body shaped like v.
+func body(v any) opOption {
+	return func(b *builder, op *Operation) {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: b.r.schemaOf(reflect.TypeOf(v))}},
+		}
+	}
+}
+
+// stream is a required request body that isn't JSON, e.g. NDJSON. It is not validated.
+func stream(contentType, description string) opOption {
+	return func(_ *builder, op *Operation) {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentType: {Schema: &Schema{Type: "string", Description: description}}},
+		}
+	}
+}
+
+// returns documents a JSON response shaped like v, no content when v is nil.
+func returns(status int, description string, v any) opOption {
+	return func(b *builder, op *Operation) {
+		resp := &Response{Description: description}
+		if v != nil {
+			resp.Content = map[string]MediaType{"application/json": {Schema: b.r.schemaOf(reflect.TypeOf(v))}}
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+	}
+}
+
+// also adds a content type to an already documented response.
+func also(status int, contentType string, v any) opOption {
+	return func(b *builder, op *Operation) {
+		var schema *Schema
+		switch v := v.(type) {
+		case *Schema:
+			schema = v
+		default:
+			schema = b.r.schemaOf(reflect.TypeOf(v))
+		}
+		op.Responses[strconv.Itoa(status)].Content[contentType] = MediaType{Schema: schema}
+	}
+}
+
+func text(status int, description, contentType string) opOption {
+	return func(_ *builder, op *Operation) {
+		op.Responses[strconv.Itoa(status)] = &Response{
+			Description: description,
+			Content:     map[string]MediaType{contentType: {Schema: &Schema{Type: "string"}}},
+		}
+	}
+}
+
+var errorDescriptions = map[int]string{
+	http.StatusBadRequest:            "Invalid request (malformed JSON, body not matching the schema, bad id)",
+	http.StatusUnauthorized:          "Missing or invalid credentials",
+	http.StatusForbidden:             "The caller lacks the route's scope",
+	http.StatusNotFound:              "Not found, or owned by another client",
+	http.StatusConflict:              "Conflicts with the current state",
+	http.StatusUnprocessableEntity:   "Semantically invalid request",
+	http.StatusRequestEntityTooLarge: "Request body too large",
+	http.StatusPreconditionFailed:    "If-Match doesn't match the current version",
+	http.StatusTooManyRequests:       "Rate limit or pending task quota exceeded",
+}
+
+// fails documents error responses, problem+json on /v1 routes and the
+// {"error": "..."} body on the others.
+func fails(statuses ...int) opOption {
+	return func(b *builder, op *Operation) {
+		content := map[string]MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}}}
+		if b.problems {
+			content = map[string]MediaType{"application/problem+json": {Schema: &Schema{Ref: "#/components/schemas/Problem"}}}
+		}
+		for _, status := range statuses {
+			op.Responses[strconv.Itoa(status)] = &Response{Description: errorDescriptions[status], Content: content}
+		}
+	}
+}
+
+// failsOr documents an error status answered with a JSON body shaped like v on
+// the unversioned route, and with a problem (carrying v) on /v1.
+func failsOr(status int, description string, v any) opOption {
+	return func(b *builder, op *Operation) {
+		returns(status, description, v)(b, op)
+		if b.problems {
+			op.Responses[strconv.Itoa(status)].Content = map[string]MediaType{
+				"application/problem+json": {Schema: &Schema{Ref: "#/components/schemas/Problem"}},
+			}
+		}
+	}
+}
+
+// etag documents the ETag header (the task version) on already documented responses.
+func etag(statuses ...int) opOption {
+	return func(_ *builder, op *Operation) {
+		for _, status := range statuses {
+			op.Responses[strconv.Itoa(status)].Headers = map[string]Header{
+				"ETag": {Description: "Version of the task, for If-Match and If-None-Match", Schema: &Schema{Type: "string"}},
+			}
+		}
+	}
+}
+
+func query(name, description string, schema *Schema) opOption {
+	return func(_ *builder, op *Operation) {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Description: description, Schema: schema})
+	}
+}
+
+func header(name, description string) opOption {
+	return func(_ *builder, op *Operation) {
+		op.Parameters = append(op.Parameters, Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}})
+	}
+}
+
+// stringID documents the path parameter name as a string instead of an int64.
+func stringID(name string) opOption {
+	return func(_ *builder, op *Operation) {
+		for i := range op.Parameters {
+			if op.Parameters[i].Name == name && op.Parameters[i].In == "path" {
+				op.Parameters[i].Schema = &Schema{Type: "string"}
+			}
+		}
+	}
+}
+
+// scope requires an API key or a bearer token (JWT or API key) with scope,
+// when authentication is turned on.
+func scope(s string) opOption {
+	return func(_ *builder, op *Operation) {
+		op.Security = []map[string][]string{{"apiKey": {}}, {"bearerAuth": {s}}}
+	}
+}
+
+func adminToken() opOption {
+	return func(_ *builder, op *Operation) {
+		op.Security = []map[string][]string{{"adminToken": {}}}
+	}
+}
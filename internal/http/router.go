@@ -1,16 +1,214 @@
 package router
 
 import (
+	"interview-task-worker-pool/internal/auth"
+	"interview-task-worker-pool/internal/health"
 	"interview-task-worker-pool/internal/http/handlers"
 	"net/http"
+	"strings"
 )
 
-func New(handler *handlers.TaskHandler) http.Handler {
-	mux := http.NewServeMux()
+type routes struct {
+	mux        *http.ServeMux
+	middleware []func(http.Handler) http.Handler
+	patterns   []string // every registered pattern, checked against the OpenAPI spec
 
-	mux.HandleFunc("POST /tasks", handler.Create)
-	mux.HandleFunc("GET /tasks", handler.List)
-	mux.HandleFunc("GET /tasks/{id}", handler.Get)
+	// routes behind authentication, registered once every option has run
+	auth      func(http.Handler) http.Handler
+	rateLimit func(http.Handler) http.Handler
+	protected []route
 
-	return mux
+	// validates JSON request bodies of the routes documented with one
+	openAPI *handlers.OpenAPIHandler
+}
+
+type route struct {
+	pattern  string
+	handler  http.HandlerFunc
+	scope    auth.Scope              // required from the caller when auth is on
+	authOnly bool                    // not served at all without auth
+	limited  bool                    // submits tasks, goes through the rate limit
+	cost     func(*http.Request) int // rate limit tokens the request takes, one when nil
+}
+
+func (r *routes) handle(pattern string, handler http.Handler) {
+	r.mux.Handle(pattern, handler)
+	r.patterns = append(r.patterns, pattern)
+}
+
+// handleVersioned registers pattern under /v1 and keeps the unversioned path
+// as a deprecated alias answering errors the old way.
+func (r *routes) handleVersioned(pattern string, handler http.Handler) {
+	method, path, _ := strings.Cut(pattern, " ")
+	r.handle(method+" /v1"+path, handlers.V1(handler))
+	r.handle(pattern, handlers.Deprecated(handler))
+}
+
+// protect registers a route that needs an authenticated caller with scope when auth is on.
+func (r *routes) protect(pattern string, scope auth.Scope, handler http.HandlerFunc) {
+	r.protected = append(r.protected, route{pattern: pattern, handler: handler, scope: scope})
+}
+
+// Option registers an optional group of routes or a middleware.
+type Option func(r *routes)
+
+func WithPool(handler *handlers.PoolHandler) Option {
+	return func(r *routes) {
+		r.protect("GET /pool/concurrency", auth.ScopeTasksRead, handler.Concurrency)
+	}
+}
+
+// WithTaskLogs serves the execution log of a task at GET /tasks/{id}/logs.
+func WithTaskLogs(handler *handlers.LogHandler) Option {
+	return func(r *routes) {
+		r.protect("GET /tasks/{id}/logs", auth.ScopeTasksRead, handler.Logs)
+	}
+}
+
+// WithAuth requires an authenticated caller on the task routes, holding the
+// route's scope (tasks:create, tasks:read, ...). Probes, metrics and the
+// token-protected /admin API stay outside of it.
+func WithAuth(authenticate func(http.Handler) http.Handler) Option {
+	return func(r *routes) {
+		r.auth = authenticate
+	}
+}
+
+// WithRateLimit limits the routes submitting tasks (POST /tasks, POST /tasks/batch).
+// It runs after authentication, so it can tell clients apart.
+func WithRateLimit(limit func(http.Handler) http.Handler) Option {
+	return func(r *routes) {
+		r.rateLimit = limit
+	}
+}
+
+// WithAPIKeys serves API key management to callers with the admin scope.
+// It needs WithAuth, without it the routes aren't registered.
+func WithAPIKeys(handler *handlers.KeyHandler) Option {
+	return func(r *routes) {
+		r.protected = append(r.protected,
+			route{pattern: "GET /api-keys", handler: handler.List, scope: auth.ScopeAdmin, authOnly: true},
+			route{pattern: "POST /api-keys", handler: handler.Create, scope: auth.ScopeAdmin, authOnly: true},
+			route{pattern: "DELETE /api-keys/{id}", handler: handler.Revoke, scope: auth.ScopeAdmin, authOnly: true},
+		)
+	}
+}
+
+// WithAdmin serves the /admin API, every route requires token.
+// An empty token leaves the admin API off.
+func WithAdmin(handler *handlers.AdminHandler, token string) Option {
+	return func(r *routes) {
+		if token == "" {
+			return
+		}
+		r.handleVersioned("GET /admin/pool", http.HandlerFunc(handlers.RequireToken(token, handler.Pool)))
+		r.handleVersioned("POST /admin/pool/pause", http.HandlerFunc(handlers.RequireToken(token, handler.Pause)))
+		r.handleVersioned("POST /admin/pool/resume", http.HandlerFunc(handlers.RequireToken(token, handler.Resume)))
+		r.handleVersioned("POST /admin/pool/drain", http.HandlerFunc(handlers.RequireToken(token, handler.Drain)))
+		r.handleVersioned("GET /admin/queue", http.HandlerFunc(handlers.RequireToken(token, handler.Queue)))
+	}
+}
+
+// WithArchive serves restoring archived tasks under /admin, behind token like
+// WithAdmin. An empty token leaves it off.
+func WithArchive(handler *handlers.ArchiveHandler, token string) Option {
+	return func(r *routes) {
+		if token == "" {
+			return
+		}
+		r.handleVersioned("POST /admin/archive/restore", http.HandlerFunc(handlers.RequireToken(token, handler.Restore)))
+	}
+}
+
+// WithTransfer serves exporting and importing the whole store under /admin,
+// behind token like WithAdmin. An empty token leaves it off.
+func WithTransfer(handler *handlers.TransferHandler, token string) Option {
+	return func(r *routes) {
+		if token == "" {
+			return
+		}
+		r.handleVersioned("GET /admin/export", http.HandlerFunc(handlers.RequireToken(token, handler.Export)))
+		r.handleVersioned("POST /admin/import", http.HandlerFunc(handlers.RequireToken(token, handler.Import)))
+	}
+}
+
+// WithHealth serves the liveness (GET /healthz) and readiness (GET /readyz) probes.
+func WithHealth(checks *health.Checks) Option {
+	return func(r *routes) {
+		r.handle("GET /healthz", http.HandlerFunc(checks.Liveness))
+		r.handle("GET /readyz", http.HandlerFunc(checks.Readiness))
+	}
+}
+
+// WithMetrics serves the Prometheus exposition at GET /metrics.
+func WithMetrics(handler http.Handler) Option {
+	return func(r *routes) {
+		r.handle("GET /metrics", handler)
+	}
+}
+
+// WithOpenAPI serves the spec at GET /openapi.json and a docs page at GET /docs,
+// and validates request bodies of the task routes against it.
+func WithOpenAPI(handler *handlers.OpenAPIHandler) Option {
+	return func(r *routes) {
+		r.openAPI = handler
+		r.handle("GET /openapi.json", http.HandlerFunc(handler.Spec))
+		r.handle("GET /docs", http.HandlerFunc(handler.Docs))
+	}
+}
+
+// WithMiddleware wraps every route, the first middleware is the outermost one.
+func WithMiddleware(mw ...func(http.Handler) http.Handler) Option {
+	return func(r *routes) {
+		r.middleware = append(r.middleware, mw...)
+	}
+}
+
+func New(handler *handlers.TaskHandler, opts ...Option) http.Handler {
+	r := build(handler, opts...)
+
+	var h http.Handler = r.mux
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+	return h
+}
+
+func build(handler *handlers.TaskHandler, opts ...Option) *routes {
+	r := &routes{mux: http.NewServeMux()}
+
+	r.protected = append(r.protected,
+		route{pattern: "POST /tasks", handler: handler.Create, scope: auth.ScopeTasksCreate, limited: true},
+		route{pattern: "POST /tasks/batch", handler: handler.CreateBatch, scope: auth.ScopeTasksCreate, limited: true, cost: handlers.BatchSize},
+	)
+	r.protect("GET /tasks", auth.ScopeTasksRead, handler.List)
+	r.protect("GET /tasks/{id}", auth.ScopeTasksRead, handler.Get)
+	r.protect("PATCH /tasks/{id}", auth.ScopeTasksCreate, handler.Update)
+	r.protect("DELETE /tasks/{id}", auth.ScopeTasksCancel, handler.Delete)
+	r.protect("GET /tasks/{id}/graph", auth.ScopeTasksRead, handler.Graph)
+	r.protect("GET /batches/{id}", auth.ScopeTasksRead, handler.GetBatch)
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	for _, rt := range r.protected {
+		var h http.Handler = rt.handler
+		if r.openAPI != nil {
+			h = r.openAPI.ValidateBody(rt.pattern, h)
+		}
+		if rt.limited && r.rateLimit != nil {
+			h = r.rateLimit(h)
+			if rt.cost != nil {
+				h = handlers.RateCost(rt.cost, h)
+			}
+		}
+		switch {
+		case r.auth != nil:
+			r.handleVersioned(rt.pattern, r.auth(handlers.RequireScope(rt.scope, h.ServeHTTP)))
+		case !rt.authOnly:
+			r.handleVersioned(rt.pattern, h)
+		}
+	}
+	return r
 }
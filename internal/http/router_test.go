@@ -0,0 +1,48 @@
+package router
+
+import (
+	"interview-task-worker-pool/internal/health"
+	"interview-task-worker-pool/internal/http/handlers"
+	"interview-task-worker-pool/internal/http/openapi"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Every route New can register must be documented, and nothing more.
+func TestRoutes_MatchOpenAPISpec(t *testing.T) {
+	doc := openapi.New()
+	openAPIHandler, err := handlers.NewOpenAPIHandler(doc)
+	if err != nil {
+		t.Fatalf("NewOpenAPIHandler() err=%v, want nil", err)
+	}
+	passThrough := func(next http.Handler) http.Handler { return next }
+
+	r := build(handlers.New(nil),
+		WithPool(handlers.NewPoolHandler(nil)),
+		WithTaskLogs(handlers.NewLogHandler(nil, nil)),
+		WithAuth(passThrough),
+		WithRateLimit(passThrough),
+		WithAPIKeys(handlers.NewKeyHandler(nil)),
+		WithAdmin(handlers.NewAdminHandler(nil), "secret"),
+		WithArchive(handlers.NewArchiveHandler(nil, nil), "secret"),
+		WithTransfer(handlers.NewTransferHandler(nil), "secret"),
+		WithHealth(health.New(time.Second)),
+		WithMetrics(http.NotFoundHandler()),
+		WithOpenAPI(openAPIHandler),
+	)
+
+	registered := slices.Sorted(slices.Values(r.patterns))
+	documented := doc.Patterns()
+	for _, p := range registered {
+		if !slices.Contains(documented, p) {
+			t.Errorf("route %q is registered but not in the OpenAPI spec", p)
+		}
+	}
+	for _, p := range documented {
+		if !slices.Contains(registered, p) {
+			t.Errorf("route %q is in the OpenAPI spec but not registered", p)
+		}
+	}
+}
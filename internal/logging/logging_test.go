@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNew_LevelAndFormat(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger, err := New(&buf, "warn", "json")
+	if err != nil {
+		t.Fatalf("New() err=%v, want nil", err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown", TaskID, 7)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("output is not one JSON line: %q", buf.String())
+	}
+	if line["msg"] != "shown" || line[TaskID] != float64(7) {
+		t.Fatalf("line=%v", line)
+	}
+
+	if _, err := New(&buf, "loud", "json"); err == nil {
+		t.Fatalf("New(level=loud) err=nil, want error")
+	}
+	if _, err := New(&buf, "info", "xml"); err == nil {
+		t.Fatalf("New(format=xml) err=nil, want error")
+	}
+}
+
+func TestMiddleware_RequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := New(&buf, "info", "json")
+
+	var seen string
+	h := Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	// a sane incoming ID is kept
+	req := httptest.NewRequest(http.MethodPost, "/tasks", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if seen != "abc-123" || rr.Header().Get(RequestIDHeader) != "abc-123" {
+		t.Fatalf("context=%q header=%q, want abc-123", seen, rr.Header().Get(RequestIDHeader))
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("access log is not JSON: %q", buf.String())
+	}
+	if line[RequestID] != "abc-123" || line[Status] != float64(http.StatusCreated) || line[Duration] == nil {
+		t.Fatalf("access log=%v", line)
+	}
+
+	// anything else gets a generated one
+	req = httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n"+strings.Repeat("x", 200))
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if len(seen) != 32 || seen != rr.Header().Get(RequestIDHeader) {
+		t.Fatalf("generated id=%q header=%q", seen, rr.Header().Get(RequestIDHeader))
+	}
+}
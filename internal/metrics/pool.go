@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"errors"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/workerpool"
+	"time"
+)
+
+var (
+	taskDurationBuckets = []float64{0.1, 0.5, 1, 2, 3, 4, 5, 7.5, 10, 30, 60}
+	queueWaitBuckets    = []float64{0.001, 0.01, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60}
+)
+
+// PoolState is what the pool gauges are read from at scrape time.
+type PoolState interface {
+	QueueDepth() int
+	QueueCapacity() int
+	Workers() int
+	BusyWorkers() int
+	TenantQueues() []workerpool.TenantStats
+}
+
+// PoolMetrics implements workerpool.Observer.
+type PoolMetrics struct {
+	enqueued  *CounterVec
+	rejected  *CounterVec
+	completed *CounterVec
+	failed    *CounterVec
+
+	duration  *HistogramVec
+	queueWait *HistogramVec
+}
+
+func NewPoolMetrics(r *Registry) *PoolMetrics {
+	return &PoolMetrics{
+		enqueued:  r.NewCounterVec("workerpool_tasks_enqueued_total", "Tasks accepted into the pool queue."),
+		rejected:  r.NewCounterVec("workerpool_tasks_rejected_total", "Tasks refused by the pool.", "reason"),
+		completed: r.NewCounterVec("workerpool_tasks_completed_total", "Tasks finished by a worker."),
+		failed:    r.NewCounterVec("workerpool_tasks_failed_total", "Tasks a worker could not finish."),
+		duration:  r.NewHistogramVec("workerpool_task_duration_seconds", "Task execution time.", taskDurationBuckets),
+		queueWait: r.NewHistogramVec("workerpool_queue_wait_seconds", "Time from enqueue until a worker started the task.", queueWaitBuckets),
+	}
+}
+
+// RegisterPoolGauges adds queue and worker gauges read from pool.
+func RegisterPoolGauges(r *Registry, pool PoolState) {
+	r.NewGaugeFunc("workerpool_queue_depth", "Tasks waiting in the pool queue.", func() float64 {
+		return float64(pool.QueueDepth())
+	})
+	r.NewGaugeFunc("workerpool_queue_capacity", "Capacity of the pool queue (POOL_SIZE).", func() float64 {
+		return float64(pool.QueueCapacity())
+	})
+	r.NewGaugeVecFunc("workerpool_tenant_queue_depth", "Tasks waiting in the pool queue per tenant (task owner).", "tenant", func() map[string]float64 {
+		depths := make(map[string]float64)
+		for _, t := range pool.TenantQueues() {
+			depths[t.Tenant] = float64(t.Queued)
+		}
+		return depths
+	})
+	r.NewGaugeFunc("workerpool_workers_busy", "Workers running a task.", func() float64 {
+		return float64(pool.BusyWorkers())
+	})
+	r.NewGaugeFunc("workerpool_workers_idle", "Workers waiting for a task.", func() float64 {
+		return float64(pool.Workers() - pool.BusyWorkers())
+	})
+}
+
+func (m *PoolMetrics) TaskEnqueued() {
+	m.enqueued.With().Inc()
+}
+
+func (m *PoolMetrics) TaskRejected(err error) {
+	reason := "other"
+	switch {
+	case errors.Is(err, workerpool.ErrPoolFull):
+		reason = "pool_full"
+	case errors.Is(err, workerpool.ErrPoolClosed):
+		reason = "pool_closed"
+	}
+	m.rejected.With(reason).Inc()
+}
+
+func (m *PoolMetrics) TaskStarted(wait time.Duration) {
+	m.queueWait.Observe(wait.Seconds())
+}
+
+func (m *PoolMetrics) TaskFinished(status domain.TaskStatus, took time.Duration) {
+	m.duration.Observe(took.Seconds())
+	if status == domain.StatusDone {
+		m.completed.With().Inc()
+		return
+	}
+	m.failed.With().Inc()
+}
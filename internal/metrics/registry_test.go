@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/workerpool"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() err = %v, want nil", err)
+	}
+	return buf.String()
+}
+
+func assertContains(t *testing.T, out string, lines ...string) {
+	t.Helper()
+
+	for _, line := range lines {
+		if !strings.Contains(out, line+"\n") {
+			t.Fatalf("output does not contain %q:\n%s", line, out)
+		}
+	}
+}
+
+func TestRegistry_Exposition(t *testing.T) {
+	r := NewRegistry()
+
+	c := r.NewCounterVec("jobs_total", "Jobs seen.", "kind")
+	c.With("a").Inc()
+	c.With("a").Add(2)
+	c.With(`q"b`).Inc()
+
+	r.NewGaugeFunc("depth", "Queue depth.", func() float64 { return 7 })
+	r.NewGaugeVecFunc("tenant_depth", "Queue depth per tenant.", "tenant", func() map[string]float64 {
+		return map[string]float64{"b": 2, "a": 1}
+	})
+
+	h := r.NewHistogramVec("latency_seconds", "Latency.", []float64{1, 0.1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	assertContains(t, scrape(t, r),
+		"# HELP jobs_total Jobs seen.",
+		"# TYPE jobs_total counter",
+		`jobs_total{kind="a"} 3`,
+		`jobs_total{kind="q\"b"} 1`,
+		"# TYPE depth gauge",
+		"depth 7",
+		"# TYPE tenant_depth gauge",
+		`tenant_depth{tenant="a"} 1`,
+		`tenant_depth{tenant="b"} 2`,
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{le="0.1"} 1`,
+		`latency_seconds_bucket{le="1"} 2`,
+		`latency_seconds_bucket{le="+Inf"} 3`,
+		"latency_seconds_sum 3.55",
+		"latency_seconds_count 3",
+	)
+}
+
+func TestPoolMetrics(t *testing.T) {
+	r := NewRegistry()
+	m := NewPoolMetrics(r)
+
+	m.TaskEnqueued()
+	m.TaskRejected(workerpool.ErrPoolFull)
+	m.TaskRejected(errors.Join(workerpool.ErrPoolClosed))
+	m.TaskStarted(20 * time.Millisecond)
+	m.TaskFinished(domain.StatusDone, 2*time.Second)
+	m.TaskFinished(domain.StatusFailed, time.Second)
+
+	assertContains(t, scrape(t, r),
+		"workerpool_tasks_enqueued_total 1",
+		`workerpool_tasks_rejected_total{reason="pool_closed"} 1`,
+		`workerpool_tasks_rejected_total{reason="pool_full"} 1`,
+		"workerpool_tasks_completed_total 1",
+		"workerpool_tasks_failed_total 1",
+		"workerpool_task_duration_seconds_count 2",
+		"workerpool_queue_wait_seconds_count 1",
+	)
+}
+
+func TestHTTPMetrics_Middleware_LabelsByRoute(t *testing.T) {
+	r := NewRegistry()
+	m := NewHTTPMetrics(r)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	h := m.Middleware(mux)
+
+	for _, path := range []string{"/tasks/1", "/tasks/2", "/nope"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assertContains(t, scrape(t, r),
+		`http_requests_total{route="GET /tasks/{id}",status="404"} 2`,
+		`http_requests_total{route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{route="GET /tasks/{id}",status="404"} 2`,
+	)
+}
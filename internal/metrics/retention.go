@@ -0,0 +1,18 @@
+package metrics
+
+import "interview-task-worker-pool/internal/domain"
+
+// RetentionMetrics implements retention.Observer.
+type RetentionMetrics struct {
+	evicted *CounterVec
+}
+
+func NewRetentionMetrics(r *Registry) *RetentionMetrics {
+	return &RetentionMetrics{
+		evicted: r.NewCounterVec("tasks_evicted_total", "Finished tasks removed by the retention janitor.", "reason", "status"),
+	}
+}
+
+func (m *RetentionMetrics) TaskEvicted(reason string, status domain.TaskStatus) {
+	m.evicted.With(reason, string(status)).Inc()
+}
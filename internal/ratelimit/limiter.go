@@ -0,0 +1,106 @@
+// Package ratelimit limits submissions per client with token buckets.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Decision is the outcome of Allow, with what the X-RateLimit-* headers report.
+type Decision struct {
+	Allowed    bool
+	Limit      int           // bucket size (burst)
+	Remaining  int           // whole tokens left after this request
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, zero when allowed
+	TooLarge   bool          // more tokens were asked for than the bucket holds
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps one token bucket per client key. The tier is passed on every
+// call, a client moved to another tier keeps its bucket.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Allow takes a token from key's bucket. A tier without a rate is unlimited.
+func (l *Limiter) Allow(key string, tier Tier) Decision {
+	return l.AllowN(key, tier, 1)
+}
+
+// AllowN takes n tokens from key's bucket, all of them or none. More than the
+// burst is refused as TooLarge, waiting doesn't help.
+func (l *Limiter) AllowN(key string, tier Tier, n int) Decision {
+	if !tier.Limited() {
+		return Decision{Allowed: true}
+	}
+	burst := float64(tier.Burst)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*tier.Rate)
+	b.last = now
+
+	d := Decision{Limit: tier.Burst}
+	switch need := float64(n); {
+	case n > tier.Burst:
+		d.TooLarge = true
+	case b.tokens >= need:
+		b.tokens -= need
+		d.Allowed = true
+	default:
+		d.RetryAfter = seconds((need - b.tokens) / tier.Rate)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = seconds((burst - b.tokens) / tier.Rate)
+	return d
+}
+
+// Sweep forgets buckets untouched for idle, they would be full by now anyway
+// for any rate above 1/idle.
+func (l *Limiter) Sweep(idle time.Duration) int {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	swept := 0
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= idle {
+			delete(l.buckets, key)
+			swept++
+		}
+	}
+	return swept
+}
+
+// Key is the bucket of a caller: the authenticated client, or the remote IP
+// when auth is off. HTTP and gRPC calls of a client share it.
+func Key(client, ip string) string {
+	if client != "" {
+		return "client:" + client
+	}
+	return "ip:" + ip
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
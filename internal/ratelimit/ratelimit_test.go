@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiter_BucketRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewLimiter()
+	l.now = func() time.Time { return now }
+	tier := Tier{Rate: 2, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if d := l.Allow("alice", tier); !d.Allowed || d.Remaining != 2-i {
+			t.Fatalf("Allow() #%d=%+v, want allowed with %d left", i, d, 2-i)
+		}
+	}
+
+	d := l.Allow("alice", tier)
+	if d.Allowed || d.RetryAfter != 500*time.Millisecond || d.Reset != 1500*time.Millisecond {
+		t.Fatalf("Allow() over burst=%+v, want refused, retry in 500ms, full in 1.5s", d)
+	}
+	if d := l.Allow("bob", tier); !d.Allowed {
+		t.Fatalf("Allow(other client)=%+v, want allowed", d)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if d := l.Allow("alice", tier); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("Allow() after refill=%+v, want allowed", d)
+	}
+
+	if d := l.Allow("alice", Tier{}); !d.Allowed || d.Limit != 0 {
+		t.Fatalf("Allow(unlimited tier)=%+v, want allowed without limit", d)
+	}
+
+	now = now.Add(time.Hour)
+	if n := l.Sweep(time.Minute); n != 2 {
+		t.Fatalf("Sweep()=%d, want 2", n)
+	}
+}
+
+func TestLimiter_AllowN(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewLimiter()
+	l.now = func() time.Time { return now }
+	tier := Tier{Rate: 1, Burst: 5}
+
+	if d := l.AllowN("alice", tier, 3); !d.Allowed || d.Remaining != 2 {
+		t.Fatalf("AllowN(3)=%+v, want allowed with 2 left", d)
+	}
+	// all or nothing: 3 tokens don't fit in 2, none are taken
+	if d := l.AllowN("alice", tier, 3); d.Allowed || d.RetryAfter != time.Second || d.Remaining != 2 {
+		t.Fatalf("AllowN(3)=%+v, want refused, retry in 1s, 2 left", d)
+	}
+	if d := l.AllowN("alice", tier, 6); d.Allowed || !d.TooLarge || d.RetryAfter != 0 {
+		t.Fatalf("AllowN(6)=%+v, want refused as too large", d)
+	}
+	if d := l.AllowN("alice", tier, 2); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("AllowN(2)=%+v, want allowed", d)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("default=1:5:10, premium=50:100:0", "svc-a=premium")
+	if err != nil {
+		t.Fatalf("ParsePolicy() err=%v, want nil", err)
+	}
+	if tier := p.For("svc-a"); tier.Name != "premium" || tier.Rate != 50 || tier.Burst != 100 || tier.MaxPending != 0 {
+		t.Fatalf("For(svc-a)=%+v, want premium", tier)
+	}
+	if tier := p.For("someone"); tier.Name != DefaultTier || p.MaxPending("someone") != 10 {
+		t.Fatalf("For(someone)=%+v, want default", tier)
+	}
+
+	empty, _ := ParsePolicy("", "")
+	if tier := empty.For("x"); tier.Limited() || tier.MaxPending != 0 {
+		t.Fatalf("For() without tiers=%+v, want no limits", tier)
+	}
+
+	for _, spec := range [][2]string{{"default=1:5", ""}, {"default=x:5:1", ""}, {"default=1:5:-1", ""}, {"default=1:5:1", "a=gold"}} {
+		if _, err := ParsePolicy(spec[0], spec[1]); !errors.Is(err, ErrInvalidTier) {
+			t.Fatalf("ParsePolicy(%q, %q) err=%v, want %v", spec[0], spec[1], err, ErrInvalidTier)
+		}
+	}
+}
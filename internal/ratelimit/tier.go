@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidTier = errors.New("invalid rate limit tier")
+
+// DefaultTier is used for clients without a tier of their own, and for
+// anonymous callers.
+const DefaultTier = "default"
+
+// Tier is a class of clients. Zero values mean no limit.
+type Tier struct {
+	Name       string
+	Rate       float64 // submissions per second refilled into the bucket
+	Burst      int     // bucket size
+	MaxPending int     // unfinished tasks a client may have
+}
+
+func (t Tier) Limited() bool {
+	return t.Rate > 0 && t.Burst > 0
+}
+
+// Policy maps clients to tiers.
+type Policy struct {
+	tiers   map[string]Tier
+	clients map[string]string // client -> tier name
+}
+
+// ParsePolicy reads tiers written as "name=rate:burst:max_pending", comma
+// separated (e.g. "default=5:10:100,premium=50:100:1000"), and client
+// assignments as "client=tier".
+func ParsePolicy(tiers, clients string) (*Policy, error) {
+	p := &Policy{tiers: make(map[string]Tier), clients: make(map[string]string)}
+
+	for _, spec := range strings.Split(tiers, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		name, limits, ok := strings.Cut(spec, "=")
+		parts := strings.Split(limits, ":")
+		if !ok || name == "" || len(parts) != 3 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTier, spec)
+		}
+		rate, errRate := strconv.ParseFloat(parts[0], 64)
+		burst, errBurst := strconv.Atoi(parts[1])
+		pending, errPending := strconv.Atoi(parts[2])
+		if errRate != nil || errBurst != nil || errPending != nil || rate < 0 || burst < 0 || pending < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTier, spec)
+		}
+		if rate > 0 && burst == 0 {
+			burst = int(max(rate, 1))
+		}
+		p.tiers[name] = Tier{Name: name, Rate: rate, Burst: burst, MaxPending: pending}
+	}
+
+	for _, spec := range strings.Split(clients, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		client, tier, ok := strings.Cut(spec, "=")
+		if _, known := p.tiers[tier]; !ok || client == "" || !known {
+			return nil, fmt.Errorf("%w: client %q", ErrInvalidTier, spec)
+		}
+		p.clients[client] = tier
+	}
+	return p, nil
+}
+
+// For returns the client's tier, the default tier (no limits unless
+// configured) otherwise.
+func (p *Policy) For(client string) Tier {
+	if name, ok := p.clients[client]; ok {
+		return p.tiers[name]
+	}
+	if t, ok := p.tiers[DefaultTier]; ok {
+		return t
+	}
+	return Tier{Name: DefaultTier}
+}
+
+// MaxPending is the client's quota, the shape service.WithQuota takes.
+func (p *Policy) MaxPending(client string) int {
+	return p.For(client).MaxPending
+}
@@ -0,0 +1,260 @@
+// Package retention evicts finished tasks, so a long-running instance doesn't
+// keep every task it ever ran.
+package retention
+
+import (
+	"context"
+	"interview-task-worker-pool/internal/domain"
+	"log/slog"
+	"time"
+)
+
+// eviction reasons
+const (
+	ReasonExpired  = "expired"   // past the TTL of its status, or its RetainUntil
+	ReasonMaxTasks = "max_tasks" // evicted to get back under MaxTasks
+)
+
+// Policy says how long finished tasks are kept. A zero TTL keeps the tasks of
+// that status, a zero MaxTasks doesn't cap the store.
+type Policy struct {
+	DoneTTL     time.Duration
+	FailedTTL   time.Duration
+	CanceledTTL time.Duration
+
+	// over MaxTasks tasks, the oldest finished ones are evicted until the store
+	// is back under it. Unfinished tasks are never evicted.
+	MaxTasks int
+}
+
+// ttl is the time a finished task of status is kept, zero for forever.
+func (p Policy) ttl(status domain.TaskStatus) time.Duration {
+	switch status {
+	case domain.StatusDone:
+		return p.DoneTTL
+	case domain.StatusFailed:
+		return p.FailedTTL
+	case domain.StatusCanceled:
+		return p.CanceledTTL
+	}
+	return 0
+}
+
+// Store is where the janitor finds and deletes finished tasks.
+type Store interface {
+	Count() (int, error)
+	// ListFinished returns up to limit finished tasks with an id above after, by id.
+	ListFinished(after int64, limit int) ([]domain.Task, error)
+	// Delete fails when the task changed since version was read.
+	Delete(id int64, version int64) (domain.Task, error)
+}
+
+// Logs drops the execution log of an evicted task.
+type Logs interface {
+	Delete(id int64)
+}
+
+// Archive keeps a copy of the tasks about to be evicted.
+type Archive interface {
+	Write(tasks []domain.Task) error
+}
+
+// Observer is notified about evicted tasks, e.g. to export metrics.
+type Observer interface {
+	TaskEvicted(reason string, status domain.TaskStatus)
+}
+
+type nopObserver struct{}
+
+func (nopObserver) TaskEvicted(string, domain.TaskStatus) {}
+
+type Option func(*Janitor)
+
+// WithBatchSize sets how many tasks a page holds, 100 by default. The store
+// is locked for one page at a time.
+func WithBatchSize(n int) Option {
+	return func(j *Janitor) {
+		if n > 0 {
+			j.batchSize = n
+		}
+	}
+}
+
+func WithTaskLogs(logs Logs) Option {
+	return func(j *Janitor) {
+		j.logs = logs
+	}
+}
+
+// WithArchive writes every task to archive before it is evicted. A page that
+// can't be archived isn't evicted, the sweep stops with the error.
+func WithArchive(archive Archive) Option {
+	return func(j *Janitor) {
+		j.archive = archive
+	}
+}
+
+func WithObserver(o Observer) Option {
+	return func(j *Janitor) {
+		if o != nil {
+			j.observer = o
+		}
+	}
+}
+
+// WithLogger sets the logger for sweep results, slog.Default() otherwise.
+func WithLogger(logger *slog.Logger) Option {
+	return func(j *Janitor) {
+		if logger != nil {
+			j.logger = logger
+		}
+	}
+}
+
+// Janitor applies a Policy to a Store.
+type Janitor struct {
+	store     Store
+	policy    Policy
+	batchSize int
+	logs      Logs
+	archive   Archive
+	observer  Observer
+	logger    *slog.Logger
+}
+
+func NewJanitor(store Store, policy Policy, opts ...Option) *Janitor {
+	j := &Janitor{
+		store:     store,
+		policy:    policy,
+		batchSize: 100,
+		observer:  nopObserver{},
+		logger:    slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Enabled reports whether the policy evicts anything at all.
+func (j *Janitor) Enabled() bool {
+	p := j.policy
+	return p.DoneTTL > 0 || p.FailedTTL > 0 || p.CanceledTTL > 0 || p.MaxTasks > 0
+}
+
+// Evicted counts the tasks a sweep evicted, per reason.
+type Evicted map[string]int
+
+func (e Evicted) Total() int {
+	n := 0
+	for _, c := range e {
+		n += c
+	}
+	return n
+}
+
+// Sweep walks the finished tasks page by page, oldest first, and deletes the
+// ones the policy doesn't keep anymore. Tasks that changed while the page was
+// looked at are left for the next sweep.
+func (j *Janitor) Sweep(now time.Time) (Evicted, error) {
+	evicted := make(Evicted)
+	if !j.Enabled() {
+		return evicted, nil
+	}
+
+	excess := 0
+	if j.policy.MaxTasks > 0 {
+		count, err := j.store.Count()
+		if err != nil {
+			return evicted, err
+		}
+		excess = count - j.policy.MaxTasks
+	}
+
+	var after int64
+	for {
+		page, err := j.store.ListFinished(after, j.batchSize)
+		if err != nil {
+			return evicted, err
+		}
+		if len(page) == 0 {
+			return evicted, nil
+		}
+
+		var due []domain.Task
+		var reasons []string
+		for _, task := range page {
+			after = task.ID
+			if reason := j.reason(task, now, excess-len(due) > 0); reason != "" {
+				due = append(due, task)
+				reasons = append(reasons, reason)
+			}
+		}
+		if j.archive != nil {
+			if err := j.archive.Write(due); err != nil {
+				return evicted, err
+			}
+		}
+
+		for i, task := range due {
+			reason := reasons[i]
+			if _, err := j.store.Delete(task.ID, task.Version); err != nil {
+				continue
+			}
+			if j.logs != nil {
+				j.logs.Delete(task.ID)
+			}
+			j.observer.TaskEvicted(reason, task.Status)
+			evicted[reason]++
+			excess--
+		}
+	}
+}
+
+// reason is why task should go, empty when it stays.
+func (j *Janitor) reason(task domain.Task, now time.Time, overCap bool) string {
+	// pinned tasks stay until RetainUntil, whatever the TTL or the cap
+	if !task.RetainUntil.IsZero() {
+		if now.Before(task.RetainUntil) {
+			return ""
+		}
+		return ReasonExpired
+	}
+
+	finished := task.FinishedAt
+	if finished.IsZero() {
+		finished = task.CreatedAt
+	}
+	if ttl := j.policy.ttl(task.Status); ttl > 0 && !now.Before(finished.Add(ttl)) {
+		return ReasonExpired
+	}
+	if overCap {
+		return ReasonMaxTasks
+	}
+	return ""
+}
+
+// Run sweeps every interval until ctx is done.
+func (j *Janitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			evicted, err := j.Sweep(time.Now())
+			if err != nil {
+				j.logger.Error("retention sweep failed", "error", err, "evicted", evicted.Total())
+				continue
+			}
+			if evicted.Total() > 0 {
+				j.logger.Info("retention sweep",
+					"expired", evicted[ReasonExpired],
+					"max_tasks", evicted[ReasonMaxTasks],
+				)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package retention_test
+
+import (
+	"errors"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/retention"
+	"interview-task-worker-pool/internal/store/memory"
+	"testing"
+	"time"
+)
+
+type fakeLogs struct{ deleted []int64 }
+
+func (l *fakeLogs) Delete(id int64) { l.deleted = append(l.deleted, id) }
+
+type fakeObserver struct{ evicted map[string]int }
+
+func (o *fakeObserver) TaskEvicted(reason string, status domain.TaskStatus) {
+	o.evicted[reason+"/"+string(status)]++
+}
+
+func finished(t *testing.T, ts *memory.TaskStore, status domain.TaskStatus, retainUntil time.Time) domain.Task {
+	t.Helper()
+	created, err := ts.Create(domain.Task{Title: "t", RetainUntil: retainUntil})
+	if err != nil {
+		t.Fatalf("Create() err=%v, want nil", err)
+	}
+	task, err := ts.UpdateStatus(created.ID, status)
+	if err != nil {
+		t.Fatalf("UpdateStatus() err=%v, want nil", err)
+	}
+	return task
+}
+
+func exists(ts *memory.TaskStore, id int64) bool {
+	_, ok := ts.Get(id)
+	return ok
+}
+
+func TestSweep_TTLPerStatus(t *testing.T) {
+	ts := memory.New()
+	done := finished(t, ts, domain.StatusDone, time.Time{})
+	failed := finished(t, ts, domain.StatusFailed, time.Time{})
+	canceled := finished(t, ts, domain.StatusCanceled, time.Time{})
+	pending, _ := ts.Create(domain.Task{Title: "p"})
+
+	logs := &fakeLogs{}
+	observer := &fakeObserver{evicted: make(map[string]int)}
+	janitor := retention.NewJanitor(ts, retention.Policy{DoneTTL: time.Hour, CanceledTTL: 3 * time.Hour},
+		retention.WithTaskLogs(logs),
+		retention.WithObserver(observer),
+	)
+
+	// nothing is due yet
+	if evicted, err := janitor.Sweep(time.Now()); err != nil || evicted.Total() != 0 {
+		t.Fatalf("Sweep(now) = %v err=%v, want nothing evicted", evicted, err)
+	}
+
+	evicted, err := janitor.Sweep(time.Now().Add(2 * time.Hour))
+	if err != nil || evicted[retention.ReasonExpired] != 1 {
+		t.Fatalf("Sweep(+2h) = %v err=%v, want 1 expired", evicted, err)
+	}
+	if exists(ts, done.ID) || !exists(ts, canceled.ID) || !exists(ts, failed.ID) || !exists(ts, pending.ID) {
+		t.Fatal("Sweep(+2h) should evict only the done task")
+	}
+	if len(logs.deleted) != 1 || logs.deleted[0] != done.ID {
+		t.Fatalf("deleted logs = %v, want [%d]", logs.deleted, done.ID)
+	}
+	if observer.evicted["expired/done"] != 1 {
+		t.Fatalf("observed = %v, want expired/done once", observer.evicted)
+	}
+
+	// a zero TTL keeps failed tasks, unfinished ones are never looked at
+	_, _ = janitor.Sweep(time.Now().Add(24 * time.Hour))
+	if exists(ts, canceled.ID) || !exists(ts, failed.ID) || !exists(ts, pending.ID) {
+		t.Fatal("Sweep(+24h) should evict the canceled task and keep the failed and pending ones")
+	}
+}
+
+func TestSweep_MaxTasksEvictsOldestFinishedFirst(t *testing.T) {
+	ts := memory.New()
+	pending, _ := ts.Create(domain.Task{Title: "p"})
+	var tasks []domain.Task
+	for i := 0; i < 5; i++ {
+		tasks = append(tasks, finished(t, ts, domain.StatusDone, time.Time{}))
+	}
+
+	// a page of 2 makes the sweep walk several pages
+	janitor := retention.NewJanitor(ts, retention.Policy{MaxTasks: 3}, retention.WithBatchSize(2))
+	evicted, err := janitor.Sweep(time.Now())
+	if err != nil || evicted[retention.ReasonMaxTasks] != 3 {
+		t.Fatalf("Sweep() = %v err=%v, want 3 evicted for max_tasks", evicted, err)
+	}
+	if n, _ := ts.Count(); n != 3 {
+		t.Fatalf("Count() = %d, want 3", n)
+	}
+	for i, task := range tasks {
+		if want := i >= 3; exists(ts, task.ID) != want {
+			t.Fatalf("task %d exists = %v, want %v", task.ID, !want, want)
+		}
+	}
+	if !exists(ts, pending.ID) {
+		t.Fatal("pending task was evicted, want it kept")
+	}
+}
+
+func TestSweep_RetainUntilOverrides(t *testing.T) {
+	ts := memory.New()
+	now := time.Now()
+	pinned := finished(t, ts, domain.StatusDone, now.Add(48*time.Hour))
+	short := finished(t, ts, domain.StatusFailed, now.Add(time.Minute))
+
+	// failed tasks are kept forever, but short only until its retain_until
+	janitor := retention.NewJanitor(ts, retention.Policy{DoneTTL: time.Hour, MaxTasks: 1})
+
+	evicted, _ := janitor.Sweep(now.Add(2 * time.Hour))
+	if !exists(ts, pinned.ID) {
+		t.Fatal("pinned task evicted before retain_until, want kept past the TTL and the cap")
+	}
+	if exists(ts, short.ID) || evicted[retention.ReasonExpired] != 1 {
+		t.Fatalf("Sweep() = %v, want the task past its retain_until expired", evicted)
+	}
+
+	_, _ = janitor.Sweep(now.Add(49 * time.Hour))
+	if exists(ts, pinned.ID) {
+		t.Fatal("pinned task kept after retain_until, want evicted")
+	}
+}
+
+func TestJanitor_Enabled(t *testing.T) {
+	if retention.NewJanitor(memory.New(), retention.Policy{}).Enabled() {
+		t.Fatal("Enabled() = true for the zero policy, want false")
+	}
+	if !retention.NewJanitor(memory.New(), retention.Policy{MaxTasks: 10}).Enabled() {
+		t.Fatal("Enabled() = false with MaxTasks, want true")
+	}
+}
+
+type fakeArchive struct {
+	written []domain.Task
+	err     error
+}
+
+func (a *fakeArchive) Write(tasks []domain.Task) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.written = append(a.written, tasks...)
+	return nil
+}
+
+func TestSweep_ArchivesBeforeEvicting(t *testing.T) {
+	ts := memory.New()
+	done := finished(t, ts, domain.StatusDone, time.Time{})
+
+	broken := &fakeArchive{err: errors.New("disk full")}
+	janitor := retention.NewJanitor(ts, retention.Policy{DoneTTL: time.Hour}, retention.WithArchive(broken))
+	if _, err := janitor.Sweep(time.Now().Add(2 * time.Hour)); err == nil {
+		t.Fatal("Sweep() err=nil with a failing archive, want the error")
+	}
+	if !exists(ts, done.ID) {
+		t.Fatal("task evicted although archiving failed, want it kept")
+	}
+
+	archive := &fakeArchive{}
+	janitor = retention.NewJanitor(ts, retention.Policy{DoneTTL: time.Hour}, retention.WithArchive(archive))
+	if _, err := janitor.Sweep(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Fatalf("Sweep() err=%v, want nil", err)
+	}
+	if exists(ts, done.ID) || len(archive.written) != 1 || archive.written[0].ID != done.ID {
+		t.Fatalf("archived = %+v, want task %d archived and evicted", archive.written, done.ID)
+	}
+}
@@ -0,0 +1,223 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"interview-task-worker-pool/internal/auth"
+	"interview-task-worker-pool/internal/logging"
+	"interview-task-worker-pool/internal/ratelimit"
+	"interview-task-worker-pool/internal/rpc/pb"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// scopes is what each method needs from the caller when auth is on, like the
+// HTTP routes.
+var scopes = map[string]auth.Scope{
+	pb.TaskService_CreateTask_FullMethodName: auth.ScopeTasksCreate,
+	pb.TaskService_GetTask_FullMethodName:    auth.ScopeTasksRead,
+	pb.TaskService_ListTasks_FullMethodName:  auth.ScopeTasksRead,
+	pb.TaskService_WatchTask_FullMethodName:  auth.ScopeTasksRead,
+	pb.TaskService_CancelTask_FullMethodName: auth.ScopeTasksCancel,
+}
+
+// limited are the methods submitting tasks, like the rate limited HTTP routes.
+var limited = map[string]bool{
+	pb.TaskService_CreateTask_FullMethodName: true,
+}
+
+type Option func(*options)
+
+type options struct {
+	authenticators []auth.Authenticator
+	logger         *slog.Logger
+	limiter        *ratelimit.Limiter
+	policy         *ratelimit.Policy
+}
+
+// WithAuth requires an authenticated caller holding the method's scope. The
+// credentials are read from the "authorization" and "x-api-key" metadata.
+func WithAuth(authenticators ...auth.Authenticator) Option {
+	return func(o *options) {
+		o.authenticators = authenticators
+	}
+}
+
+// WithRateLimit limits CreateTask per client with the client's tier. Sharing the
+// limiter with the HTTP API gives a client one bucket for both.
+func WithRateLimit(limiter *ratelimit.Limiter, policy *ratelimit.Policy) Option {
+	return func(o *options) {
+		o.limiter = limiter
+		o.policy = policy
+	}
+}
+
+// WithLogger writes one log line per call, slog.Default() otherwise.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// New returns a gRPC server serving srv.
+func New(srv *Server, opts ...Option) *grpc.Server {
+	o := &options{logger: slog.Default()}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(o.unary, o.rateLimit),
+		grpc.ChainStreamInterceptor(o.stream),
+	)
+	pb.RegisterTaskServiceServer(server, srv)
+	return server
+}
+
+func (o *options) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx, err := o.prepare(ctx, info.FullMethod)
+
+	var resp any
+	if err == nil {
+		resp, err = handler(ctx, req)
+	}
+	o.log(ctx, info.FullMethod, err, start)
+	return resp, err
+}
+
+// rateLimit runs after unary, the caller is on the context. The bucket state
+// goes out as x-ratelimit-* headers, a refused call is ResourceExhausted with
+// retry-after.
+func (o *options) rateLimit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if o.limiter == nil || !limited[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	var client string
+	if p, ok := auth.PrincipalFromContext(ctx); ok {
+		client = p.ID
+	}
+	d := o.limiter.Allow(ratelimit.Key(client, peerIP(ctx)), o.policy.For(client))
+	if d.Limit == 0 {
+		return handler(ctx, req)
+	}
+
+	md := metadata.Pairs(
+		"x-ratelimit-limit", strconv.Itoa(d.Limit),
+		"x-ratelimit-remaining", strconv.Itoa(d.Remaining),
+		"x-ratelimit-reset", strconv.Itoa(ceilSeconds(d.Reset)),
+	)
+	if !d.Allowed {
+		md.Set("retry-after", strconv.Itoa(ceilSeconds(d.RetryAfter)))
+	}
+	_ = grpc.SetHeader(ctx, md)
+	if !d.Allowed {
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return handler(ctx, req)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func (o *options) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, err := o.prepare(ss.Context(), info.FullMethod)
+
+	if err == nil {
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+	o.log(ctx, info.FullMethod, err, start)
+	return err
+}
+
+// prepare puts the request ID and the caller on the context.
+func (o *options) prepare(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var id string
+	if ids := md.Get("x-request-id"); len(ids) > 0 {
+		id = ids[0]
+	}
+	id = logging.KeepOrNewRequestID(id)
+	ctx = logging.ContextWithRequestID(ctx, id)
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", id))
+
+	if len(o.authenticators) == 0 {
+		return ctx, nil
+	}
+	principal, err := o.authenticate(md)
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if scope := scopes[method]; !principal.Has(scope) {
+		return ctx, status.Error(codes.PermissionDenied, "missing scope "+string(scope))
+	}
+	return auth.ContextWithPrincipal(ctx, principal), nil
+}
+
+// authenticate hands the credential metadata to the HTTP authenticators as
+// request headers.
+func (o *options) authenticate(md metadata.MD) (auth.Principal, error) {
+	r := &http.Request{Header: make(http.Header)}
+	for _, v := range md.Get("authorization") {
+		r.Header.Add("Authorization", v)
+	}
+	for _, v := range md.Get("x-api-key") {
+		r.Header.Add("X-API-Key", v)
+	}
+
+	for _, a := range o.authenticators {
+		principal, err := a.AuthenticateRequest(r)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			continue
+		}
+		return principal, err
+	}
+	return auth.Principal{}, auth.ErrNoCredentials
+}
+
+func (o *options) log(ctx context.Context, method string, err error, start time.Time) {
+	o.logger.Info("grpc request",
+		logging.RequestID, logging.RequestIDFromContext(ctx),
+		"method", method,
+		"code", status.Code(err).String(),
+		logging.Duration, time.Since(start),
+	)
+}
+
+// serverStream carries the context prepare built to stream handlers.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,200 @@
+// Package rpc serves the task API over gRPC, on the same service.TaskService
+// as the HTTP API. The protobuf definitions and generated code are in pb.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative -I pb pb/tasks.proto
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/rpc/pb"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/workerpool"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultWatchInterval is how often WatchTask looks at the task.
+const DefaultWatchInterval = 250 * time.Millisecond
+
+type TaskService interface {
+	CreateTask(ctx context.Context, in service.CreateTaskInput) (service.CreateTaskResult, error)
+	GetTask(ctx context.Context, id int64) (domain.Task, error)
+	ListTasks(ctx context.Context, filter service.TaskFilter) ([]domain.Task, error)
+	CancelTask(ctx context.Context, id int64) (domain.Task, error)
+}
+
+type Server struct {
+	pb.UnimplementedTaskServiceServer
+
+	tasks         TaskService
+	watchInterval time.Duration
+}
+
+func NewServer(tasks TaskService) *Server {
+	return &Server{tasks: tasks, watchInterval: DefaultWatchInterval}
+}
+
+func (s *Server) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.CreateTaskResponse, error) {
+	res, err := s.tasks.CreateTask(ctx, service.CreateTaskInput{
+		Title:          req.GetTitle(),
+		Description:    req.GetDescription(),
+		IdempotencyKey: req.GetIdempotencyKey(),
+		DedupeKey:      req.GetDedupeKey(),
+
+		ConcurrencyKey:   req.GetConcurrencyKey(),
+		ConcurrencyLimit: int(req.GetConcurrencyLimit()),
+
+		DependsOn: req.GetDependsOn(),
+
+		Priority:    int(req.GetPriority()),
+		Metadata:    req.GetMetadata(),
+		RetainUntil: fromTimestamp(req.GetRetainUntil()),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.CreateTaskResponse{Task: toProto(res.Task), Replayed: res.Replayed, Attached: res.Attached}, nil
+}
+
+func (s *Server) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.Task, error) {
+	task, err := s.tasks.GetTask(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(task), nil
+}
+
+// ListTasks streams the tasks by id.
+func (s *Server) ListTasks(req *pb.ListTasksRequest, stream pb.TaskService_ListTasksServer) error {
+	tasks, err := s.tasks.ListTasks(stream.Context(), service.TaskFilter{Owner: req.GetOwner()})
+	if err != nil {
+		return statusError(err)
+	}
+	slices.SortFunc(tasks, func(a, b domain.Task) int { return cmp.Compare(a.ID, b.ID) })
+
+	for _, task := range tasks {
+		if err := stream.Send(toProto(task)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) CancelTask(ctx context.Context, req *pb.CancelTaskRequest) (*pb.Task, error) {
+	task, err := s.tasks.CancelTask(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toProto(task), nil
+}
+
+// WatchTask polls the task and sends it whenever its status changed, until it
+// is terminal or the client goes away.
+func (s *Server) WatchTask(req *pb.WatchTaskRequest, stream pb.TaskService_WatchTaskServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+
+	var last domain.TaskStatus
+	for {
+		task, err := s.tasks.GetTask(ctx, req.GetId())
+		if err != nil {
+			return statusError(err)
+		}
+		if task.Status != last {
+			if err := stream.Send(toProto(task)); err != nil {
+				return err
+			}
+			last = task.Status
+		}
+		if task.Status.Terminal() {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// statusError maps service and pool errors onto gRPC status codes, the way the
+// HTTP handlers map them onto status codes.
+func statusError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, service.ErrInvalidInput), errors.Is(err, service.ErrInvalidID):
+		code = codes.InvalidArgument
+	case errors.Is(err, service.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, service.ErrDuplicate):
+		code = codes.AlreadyExists
+	case errors.Is(err, service.ErrIdempotencyConflict), errors.Is(err, service.ErrInvalidDependency), errors.Is(err, service.ErrTaskFinished):
+		code = codes.FailedPrecondition
+	case errors.Is(err, service.ErrQuotaExceeded):
+		code = codes.ResourceExhausted
+	case errors.Is(err, workerpool.ErrPoolFull), errors.Is(err, workerpool.ErrPoolClosed):
+		code = codes.Unavailable
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	if code == codes.Internal {
+		return status.Error(code, "internal server error")
+	}
+	return status.Error(code, err.Error())
+}
+
+var statuses = map[domain.TaskStatus]pb.TaskStatus{
+	domain.StatusBlocked:  pb.TaskStatus_TASK_STATUS_BLOCKED,
+	domain.StatusPending:  pb.TaskStatus_TASK_STATUS_PENDING,
+	domain.StatusRunning:  pb.TaskStatus_TASK_STATUS_RUNNING,
+	domain.StatusDone:     pb.TaskStatus_TASK_STATUS_DONE,
+	domain.StatusFailed:   pb.TaskStatus_TASK_STATUS_FAILED,
+	domain.StatusCanceled: pb.TaskStatus_TASK_STATUS_CANCELED,
+}
+
+func toProto(task domain.Task) *pb.Task {
+	return &pb.Task{
+		Id:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      statuses[task.Status],
+		Error:       task.Error,
+		Owner:       task.Owner,
+		DedupeKey:   task.DedupeKey,
+
+		ConcurrencyKey:   task.ConcurrencyKey,
+		ConcurrencyLimit: int32(task.ConcurrencyLimit),
+
+		DependsOn: task.DependsOn,
+
+		Priority:    int32(task.Priority),
+		Metadata:    task.Metadata,
+		RetainUntil: toTimestamp(task.RetainUntil),
+		Version:     task.Version,
+	}
+}
+
+// toTimestamp leaves a zero time unset.
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// fromTimestamp is the zero time for an unset timestamp.
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
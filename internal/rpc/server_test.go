@@ -0,0 +1,305 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"interview-task-worker-pool/internal/auth"
+	"interview-task-worker-pool/internal/dag"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/ratelimit"
+	"interview-task-worker-pool/internal/rpc/pb"
+	"interview-task-worker-pool/internal/service"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/workerpool"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newClient serves svc on an in-memory listener and returns a client for it.
+func newClient(t *testing.T, svc TaskService, opts ...Option) pb.TaskServiceClient {
+	t.Helper()
+
+	srv := NewServer(svc)
+	srv.watchInterval = 10 * time.Millisecond
+	server := New(srv, append(opts, WithLogger(slog.New(slog.DiscardHandler)))...)
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient err=%v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewTaskServiceClient(conn)
+}
+
+func newService(t *testing.T, workers int, exec workerpool.Executor) *service.TaskService {
+	t.Helper()
+
+	store := memory.New()
+	pool := workerpool.New(10, store, workerpool.WithExecutor(exec), workerpool.WithLogger(slog.New(slog.DiscardHandler)))
+	resolver := dag.NewResolver(store, pool)
+	pool.OnFinish(resolver.Resolve)
+	pool.Start(workers)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = pool.Shutdown(ctx)
+	})
+
+	svc, err := service.New(store, pool, service.WithResolver(resolver))
+	if err != nil {
+		t.Fatalf("service.New err=%v", err)
+	}
+	return svc
+}
+
+func TestServer_CreateGetListCancel(t *testing.T) {
+	client := newClient(t, newService(t, 0, nil)) // no workers, tasks stay pending
+	ctx := context.Background()
+
+	created, err := client.CreateTask(ctx, &pb.CreateTaskRequest{Title: "a", DedupeKey: "k"})
+	if err != nil {
+		t.Fatalf("CreateTask() err=%v, want nil", err)
+	}
+	if created.GetTask().GetId() == 0 || created.GetTask().GetStatus() != pb.TaskStatus_TASK_STATUS_PENDING {
+		t.Fatalf("CreateTask()=%v, want a pending task", created)
+	}
+	attached, err := client.CreateTask(ctx, &pb.CreateTaskRequest{Title: "b", DedupeKey: "k"})
+	if err != nil || !attached.GetAttached() || attached.GetTask().GetId() != created.GetTask().GetId() {
+		t.Fatalf("CreateTask(same dedupe key)=%v err=%v, want attached", attached, err)
+	}
+	if _, err := client.CreateTask(ctx, &pb.CreateTaskRequest{Title: "c"}); err != nil {
+		t.Fatalf("CreateTask(c) err=%v", err)
+	}
+
+	got, err := client.GetTask(ctx, &pb.GetTaskRequest{Id: created.GetTask().GetId()})
+	if err != nil || got.GetTitle() != "a" {
+		t.Fatalf("GetTask()=%v err=%v, want task a", got, err)
+	}
+
+	stream, err := client.ListTasks(ctx, &pb.ListTasksRequest{})
+	if err != nil {
+		t.Fatalf("ListTasks() err=%v", err)
+	}
+	var ids []int64
+	for {
+		task, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ListTasks Recv err=%v", err)
+		}
+		ids = append(ids, task.GetId())
+	}
+	if len(ids) != 2 || ids[0] > ids[1] {
+		t.Fatalf("ListTasks ids=%v, want 2 tasks by id", ids)
+	}
+
+	canceled, err := client.CancelTask(ctx, &pb.CancelTaskRequest{Id: created.GetTask().GetId()})
+	if err != nil || canceled.GetStatus() != pb.TaskStatus_TASK_STATUS_CANCELED {
+		t.Fatalf("CancelTask()=%v err=%v, want canceled", canceled, err)
+	}
+	if _, err := client.CancelTask(ctx, &pb.CancelTaskRequest{Id: created.GetTask().GetId()}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("CancelTask(again) code=%v, want %v", status.Code(err), codes.FailedPrecondition)
+	}
+}
+
+func TestServer_CreateTask_PriorityMetadataRetainUntil(t *testing.T) {
+	client := newClient(t, newService(t, 0, nil))
+	ctx := context.Background()
+	until := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	created, err := client.CreateTask(ctx, &pb.CreateTaskRequest{
+		Title:       "a",
+		Priority:    7,
+		Metadata:    map[string]string{"team": "infra"},
+		RetainUntil: timestamppb.New(until),
+	})
+	if err != nil {
+		t.Fatalf("CreateTask() err=%v, want nil", err)
+	}
+	task := created.GetTask()
+	if task.GetPriority() != 7 || task.GetMetadata()["team"] != "infra" || !task.GetRetainUntil().AsTime().Equal(until) || task.GetVersion() != 1 {
+		t.Fatalf("CreateTask()=%v, want priority, metadata, retain_until and version 1", task)
+	}
+
+	canceled, err := client.CancelTask(ctx, &pb.CancelTaskRequest{Id: task.GetId()})
+	if err != nil || canceled.GetVersion() != 2 {
+		t.Fatalf("CancelTask()=%v err=%v, want version 2", canceled, err)
+	}
+
+	plain, err := client.CreateTask(ctx, &pb.CreateTaskRequest{Title: "b"})
+	if err != nil || plain.GetTask().GetRetainUntil() != nil {
+		t.Fatalf("CreateTask(b)=%v err=%v, want no retain_until", plain, err)
+	}
+	if _, err := client.CreateTask(ctx, &pb.CreateTaskRequest{Title: "c", Metadata: map[string]string{"": "x"}}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("CreateTask(empty metadata key) code=%v, want %v", status.Code(err), codes.InvalidArgument)
+	}
+}
+
+func TestServer_ErrorCodes(t *testing.T) {
+	client := newClient(t, newService(t, 0, nil))
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"empty title", func() error {
+			_, err := client.CreateTask(ctx, &pb.CreateTaskRequest{Title: " "})
+			return err
+		}, codes.InvalidArgument},
+		{"invalid id", func() error {
+			_, err := client.GetTask(ctx, &pb.GetTaskRequest{Id: 0})
+			return err
+		}, codes.InvalidArgument},
+		{"unknown task", func() error {
+			_, err := client.GetTask(ctx, &pb.GetTaskRequest{Id: 42})
+			return err
+		}, codes.NotFound},
+		{"unknown dependency", func() error {
+			_, err := client.CreateTask(ctx, &pb.CreateTaskRequest{Title: "a", DependsOn: []int64{42}})
+			return err
+		}, codes.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		if code := status.Code(tt.call()); code != tt.want {
+			t.Fatalf("%s: code=%v, want %v", tt.name, code, tt.want)
+		}
+	}
+
+	if err := statusError(workerpool.ErrPoolFull); status.Code(err) != codes.Unavailable {
+		t.Fatalf("statusError(ErrPoolFull) code=%v, want %v", status.Code(err), codes.Unavailable)
+	}
+	if err := statusError(service.ErrQuotaExceeded); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("statusError(ErrQuotaExceeded) code=%v, want %v", status.Code(err), codes.ResourceExhausted)
+	}
+	if err := statusError(errors.New("boom")); status.Convert(err).Message() != "internal server error" {
+		t.Fatalf("statusError(unknown)=%v, want an internal error without details", err)
+	}
+}
+
+func TestServer_WatchTask_StreamsStatusChanges(t *testing.T) {
+	release := make(chan struct{})
+	exec := func(ctx context.Context, _ domain.Task, _ *slog.Logger) error {
+		<-release
+		return nil
+	}
+	client := newClient(t, newService(t, 1, exec))
+	ctx := context.Background()
+
+	created, err := client.CreateTask(ctx, &pb.CreateTaskRequest{Title: "a"})
+	if err != nil {
+		t.Fatalf("CreateTask() err=%v", err)
+	}
+	stream, err := client.WatchTask(ctx, &pb.WatchTaskRequest{Id: created.GetTask().GetId()})
+	if err != nil {
+		t.Fatalf("WatchTask() err=%v", err)
+	}
+
+	var seen []pb.TaskStatus
+	for {
+		task, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("WatchTask Recv err=%v", err)
+		}
+		seen = append(seen, task.GetStatus())
+		if task.GetStatus() == pb.TaskStatus_TASK_STATUS_RUNNING {
+			close(release)
+		}
+	}
+	if len(seen) == 0 || seen[len(seen)-1] != pb.TaskStatus_TASK_STATUS_DONE {
+		t.Fatalf("WatchTask statuses=%v, want the stream to end with done", seen)
+	}
+	for i := 1; i < len(seen); i++ {
+		if seen[i] == seen[i-1] {
+			t.Fatalf("WatchTask statuses=%v, want only changes", seen)
+		}
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	keys := auth.NewKeyStore()
+	if err := keys.AddSpecs("alice:a-key:tasks:read"); err != nil {
+		t.Fatalf("AddSpecs err=%v", err)
+	}
+	client := newClient(t, newService(t, 0, nil), WithAuth(keys))
+
+	if _, err := client.GetTask(context.Background(), &pb.GetTaskRequest{Id: 1}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("GetTask(no key) code=%v, want %v", status.Code(err), codes.Unauthenticated)
+	}
+	bad := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "nope")
+	if _, err := client.GetTask(bad, &pb.GetTaskRequest{Id: 1}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("GetTask(wrong key) code=%v, want %v", status.Code(err), codes.Unauthenticated)
+	}
+
+	alice := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer a-key")
+	if _, err := client.CreateTask(alice, &pb.CreateTaskRequest{Title: "a"}); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("CreateTask(read only key) code=%v, want %v", status.Code(err), codes.PermissionDenied)
+	}
+	stream, err := client.ListTasks(alice, &pb.ListTasksRequest{})
+	if err != nil {
+		t.Fatalf("ListTasks() err=%v", err)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Fatalf("ListTasks Recv err=%v, want an empty stream", err)
+	}
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	keys := auth.NewKeyStore()
+	_ = keys.AddSpecs("alice:a-key")
+	tiers, err := ratelimit.ParsePolicy("default=0.001:3:0", "")
+	if err != nil {
+		t.Fatalf("ParsePolicy err=%v", err)
+	}
+	limiter := ratelimit.NewLimiter()
+	client := newClient(t, newService(t, 0, nil), WithAuth(keys), WithRateLimit(limiter, tiers))
+
+	// the HTTP API took a token from alice's bucket already
+	limiter.Allow(ratelimit.Key("alice", ""), tiers.For("alice"))
+
+	alice := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "a-key")
+	for i := 0; i < 2; i++ {
+		var header metadata.MD
+		if _, err := client.CreateTask(alice, &pb.CreateTaskRequest{Title: "t"}, grpc.Header(&header)); err != nil {
+			t.Fatalf("CreateTask #%d err=%v", i, err)
+		}
+		if got := header.Get("x-ratelimit-remaining"); len(got) != 1 || got[0] != strconv.Itoa(1-i) {
+			t.Fatalf("CreateTask #%d x-ratelimit-remaining=%v, want %d", i, got, 1-i)
+		}
+	}
+
+	var header metadata.MD
+	_, err = client.CreateTask(alice, &pb.CreateTaskRequest{Title: "t"}, grpc.Header(&header))
+	if status.Code(err) != codes.ResourceExhausted || len(header.Get("retry-after")) != 1 {
+		t.Fatalf("CreateTask over rate code=%v header=%v, want %v with retry-after", status.Code(err), header, codes.ResourceExhausted)
+	}
+	// reads aren't limited
+	if _, err := client.GetTask(alice, &pb.GetTaskRequest{Id: 1}); err != nil {
+		t.Fatalf("GetTask() err=%v, want nil", err)
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"interview-task-worker-pool/internal/auth"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/store"
+	"interview-task-worker-pool/internal/tracing"
+	"interview-task-worker-pool/internal/workerpool"
+	"time"
+)
+
+type BatchMode string
+
+const (
+	BatchAllOrNothing BatchMode = "all_or_nothing" // nothing is created unless every item can be
+	BatchBestEffort   BatchMode = "best_effort"    // valid items are created, the rest are reported
+
+	maxBatchSize = 1000
+)
+
+type BatchItemResult struct {
+	Task     domain.Task
+	Attached bool
+	Err      error
+}
+
+type CreateBatchResult struct {
+	BatchID int64 // zero when the batch was rejected
+	Items   []BatchItemResult
+}
+
+type BatchProgress struct {
+	ID        int64
+	CreatedAt time.Time
+	Total     int
+	Counts    map[domain.TaskStatus]int
+}
+
+// CreateBatch validates every item first and then inserts the valid ones with a
+// single store operation, which also applies the caller's quota. Items keep their
+// input order in the result.
+//
+// In all-or-nothing mode any invalid item, dedupe conflict, item over the quota or
+// lack of queue room rejects the whole batch with ErrBatchRejected, the items that
+// were fine report ErrBatchRejected too. The queue room is held before the insert
+// for every item, blocked ones included since the resolver may release them right
+// away, so only a pool closing meanwhile can still fail an item after it. In best-effort
+// mode enqueue failures (ErrPoolFull, ErrPoolClosed) are reported per item, like
+// for a single create.
+func (s *TaskService) CreateBatch(ctx context.Context, inputs []CreateTaskInput, mode BatchMode) (_ CreateBatchResult, err error) {
+	ctx, span := s.tracer.Start(ctx, "TaskService.CreateBatch", tracing.WithAttributes(
+		tracing.String("batch.mode", string(mode)),
+		tracing.Int("batch.size", int64(len(inputs))),
+	))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	if mode == "" {
+		mode = BatchBestEffort
+	}
+	if mode != BatchAllOrNothing && mode != BatchBestEffort {
+		return CreateBatchResult{}, invalidField("mode", "must be all_or_nothing or best_effort")
+	}
+	if len(inputs) == 0 || len(inputs) > maxBatchSize {
+		return CreateBatchResult{}, invalidField("tasks", fmt.Sprintf("must have 1 to %d tasks", maxBatchSize))
+	}
+
+	items := make([]BatchItemResult, len(inputs))
+	tasks := make([]domain.Task, 0, len(inputs))
+	index := make([]int, 0, len(inputs)) // tasks[j] belongs to items[index[j]]
+
+	invalid := false
+	for i := range inputs {
+		task, err := s.prepare(ctx, &inputs[i])
+		if err != nil {
+			items[i].Err = err
+			invalid = true
+			continue
+		}
+		tasks = append(tasks, task)
+		index = append(index, i)
+	}
+
+	atomic := mode == BatchAllOrNothing
+	if atomic && invalid {
+		return rejectBatch(items), ErrBatchRejected
+	}
+
+	pool := s.pool
+	if atomic {
+		reservation, err := s.reserve(len(tasks))
+		if err != nil {
+			for _, i := range index {
+				items[i].Err = err
+			}
+			return rejectBatch(items), ErrBatchRejected
+		}
+		if reservation != nil {
+			defer reservation.Release()
+			pool = reservation
+		}
+	}
+
+	var owner string
+	if p, ok := auth.PrincipalFromContext(ctx); ok {
+		owner = p.ID
+	}
+
+	_, storeSpan := s.tracer.Start(ctx, "store.CreateBatch")
+	batch, stored, err := s.store.CreateBatch(owner, tasks, s.dedupePolicy, atomic, s.maxActive(owner))
+	storeSpan.RecordError(err)
+	storeSpan.End()
+	if err != nil {
+		if errors.Is(err, store.ErrBatchRejected) {
+			for j, item := range stored {
+				if item.Err != nil {
+					items[index[j]].Err = mapStoreErr(item.Err)
+				}
+			}
+			return rejectBatch(items), ErrBatchRejected
+		}
+		return CreateBatchResult{}, err
+	}
+
+	for j, item := range stored {
+		i := index[j]
+		switch {
+		case item.Err != nil:
+			items[i].Err = mapStoreErr(item.Err)
+		case item.Result.Attached:
+			items[i] = BatchItemResult{Task: item.Result.Task, Attached: true}
+		default:
+			task, err := s.submit(ctx, item.Result, pool)
+			items[i] = BatchItemResult{Task: task, Err: err}
+		}
+	}
+
+	return CreateBatchResult{BatchID: batch.ID, Items: items}, nil
+}
+
+// reserve holds queue room for n tasks, the room blocked tasks don't take is
+// given back with the rest once the batch is submitted. It returns nil when the
+// pool can't reserve.
+func (s *TaskService) reserve(n int) (*workerpool.Reservation, error) {
+	r, ok := s.pool.(reserver)
+	if !ok {
+		return nil, nil
+	}
+	return r.Reserve(n)
+}
+
+// rejectBatch marks the items that were fine as rejected along with the batch.
+func rejectBatch(items []BatchItemResult) CreateBatchResult {
+	for i := range items {
+		if items[i].Err == nil {
+			items[i].Err = ErrBatchRejected
+		}
+	}
+	return CreateBatchResult{Items: items}
+}
+
+// GetBatch aggregates the batch's task statuses, someone else's batch is
+// ErrBatchNotFound.
+func (s *TaskService) GetBatch(ctx context.Context, id int64) (BatchProgress, error) {
+	if id <= 0 {
+		return BatchProgress{}, ErrInvalidID
+	}
+
+	batch, ok := s.store.GetBatch(id)
+	if !ok || !ownedBy(ctx, batch.Owner) {
+		return BatchProgress{}, ErrBatchNotFound
+	}
+
+	progress := BatchProgress{
+		ID:        batch.ID,
+		CreatedAt: batch.CreatedAt,
+		Total:     len(batch.TaskIDs),
+		Counts:    make(map[domain.TaskStatus]int),
+	}
+	for _, taskID := range batch.TaskIDs {
+		task, ok := s.store.Get(taskID)
+		if !ok {
+			continue
+		}
+		progress.Counts[task.Status]++
+	}
+	return progress, nil
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"interview-task-worker-pool/internal/auth"
+	"interview-task-worker-pool/internal/dag"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/store"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/workerpool"
+)
+
+func batchStore(t *testing.T, wantAtomic bool) *fakeStore {
+	return &fakeStore{
+		batchFn: func(tasks []domain.Task, _ store.DedupePolicy, atomic bool, _ int) (store.Batch, []store.BatchItem, error) {
+			if atomic != wantAtomic {
+				t.Fatalf("CreateBatch(atomic)=%v, want %v", atomic, wantAtomic)
+			}
+			items := make([]store.BatchItem, len(tasks))
+			for i, task := range tasks {
+				task.ID = int64(i + 1)
+				task.Status = domain.StatusPending
+				items[i] = store.BatchItem{Result: store.CreateResult{Task: task}}
+			}
+			return store.Batch{ID: 1}, items, nil
+		},
+		failFn: func(id int64, reason string) (domain.Task, error) {
+			return domain.Task{ID: id, Status: domain.StatusFailed, Error: reason}, nil
+		},
+	}
+}
+
+func TestCreateBatch_BestEffort_ReportsPerItem(t *testing.T) {
+	enqueued := 0
+	svc, _ := New(batchStore(t, false), &fakePool{enqueueFn: func(int64) error {
+		enqueued++
+		if enqueued == 2 {
+			return workerpool.ErrPoolFull
+		}
+		return nil
+	}})
+
+	res, err := svc.CreateBatch(context.Background(), []CreateTaskInput{{Title: "a"}, {Title: " "}, {Title: "c"}}, BatchBestEffort)
+	if err != nil {
+		t.Fatalf("CreateBatch() err=%v, want nil", err)
+	}
+	if res.BatchID != 1 || len(res.Items) != 3 {
+		t.Fatalf("CreateBatch() got=%+v, want batch 1 with 3 items", res)
+	}
+	if res.Items[0].Err != nil || res.Items[0].Task.ID == 0 {
+		t.Fatalf("item 0=%+v, want created", res.Items[0])
+	}
+	if !errors.Is(res.Items[1].Err, ErrInvalidInput) {
+		t.Fatalf("item 1 err=%v, want %v", res.Items[1].Err, ErrInvalidInput)
+	}
+	if !errors.Is(res.Items[2].Err, workerpool.ErrPoolFull) || res.Items[2].Task.Status != domain.StatusFailed {
+		t.Fatalf("item 2=%+v, want failed with %v", res.Items[2], workerpool.ErrPoolFull)
+	}
+}
+
+func TestCreateBatch_AllOrNothing_InvalidItemRejectsBatch(t *testing.T) {
+	svc, _ := New(&fakeStore{
+		batchFn: func([]domain.Task, store.DedupePolicy, bool, int) (store.Batch, []store.BatchItem, error) {
+			t.Fatalf("CreateBatch() should not reach the store")
+			return store.Batch{}, nil, nil
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }})
+
+	res, err := svc.CreateBatch(context.Background(), []CreateTaskInput{{Title: "a"}, {Title: ""}}, BatchAllOrNothing)
+	if !errors.Is(err, ErrBatchRejected) {
+		t.Fatalf("CreateBatch() err=%v, want %v", err, ErrBatchRejected)
+	}
+	if !errors.Is(res.Items[0].Err, ErrBatchRejected) || !errors.Is(res.Items[1].Err, ErrInvalidInput) {
+		t.Fatalf("items=%+v, want [rejected, invalid]", res.Items)
+	}
+}
+
+func TestCreateBatch_AllOrNothing_StoreConflictRejectsBatch(t *testing.T) {
+	svc, _ := New(&fakeStore{
+		batchFn: func([]domain.Task, store.DedupePolicy, bool, int) (store.Batch, []store.BatchItem, error) {
+			return store.Batch{}, []store.BatchItem{{}, {Err: store.ErrDuplicate}}, store.ErrBatchRejected
+		},
+	}, &fakePool{enqueueFn: func(int64) error {
+		t.Fatalf("Enqueue() should not be called for a rejected batch")
+		return nil
+	}})
+
+	res, err := svc.CreateBatch(context.Background(), []CreateTaskInput{{Title: "a"}, {Title: "b", DedupeKey: "k"}}, BatchAllOrNothing)
+	if !errors.Is(err, ErrBatchRejected) {
+		t.Fatalf("CreateBatch() err=%v, want %v", err, ErrBatchRejected)
+	}
+	if !errors.Is(res.Items[1].Err, ErrDuplicate) {
+		t.Fatalf("item 1 err=%v, want %v", res.Items[1].Err, ErrDuplicate)
+	}
+}
+
+func TestCreateBatch_Quota_AppliedByTheStore(t *testing.T) {
+	var gotMax int
+	svc, _ := New(&fakeStore{
+		batchFn: func(tasks []domain.Task, _ store.DedupePolicy, _ bool, maxActive int) (store.Batch, []store.BatchItem, error) {
+			gotMax = maxActive
+			return store.Batch{ID: 1}, []store.BatchItem{
+				{Result: store.CreateResult{Task: domain.Task{ID: 1, Status: domain.StatusPending}}},
+				{Err: store.ErrQuotaExceeded},
+			}, nil
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }}, WithQuota(func(string) int { return 3 }))
+
+	ctx := auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: "alice"})
+	res, err := svc.CreateBatch(ctx, []CreateTaskInput{{Title: "a"}, {Title: "b"}}, BatchBestEffort)
+	if err != nil {
+		t.Fatalf("CreateBatch() err=%v, want nil", err)
+	}
+	if gotMax != 3 {
+		t.Fatalf("CreateBatch(maxActive)=%d, want 3", gotMax)
+	}
+	if res.Items[0].Err != nil || !errors.Is(res.Items[1].Err, ErrQuotaExceeded) {
+		t.Fatalf("items=%+v, want item 1 over the quota", res.Items)
+	}
+}
+
+func TestCreateBatch_AllOrNothing_NoQueueRoomRejectsBatch(t *testing.T) {
+	pool := workerpool.New(2, nil)
+	svc, _ := New(&fakeStore{
+		batchFn: func([]domain.Task, store.DedupePolicy, bool, int) (store.Batch, []store.BatchItem, error) {
+			t.Fatalf("CreateBatch() should not reach the store")
+			return store.Batch{}, nil, nil
+		},
+	}, pool)
+
+	res, err := svc.CreateBatch(context.Background(), []CreateTaskInput{{Title: "a"}, {Title: "b"}, {Title: "c"}}, BatchAllOrNothing)
+	if !errors.Is(err, ErrBatchRejected) {
+		t.Fatalf("CreateBatch() err=%v, want %v", err, ErrBatchRejected)
+	}
+	for i, item := range res.Items {
+		if !errors.Is(item.Err, workerpool.ErrPoolFull) {
+			t.Fatalf("item %d err=%v, want %v", i, item.Err, workerpool.ErrPoolFull)
+		}
+	}
+	if pool.QueueDepth() != 0 {
+		t.Fatalf("QueueDepth()=%d, want 0", pool.QueueDepth())
+	}
+}
+
+func TestCreateBatch_AllOrNothing_HoldsRoomForReleasedBlockedTasks(t *testing.T) {
+	// b waits on a done parent, the resolver releases it right away
+	batch := []CreateTaskInput{{Title: "a"}, {Title: "b", DependsOn: []int64{1}}}
+	newService := func(size int) (*TaskService, *memory.TaskStore, *workerpool.Pool) {
+		st := memory.New()
+		parent, _ := st.Create(domain.Task{Title: "parent"})
+		_, _ = st.UpdateStatus(parent.ID, domain.StatusDone)
+		filler, _ := st.Create(domain.Task{Title: "filler"})
+
+		pool := workerpool.New(size, st) // not started, the filler stays queued
+		_ = pool.Enqueue(filler.ID)
+		svc, _ := New(st, pool, WithResolver(dag.NewResolver(st, pool)))
+		return svc, st, pool
+	}
+
+	// room for one more: the batch needs two
+	svc, st, pool := newService(2)
+	res, err := svc.CreateBatch(context.Background(), batch, BatchAllOrNothing)
+	if !errors.Is(err, ErrBatchRejected) {
+		t.Fatalf("CreateBatch() err=%v, want %v", err, ErrBatchRejected)
+	}
+	for i, item := range res.Items {
+		if !errors.Is(item.Err, workerpool.ErrPoolFull) {
+			t.Fatalf("item %d err=%v, want %v", i, item.Err, workerpool.ErrPoolFull)
+		}
+	}
+	if n, _ := st.Count(); n != 2 || pool.QueueDepth() != 1 {
+		t.Fatalf("stored=%d queued=%d, want the batch left out", n, pool.QueueDepth())
+	}
+
+	svc, _, pool = newService(3)
+	res, err = svc.CreateBatch(context.Background(), batch, BatchAllOrNothing)
+	if err != nil {
+		t.Fatalf("CreateBatch() err=%v, want nil", err)
+	}
+	for i, item := range res.Items {
+		if item.Err != nil || item.Task.Status != domain.StatusPending {
+			t.Fatalf("item %d = %+v err=%v, want pending", i, item.Task, item.Err)
+		}
+	}
+	if pool.QueueDepth() != 3 {
+		t.Fatalf("QueueDepth()=%d, want 3", pool.QueueDepth())
+	}
+}
+
+func TestCreateBatch_InvalidModeOrSize(t *testing.T) {
+	svc, _ := New(batchStore(t, false), &fakePool{enqueueFn: func(int64) error { return nil }})
+
+	if _, err := svc.CreateBatch(context.Background(), []CreateTaskInput{{Title: "a"}}, "sometimes"); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("CreateBatch(bad mode) err=%v, want %v", err, ErrInvalidInput)
+	}
+	if _, err := svc.CreateBatch(context.Background(), nil, BatchBestEffort); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("CreateBatch(empty) err=%v, want %v", err, ErrInvalidInput)
+	}
+}
+
+func TestGetBatch_CountsPerStatus(t *testing.T) {
+	tasks := map[int64]domain.Task{
+		1: {ID: 1, Status: domain.StatusDone},
+		2: {ID: 2, Status: domain.StatusDone},
+		3: {ID: 3, Status: domain.StatusRunning},
+	}
+	svc, _ := New(&fakeStore{
+		getBatchFn: func(id int64) (store.Batch, bool) {
+			return store.Batch{ID: id, TaskIDs: []int64{1, 2, 3}}, id == 5
+		},
+		getFn: func(id int64) (domain.Task, bool) {
+			task, ok := tasks[id]
+			return task, ok
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }})
+
+	progress, err := svc.GetBatch(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("GetBatch() err=%v, want nil", err)
+	}
+	if progress.Total != 3 || progress.Counts[domain.StatusDone] != 2 || progress.Counts[domain.StatusRunning] != 1 {
+		t.Fatalf("GetBatch()=%+v, want total=3 done=2 running=1", progress)
+	}
+
+	if _, err := svc.GetBatch(context.Background(), 6); !errors.Is(err, ErrBatchNotFound) {
+		t.Fatalf("GetBatch(6) err=%v, want %v", err, ErrBatchNotFound)
+	}
+}
+
+func TestGetBatch_OwnerOnly(t *testing.T) {
+	// every task of the batch is gone, the owner on the batch still decides
+	svc, _ := New(&fakeStore{
+		getBatchFn: func(id int64) (store.Batch, bool) {
+			return store.Batch{ID: id, Owner: "alice", TaskIDs: []int64{1, 2}}, true
+		},
+		getFn: func(int64) (domain.Task, bool) { return domain.Task{}, false },
+	}, &fakePool{enqueueFn: func(int64) error { return nil }})
+
+	alice := auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: "alice"})
+	bob := auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: "bob"})
+	admin := auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: "ops", Scopes: []auth.Scope{auth.ScopeAdmin}})
+
+	if _, err := svc.GetBatch(bob, 5); !errors.Is(err, ErrBatchNotFound) {
+		t.Fatalf("GetBatch(bob) err=%v, want %v", err, ErrBatchNotFound)
+	}
+	for name, ctx := range map[string]context.Context{"alice": alice, "admin": admin} {
+		if progress, err := svc.GetBatch(ctx, 5); err != nil || progress.Total != 2 {
+			t.Fatalf("GetBatch(%s)=%+v err=%v, want the batch", name, progress, err)
+		}
+	}
+}
@@ -3,9 +3,37 @@ package service
 import "errors"
 
 var (
-	ErrNotFound     = errors.New("task not found")
-	ErrInvalidInput = errors.New("invalid input")
-	ErrStoreNil     = errors.New("task store is nil")
-	ErrInvalidID    = errors.New("invalid task id")
-	ErrPoolNil      = errors.New("pool is nil")
+	ErrNotFound            = errors.New("task not found")
+	ErrInvalidInput        = errors.New("invalid input")
+	ErrStoreNil            = errors.New("task store is nil")
+	ErrInvalidID           = errors.New("invalid task id")
+	ErrPoolNil             = errors.New("pool is nil")
+	ErrIdempotencyConflict = errors.New("idempotency key already used with a different request")
+	ErrDuplicate           = errors.New("a task with the same dedupe key is already in flight")
+	ErrInvalidDependency   = errors.New("invalid dependency")
+	ErrBatchRejected       = errors.New("batch rejected")
+	ErrBatchNotFound       = errors.New("batch not found")
+	ErrQuotaExceeded       = errors.New("pending task quota exceeded")
+	ErrTaskFinished        = errors.New("task already finished")
+	ErrNotPending          = errors.New("task is no longer pending")
+	ErrVersionConflict     = errors.New("task was modified concurrently")
+	ErrHasDependents       = errors.New("blocked tasks depend on the task")
 )
+
+// InputError is an ErrInvalidInput naming the field at fault, by its JSON name.
+type InputError struct {
+	Field  string
+	Reason string
+}
+
+func (e *InputError) Error() string {
+	return ErrInvalidInput.Error() + ": " + e.Field + " " + e.Reason
+}
+
+func (e *InputError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+func invalidField(field, reason string) error {
+	return &InputError{Field: field, Reason: reason}
+}
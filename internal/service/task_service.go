@@ -1,31 +1,138 @@
 package service
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"interview-task-worker-pool/internal/auth"
+	"interview-task-worker-pool/internal/dag"
 	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/logging"
+	"interview-task-worker-pool/internal/store"
+	"interview-task-worker-pool/internal/tracing"
 	"interview-task-worker-pool/internal/workerpool"
 	"math/rand"
+	"sort"
 	"strings"
 	"time"
 )
 
+const (
+	DefaultIdempotencyTTL = 24 * time.Hour
+	DefaultDedupePolicy   = store.DedupeReturnExisting
+	maxKeyLen             = 255
+	maxDependencies       = 100
+	maxMetadata           = 32
+	maxMetadataValueLen   = 1024
+)
+
 type TaskStore interface {
-	Create(task domain.Task) (domain.Task, error)
+	CreateWith(task domain.Task, opts store.CreateOptions) (store.CreateResult, error)
+	CreateBatch(owner string, tasks []domain.Task, policy store.DedupePolicy, atomic bool, maxActive int) (store.Batch, []store.BatchItem, error)
+	GetBatch(id int64) (store.Batch, bool)
 	Get(id int64) (domain.Task, bool)
 	List() ([]domain.Task, error)
+	Find(filter store.Filter) ([]domain.Task, error)
 	Fail(id int64, reason string) (domain.Task, error)
+	Cancel(id int64, version int64, reason string) (domain.Task, error)
+	Update(id int64, version int64, patch store.TaskPatch) (domain.Task, error)
+	Delete(id int64, version int64) (domain.Task, error)
 }
 
 type TaskPool interface {
 	Enqueue(id int64) error
 }
 
+// DependencyResolver releases blocked tasks once their dependencies are done.
+type DependencyResolver interface {
+	Check(deps []int64) error
+	Register(task domain.Task, pool workerpool.TaskPool) (domain.Task, error)
+	Resolve(id int64, status domain.TaskStatus)
+	Cancel(id int64, version int64, reason string) (domain.Task, error)
+	Delete(id int64, version int64) (domain.Task, error)
+}
+
+// TaskLogs drops the execution log of a deleted task, see tasklog.Store.
+type TaskLogs interface {
+	Delete(id int64)
+}
+
+// aborter is implemented by pools that can stop a running task, see workerpool.Pool.Abort.
+type aborter interface {
+	Abort(id int64) bool
+}
+
+// reserver is implemented by pools that can hold queue room for a batch, see
+// workerpool.Pool.Reserve.
+type reserver interface {
+	Reserve(n int) (*workerpool.Reservation, error)
+}
+
 type TaskService struct {
 	store TaskStore
 	pool  workerpool.TaskPool
+
+	idempotencyTTL time.Duration
+	dedupePolicy   store.DedupePolicy
+	resolver       DependencyResolver
+	tracer         *tracing.Tracer
+	quota          func(owner string) int
+	taskLogs       TaskLogs
+}
+
+type Option func(*TaskService)
+
+// WithIdempotencyTTL sets how long an Idempotency-Key is remembered.
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(s *TaskService) {
+		if ttl > 0 {
+			s.idempotencyTTL = ttl
+		}
+	}
+}
+
+// WithDedupePolicy sets what happens to a task whose dedupe key is already in flight.
+func WithDedupePolicy(policy store.DedupePolicy) Option {
+	return func(s *TaskService) {
+		if policy.Valid() {
+			s.dedupePolicy = policy
+		}
+	}
+}
+
+// WithResolver enables depends_on, without it tasks can't have dependencies.
+func WithResolver(resolver DependencyResolver) Option {
+	return func(s *TaskService) {
+		s.resolver = resolver
+	}
 }
 
-func New(store TaskStore, pool workerpool.TaskPool) (*TaskService, error) {
+// WithTracer records spans for task creation and stores the trace on the task,
+// so the worker that runs it can continue the same trace.
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(s *TaskService) {
+		s.tracer = tracer
+	}
+}
+
+// WithQuota caps the unfinished tasks of each authenticated client, quota returns
+// the cap for an owner (0 for none).
+func WithQuota(quota func(owner string) int) Option {
+	return func(s *TaskService) {
+		s.quota = quota
+	}
+}
+
+// WithTaskLogs deletes the execution log of a task together with the task.
+func WithTaskLogs(logs TaskLogs) Option {
+	return func(s *TaskService) {
+		s.taskLogs = logs
+	}
+}
+
+func New(store TaskStore, pool workerpool.TaskPool, opts ...Option) (*TaskService, error) {
 	if store == nil {
 		return nil, ErrStoreNil
 	}
@@ -33,42 +140,218 @@ func New(store TaskStore, pool workerpool.TaskPool) (*TaskService, error) {
 		return nil, ErrPoolNil
 	}
 
-	return &TaskService{store: store, pool: pool}, nil
+	s := &TaskService{
+		store:          store,
+		pool:           pool,
+		idempotencyTTL: DefaultIdempotencyTTL,
+		dedupePolicy:   DefaultDedupePolicy,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+type CreateTaskInput struct {
+	Title          string
+	Description    string
+	IdempotencyKey string // optional, retries with the same key return the original task
+	DedupeKey      string // optional, at most one pending/running task per key
+
+	ConcurrencyKey   string // optional, tasks sharing it are limited by the pool
+	ConcurrencyLimit int    // max running tasks for ConcurrencyKey, defaults to 1
+
+	DependsOn []int64 // optional, the task stays blocked until these are done
+
+	Priority int               // optional, the client's own ordering
+	Metadata map[string]string // optional, at most 32 entries
+
+	RetainUntil time.Time // optional, overrides the retention TTL of the finished task
+}
+
+// fingerprint identifies the request a key was first used with, so reusing a key
+// for a different body can be told apart from a plain retry.
+func (in CreateTaskInput) fingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %q %q %q %d %v %d %q %d", in.Title, in.Description, in.DedupeKey, in.ConcurrencyKey, in.ConcurrencyLimit, in.DependsOn, in.Priority, in.Metadata, in.RetainUntil.UnixNano())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type CreateTaskResult struct {
+	Task     domain.Task
+	Replayed bool // returned for a repeated Idempotency-Key
+	Attached bool // an in-flight task with the same dedupe key was returned instead
+}
+
+func (s *TaskService) CreateTask(ctx context.Context, in CreateTaskInput) (_ CreateTaskResult, err error) {
+	ctx, span := s.tracer.Start(ctx, "TaskService.CreateTask")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	task, err := s.prepare(ctx, &in)
+	if err != nil {
+		return CreateTaskResult{}, err
+	}
+
+	_, storeSpan := s.tracer.Start(ctx, "store.CreateWith")
+	res, err := s.store.CreateWith(task, store.CreateOptions{
+		IdempotencyKey: scopedKey(task.Owner, in.IdempotencyKey),
+		Fingerprint:    in.fingerprint(),
+		KeyTTL:         s.idempotencyTTL,
+		DedupePolicy:   s.dedupePolicy,
+		MaxActive:      s.maxActive(task.Owner),
+	})
+	storeSpan.RecordError(err)
+	storeSpan.End()
+	if err != nil {
+		return CreateTaskResult{}, mapStoreErr(err)
+	}
+	span.SetAttributes(
+		tracing.Int("task.id", res.Task.ID),
+		tracing.Bool("task.replayed", res.Replayed),
+		tracing.Bool("task.attached", res.Attached),
+	)
+
+	// a retry never enqueues again, it gets the original task and outcome back
+	if res.Replayed && res.Attached {
+		return CreateTaskResult{Task: res.Task, Replayed: true, Attached: true}, nil
+	}
+	if res.Replayed {
+		return CreateTaskResult{Task: res.Task, Replayed: true}, replayErr(res.Task)
+	}
+	// the in-flight task is already queued or running
+	if res.Attached {
+		return CreateTaskResult{Task: res.Task, Attached: true}, nil
+	}
+
+	submitted, err := s.submit(ctx, res, s.pool)
+	return CreateTaskResult{Task: submitted}, err
+}
+
+// maxActive is the owner's quota of unfinished tasks, anonymous callers have none.
+func (s *TaskService) maxActive(owner string) int {
+	if s.quota == nil || owner == "" {
+		return 0
+	}
+	return s.quota(owner)
 }
 
-func (s *TaskService) CreateTask(title, description string) (domain.Task, error) {
-	title = strings.TrimSpace(title)
-	description = strings.TrimSpace(description)
+// traceParent is the traceparent of the current span, empty when not tracing.
+func traceParent(ctx context.Context) string {
+	return tracing.SpanContextFromContext(ctx).Traceparent()
+}
+
+// scopedKey keeps the idempotency keys of different owners apart.
+func scopedKey(owner, key string) string {
+	if owner == "" || key == "" {
+		return key
+	}
+	return owner + "\x00" + key
+}
+
+// prepare normalizes and validates the input and builds the task to store,
+// owned by the caller and carrying its trace and request ID.
+func (s *TaskService) prepare(ctx context.Context, in *CreateTaskInput) (domain.Task, error) {
+	in.Title = strings.TrimSpace(in.Title)
+	in.Description = strings.TrimSpace(in.Description)
+	in.IdempotencyKey = strings.TrimSpace(in.IdempotencyKey)
+	in.DedupeKey = strings.TrimSpace(in.DedupeKey)
+	in.ConcurrencyKey = strings.TrimSpace(in.ConcurrencyKey)
 
 	// assumption: description is optional
-	if title == "" {
-		return domain.Task{}, ErrInvalidInput
+	if in.Title == "" {
+		return domain.Task{}, invalidField("title", "is required")
+	}
+	for _, key := range []struct{ field, value string }{
+		{"idempotency_key", in.IdempotencyKey},
+		{"dedupe_key", in.DedupeKey},
+		{"concurrency_key", in.ConcurrencyKey},
+	} {
+		if len(key.value) > maxKeyLen {
+			return domain.Task{}, invalidField(key.field, fmt.Sprintf("is longer than %d bytes", maxKeyLen))
+		}
+	}
+	if in.ConcurrencyLimit < 0 {
+		return domain.Task{}, invalidField("concurrency_limit", "is negative")
+	}
+	if in.ConcurrencyKey == "" && in.ConcurrencyLimit != 0 {
+		return domain.Task{}, invalidField("concurrency_limit", "needs a concurrency_key")
+	}
+	if in.ConcurrencyKey != "" && in.ConcurrencyLimit == 0 {
+		in.ConcurrencyLimit = 1
+	}
+	if err := checkMetadata(in.Metadata); err != nil {
+		return domain.Task{}, err
+	}
+	if !in.RetainUntil.IsZero() && !in.RetainUntil.After(time.Now()) {
+		return domain.Task{}, invalidField("retain_until", "is in the past")
+	}
+	if err := s.checkDependencies(ctx, in.DependsOn); err != nil {
+		return domain.Task{}, err
 	}
 
 	task := domain.Task{
-		Title:        title,
-		Description:  description,
-		CreatedAt:    time.Now(),
-		WorkDuration: time.Duration(rand.Intn(5)+1) * time.Second,
+		Title:            in.Title,
+		Description:      in.Description,
+		DedupeKey:        in.DedupeKey,
+		ConcurrencyKey:   in.ConcurrencyKey,
+		ConcurrencyLimit: in.ConcurrencyLimit,
+		DependsOn:        in.DependsOn,
+		Priority:         in.Priority,
+		Metadata:         in.Metadata,
+		RetainUntil:      in.RetainUntil,
+		CreatedAt:        time.Now(),
+		WorkDuration:     time.Duration(rand.Intn(5)+1) * time.Second,
+		TraceParent:      traceParent(ctx),
+		RequestID:        logging.RequestIDFromContext(ctx),
+	}
+	if p, ok := auth.PrincipalFromContext(ctx); ok {
+		task.Owner = p.ID
+	}
+	if len(in.DependsOn) > 0 {
+		task.Status = domain.StatusBlocked
+	}
+	return task, nil
+}
+
+// submit hands a freshly stored task over to the resolver or the pool.
+func (s *TaskService) submit(ctx context.Context, res store.CreateResult, pool workerpool.TaskPool) (domain.Task, error) {
+	created := res.Task
+
+	if res.ReplacedID != 0 && s.resolver != nil {
+		s.resolver.Resolve(res.ReplacedID, domain.StatusCanceled)
 	}
 
-	created, err := s.store.Create(task)
-	if err != nil {
-		return domain.Task{}, err
+	// blocked tasks are enqueued by the resolver once their dependencies are done
+	if created.Status == domain.StatusBlocked {
+		_, span := s.tracer.Start(ctx, "resolver.Register", tracing.WithAttributes(tracing.Int("task.id", created.ID)))
+		defer span.End()
+		return s.resolver.Register(created, pool)
 	}
 
 	// Enqueue (non-blocking)
-	if err := s.pool.Enqueue(created.ID); err != nil {
+	_, span := s.tracer.Start(ctx, "pool.Enqueue", tracing.WithAttributes(tracing.Int("task.id", created.ID)))
+	err := pool.Enqueue(created.ID)
+	span.RecordError(err)
+	span.End()
+	if err != nil {
 		// pool overflow ~> mark task failed and attach reason
 		if errors.Is(err, workerpool.ErrPoolFull) {
+			_, span := s.tracer.Start(ctx, "store.Fail")
 			failedTask, fErr := s.store.Fail(created.ID, workerpool.ErrPoolFull.Error())
+			span.End()
 			if fErr != nil {
 				return domain.Task{}, fErr
 			}
 			return failedTask, workerpool.ErrPoolFull
 		}
 		if errors.Is(err, workerpool.ErrPoolClosed) {
+			_, span := s.tracer.Start(ctx, "store.Fail")
 			failedTask, fErr := s.store.Fail(created.ID, workerpool.ErrPoolClosed.Error())
+			span.End()
 			if fErr != nil {
 				return domain.Task{}, fErr
 			}
@@ -79,18 +362,297 @@ func (s *TaskService) CreateTask(title, description string) (domain.Task, error)
 	return created, nil
 }
 
-func (s *TaskService) GetTask(id int64) (domain.Task, error) {
+func mapStoreErr(err error) error {
+	switch {
+	case errors.Is(err, store.ErrIdempotencyConflict):
+		return ErrIdempotencyConflict
+	case errors.Is(err, store.ErrDuplicate):
+		return ErrDuplicate
+	case errors.Is(err, store.ErrQuotaExceeded):
+		return ErrQuotaExceeded
+	case errors.Is(err, store.ErrInvalidTransition):
+		return ErrTaskFinished
+	case errors.Is(err, store.ErrNotPending):
+		return ErrNotPending
+	case errors.Is(err, store.ErrVersionConflict):
+		return ErrVersionConflict
+	case errors.Is(err, store.ErrNotFound):
+		return ErrNotFound
+	}
+	return err
+}
+
+func checkMetadata(metadata map[string]string) error {
+	if len(metadata) > maxMetadata {
+		return invalidField("metadata", fmt.Sprintf("has more than %d entries", maxMetadata))
+	}
+	for k, v := range metadata {
+		if k == "" || len(k) > maxKeyLen {
+			return invalidField("metadata", fmt.Sprintf("has an empty key or one longer than %d bytes", maxKeyLen))
+		}
+		if len(v) > maxMetadataValueLen {
+			return invalidField("metadata", fmt.Sprintf("value of %q is longer than %d bytes", k, maxMetadataValueLen))
+		}
+	}
+	return nil
+}
+
+func (s *TaskService) checkDependencies(ctx context.Context, deps []int64) error {
+	if len(deps) == 0 {
+		return nil
+	}
+	if s.resolver == nil {
+		return invalidField("depends_on", "is not supported")
+	}
+	if len(deps) > maxDependencies {
+		return invalidField("depends_on", fmt.Sprintf("has more than %d ids", maxDependencies))
+	}
+
+	seen := make(map[int64]bool, len(deps))
+	for _, id := range deps {
+		if id <= 0 || seen[id] {
+			return invalidField("depends_on", fmt.Sprintf("has an invalid or repeated id %d", id))
+		}
+		seen[id] = true
+
+		// someone else's task is as unknown as a missing one
+		if task, ok := s.store.Get(id); ok && !visible(ctx, task) {
+			return fmt.Errorf("%w: unknown dependency %d", ErrInvalidDependency, id)
+		}
+	}
+
+	if err := s.resolver.Check(deps); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDependency, err)
+	}
+	return nil
+}
+
+// replayErr rebuilds the error the original create returned, the only failures a
+// create can report with a task are the enqueue ones and those are kept in task.Error.
+func replayErr(task domain.Task) error {
+	if task.Status != domain.StatusFailed {
+		return nil
+	}
+	switch task.Error {
+	case workerpool.ErrPoolFull.Error():
+		return workerpool.ErrPoolFull
+	case workerpool.ErrPoolClosed.Error():
+		return workerpool.ErrPoolClosed
+	}
+	return nil
+}
+
+// visible reports whether the caller may see task: its owner and admins can,
+// everyone can when auth is off.
+func visible(ctx context.Context, task domain.Task) bool {
+	return ownedBy(ctx, task.Owner)
+}
+
+// ownedBy reports whether the caller may see what owner created.
+func ownedBy(ctx context.Context, owner string) bool {
+	p, ok := auth.PrincipalFromContext(ctx)
+	return !ok || p.IsAdmin() || owner == p.ID
+}
+
+// GetTask returns the task, ErrNotFound when it belongs to someone else.
+func (s *TaskService) GetTask(ctx context.Context, id int64) (domain.Task, error) {
 	if id <= 0 {
 		return domain.Task{}, ErrInvalidID
 	}
 
 	task, ok := s.store.Get(id)
-	if !ok {
+	if !ok || !visible(ctx, task) {
 		return domain.Task{}, ErrNotFound
 	}
 	return task, nil
 }
 
-func (s *TaskService) ListTasks() ([]domain.Task, error) {
-	return s.store.List()
+// CancelTask cancels a blocked, pending or running task. Blocked tasks waiting on
+// it are canceled too, a running one has its executor stopped.
+func (s *TaskService) CancelTask(ctx context.Context, id int64) (domain.Task, error) {
+	if _, err := s.GetTask(ctx, id); err != nil {
+		return domain.Task{}, err
+	}
+	return s.cancel(id, 0)
+}
+
+// cancel cancels task id if its version still is version (any with 0).
+func (s *TaskService) cancel(id int64, version int64) (domain.Task, error) {
+	const reason = "canceled by request"
+	var (
+		task domain.Task
+		err  error
+	)
+	if s.resolver != nil {
+		task, err = s.resolver.Cancel(id, version, reason)
+	} else {
+		task, err = s.store.Cancel(id, version, reason)
+	}
+	if err != nil {
+		return domain.Task{}, mapStoreErr(err)
+	}
+
+	if a, ok := s.pool.(aborter); ok {
+		a.Abort(id)
+	}
+	return task, nil
+}
+
+// UpdateTaskInput lists the fields to change, nil ones are kept.
+type UpdateTaskInput struct {
+	Title       *string
+	Description *string
+	Priority    *int
+	Metadata    map[string]string // replaces the whole metadata when non-nil
+
+	// the version the change was made against, the update fails with
+	// ErrVersionConflict if the task changed since. Zero skips the check.
+	Version int64
+}
+
+// UpdateTask edits a task that hasn't started yet (blocked or pending),
+// ErrNotPending once it has.
+func (s *TaskService) UpdateTask(ctx context.Context, id int64, in UpdateTaskInput) (domain.Task, error) {
+	if _, err := s.GetTask(ctx, id); err != nil {
+		return domain.Task{}, err
+	}
+
+	if in.Title != nil {
+		title := strings.TrimSpace(*in.Title)
+		if title == "" {
+			return domain.Task{}, invalidField("title", "is required")
+		}
+		in.Title = &title
+	}
+	if in.Description != nil {
+		description := strings.TrimSpace(*in.Description)
+		in.Description = &description
+	}
+	if err := checkMetadata(in.Metadata); err != nil {
+		return domain.Task{}, err
+	}
+
+	task, err := s.store.Update(id, in.Version, store.TaskPatch{
+		Title:       in.Title,
+		Description: in.Description,
+		Priority:    in.Priority,
+		Metadata:    in.Metadata,
+	})
+	if err != nil {
+		return domain.Task{}, mapStoreErr(err)
+	}
+	return task, nil
+}
+
+// DeleteTask removes a task and its execution log. Unfinished tasks are
+// canceled first, like CancelTask does. A non-zero version must be the task's
+// current one, else ErrVersionConflict. A task blocked tasks still depend on
+// is kept, ErrHasDependents.
+func (s *TaskService) DeleteTask(ctx context.Context, id int64, version int64) error {
+	task, err := s.GetTask(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !task.Status.Terminal() {
+		canceled, err := s.cancel(id, version)
+		switch {
+		case err == nil:
+			// the cancel was the version asked for, the delete must follow it
+			version = canceled.Version
+		case errors.Is(err, ErrTaskFinished):
+			// finished in between, the delete checks the version against the finished task
+		default:
+			return err
+		}
+	}
+
+	if err := s.delete(id, version); err != nil {
+		return err
+	}
+	if s.taskLogs != nil {
+		s.taskLogs.Delete(id)
+	}
+	return nil
+}
+
+// delete removes a finished task, the resolver refuses one that blocked tasks
+// still wait on.
+func (s *TaskService) delete(id int64, version int64) error {
+	var err error
+	if s.resolver != nil {
+		_, err = s.resolver.Delete(id, version)
+	} else {
+		_, err = s.store.Delete(id, version)
+	}
+	if errors.Is(err, dag.ErrHasDependents) {
+		return ErrHasDependents
+	}
+	return mapStoreErr(err)
+}
+
+type TaskFilter struct {
+	Owner string // only admins can list other owners' tasks
+}
+
+// ListTasks returns the caller's tasks, or every task matching filter for admins.
+// The owner is filtered by the store, which can use its index for it.
+func (s *TaskService) ListTasks(ctx context.Context, filter TaskFilter) ([]domain.Task, error) {
+	q := store.Filter{Owner: filter.Owner}
+	if p, ok := auth.PrincipalFromContext(ctx); ok && !p.IsAdmin() {
+		if filter.Owner != "" && filter.Owner != p.ID {
+			return []domain.Task{}, nil
+		}
+		q.Owner = p.ID
+	}
+	return s.store.Find(q)
+}
+
+// TaskGraph returns every task connected to id through dependencies, ancestors and
+// descendants alike, the task itself included.
+func (s *TaskService) TaskGraph(ctx context.Context, id int64) ([]domain.Task, error) {
+	if _, err := s.GetTask(ctx, id); err != nil {
+		return nil, err
+	}
+
+	all, err := s.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]domain.Task, len(all))
+	dependents := make(map[int64][]int64)
+	for _, t := range all {
+		if !visible(ctx, t) {
+			continue
+		}
+		byID[t.ID] = t
+		for _, parentID := range t.DependsOn {
+			dependents[parentID] = append(dependents[parentID], t.ID)
+		}
+	}
+
+	seen := map[int64]bool{id: true}
+	stack := []int64{id}
+	graph := make([]domain.Task, 0)
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		t, ok := byID[cur]
+		if !ok {
+			continue
+		}
+		graph = append(graph, t)
+
+		for _, next := range append(append([]int64(nil), t.DependsOn...), dependents[cur]...) {
+			if !seen[next] {
+				seen[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+
+	sort.Slice(graph, func(i, j int) bool { return graph[i].ID < graph[j].ID })
+	return graph, nil
 }
@@ -1,24 +1,47 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
+	"interview-task-worker-pool/internal/auth"
 	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/logging"
+	"interview-task-worker-pool/internal/store"
+	"interview-task-worker-pool/internal/tracing"
 	"interview-task-worker-pool/internal/workerpool"
 )
 
 // --- fakes ---
 
 type fakeStore struct {
-	createFn func(domain.Task) (domain.Task, error)
-	getFn    func(int64) (domain.Task, bool)
-	listFn   func() ([]domain.Task, error)
-	failFn   func(int64, string) (domain.Task, error)
+	createFn     func(domain.Task) (domain.Task, error)
+	createWithFn func(domain.Task, store.CreateOptions) (store.CreateResult, error)
+	batchFn      func([]domain.Task, store.DedupePolicy, bool, int) (store.Batch, []store.BatchItem, error)
+	getBatchFn   func(int64) (store.Batch, bool)
+	getFn        func(int64) (domain.Task, bool)
+	listFn       func() ([]domain.Task, error)
+	failFn       func(int64, string) (domain.Task, error)
+	cancelFn     func(int64, string) (domain.Task, error)
+	updateFn     func(int64, int64, store.TaskPatch) (domain.Task, error)
+	deleteFn     func(int64, int64) (domain.Task, error)
 }
 
-func (s *fakeStore) Create(t domain.Task) (domain.Task, error) {
-	return s.createFn(t)
+func (s *fakeStore) CreateWith(t domain.Task, opts store.CreateOptions) (store.CreateResult, error) {
+	if s.createWithFn != nil {
+		return s.createWithFn(t, opts)
+	}
+	created, err := s.createFn(t)
+	return store.CreateResult{Task: created}, err
+}
+func (s *fakeStore) CreateBatch(owner string, tasks []domain.Task, policy store.DedupePolicy, atomic bool, maxActive int) (store.Batch, []store.BatchItem, error) {
+	return s.batchFn(tasks, policy, atomic, maxActive)
+}
+func (s *fakeStore) GetBatch(id int64) (store.Batch, bool) {
+	return s.getBatchFn(id)
 }
 func (s *fakeStore) Get(id int64) (domain.Task, bool) {
 	return s.getFn(id)
@@ -26,17 +49,41 @@ func (s *fakeStore) Get(id int64) (domain.Task, bool) {
 func (s *fakeStore) List() ([]domain.Task, error) {
 	return s.listFn()
 }
+func (s *fakeStore) Find(filter store.Filter) ([]domain.Task, error) {
+	all, err := s.listFn()
+	tasks := make([]domain.Task, 0)
+	for _, task := range all {
+		if filter.Match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, err
+}
 func (s *fakeStore) Fail(id int64, reason string) (domain.Task, error) {
 	return s.failFn(id, reason)
 }
+func (s *fakeStore) Cancel(id int64, version int64, reason string) (domain.Task, error) {
+	return s.cancelFn(id, reason)
+}
+func (s *fakeStore) Update(id int64, version int64, patch store.TaskPatch) (domain.Task, error) {
+	return s.updateFn(id, version, patch)
+}
+func (s *fakeStore) Delete(id int64, version int64) (domain.Task, error) {
+	return s.deleteFn(id, version)
+}
 
 type fakePool struct {
 	enqueueFn func(int64) error
+	aborted   []int64
 }
 
 func (p *fakePool) Enqueue(id int64) error {
 	return p.enqueueFn(id)
 }
+func (p *fakePool) Abort(id int64) bool {
+	p.aborted = append(p.aborted, id)
+	return true
+}
 
 // --- tests ---
 
@@ -83,13 +130,22 @@ func TestCreateTask_InvalidInput(t *testing.T) {
 		t.Fatalf("New() err=%v, want nil", err)
 	}
 
-	_, e := svc.CreateTask("   ", "desc")
+	_, e := svc.CreateTask(context.Background(), CreateTaskInput{Title: "   ", Description: "desc"})
 	if e == nil {
 		t.Fatalf("CreateTask() err=nil, want ErrInvalidInput")
 	}
 	if !errors.Is(e, ErrInvalidInput) {
 		t.Fatalf("CreateTask() err=%v, want %v", e, ErrInvalidInput)
 	}
+	var inputErr *InputError
+	if !errors.As(e, &inputErr) || inputErr.Field != "title" {
+		t.Fatalf("CreateTask() err=%v, want an InputError on title", e)
+	}
+
+	_, e = svc.CreateTask(context.Background(), CreateTaskInput{Title: "t", RetainUntil: time.Now().Add(-time.Minute)})
+	if !errors.As(e, &inputErr) || inputErr.Field != "retain_until" {
+		t.Fatalf("CreateTask(retain_until in the past) err=%v, want an InputError on retain_until", e)
+	}
 }
 
 func TestCreateTask_Success_Enqueued(t *testing.T) {
@@ -123,10 +179,11 @@ func TestCreateTask_Success_Enqueued(t *testing.T) {
 		t.Fatalf("New() err=%v, want nil", err)
 	}
 
-	out, e := svc.CreateTask("Title", "Desc")
+	res, e := svc.CreateTask(context.Background(), CreateTaskInput{Title: "Title", Description: "Desc"})
 	if e != nil {
 		t.Fatalf("CreateTask() err=%v, want nil", e)
 	}
+	out := res.Task
 	if !enqueueCalled {
 		t.Fatalf("Enqueue was not called")
 	}
@@ -177,7 +234,8 @@ func TestCreateTask_PoolFull_FailsTask(t *testing.T) {
 
 	svc, _ := New(store, pool)
 
-	task, err := svc.CreateTask("t", "d")
+	res, err := svc.CreateTask(context.Background(), CreateTaskInput{Title: "t", Description: "d"})
+	task := res.Task
 	if err == nil {
 		t.Fatalf("CreateTask() err=nil, want %v", workerpool.ErrPoolFull)
 	}
@@ -217,7 +275,8 @@ func TestCreateTask_PoolClosed_FailsTask(t *testing.T) {
 
 	svc, _ := New(store, pool)
 
-	task, err := svc.CreateTask("t", "d")
+	res, err := svc.CreateTask(context.Background(), CreateTaskInput{Title: "t", Description: "d"})
+	task := res.Task
 	if err == nil {
 		t.Fatalf("CreateTask() err=nil, want %v", workerpool.ErrPoolClosed)
 	}
@@ -240,8 +299,465 @@ func TestGetTask_InvalidID(t *testing.T) {
 		failFn:   func(int64, string) (domain.Task, error) { return domain.Task{}, nil },
 	}, &fakePool{enqueueFn: func(int64) error { return nil }})
 
-	_, err := svc.GetTask(0)
+	_, err := svc.GetTask(context.Background(), 0)
 	if err == nil || !errors.Is(err, ErrInvalidID) {
 		t.Fatalf("GetTask() err=%v, want %v", err, ErrInvalidID)
 	}
 }
+
+func TestCreateTask_IdempotencyKey_PassedToStore(t *testing.T) {
+	var got store.CreateOptions
+	svc, _ := New(&fakeStore{
+		createWithFn: func(task domain.Task, opts store.CreateOptions) (store.CreateResult, error) {
+			got = opts
+			task.ID = 1
+			task.Status = domain.StatusPending
+			return store.CreateResult{Task: task}, nil
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }}, WithIdempotencyTTL(time.Minute))
+
+	_, err := svc.CreateTask(context.Background(), CreateTaskInput{Title: "t", Description: "d", IdempotencyKey: " key-1 "})
+	if err != nil {
+		t.Fatalf("CreateTask() err=%v, want nil", err)
+	}
+	if got.IdempotencyKey != "key-1" {
+		t.Fatalf("opts.IdempotencyKey=%q, want %q", got.IdempotencyKey, "key-1")
+	}
+	if got.Fingerprint == "" {
+		t.Fatalf("opts.Fingerprint is empty, want non-empty")
+	}
+	if got.KeyTTL != time.Minute {
+		t.Fatalf("opts.KeyTTL=%s, want %s", got.KeyTTL, time.Minute)
+	}
+}
+
+func TestCreateTask_IdempotentReplay_NoEnqueue(t *testing.T) {
+	svc, _ := New(&fakeStore{
+		createWithFn: func(task domain.Task, opts store.CreateOptions) (store.CreateResult, error) {
+			task.ID = 7
+			task.Status = domain.StatusFailed
+			task.Error = workerpool.ErrPoolFull.Error()
+			return store.CreateResult{Task: task, Replayed: true}, nil
+		},
+	}, &fakePool{enqueueFn: func(int64) error {
+		t.Fatalf("Enqueue() should not be called on replay")
+		return nil
+	}})
+
+	res, err := svc.CreateTask(context.Background(), CreateTaskInput{Title: "t", IdempotencyKey: "k"})
+	if !errors.Is(err, workerpool.ErrPoolFull) {
+		t.Fatalf("CreateTask() err=%v, want %v", err, workerpool.ErrPoolFull)
+	}
+	if !res.Replayed || res.Task.ID != 7 {
+		t.Fatalf("CreateTask() got=%+v, want replay of task 7", res)
+	}
+}
+
+func TestCreateTask_IdempotencyConflict(t *testing.T) {
+	svc, _ := New(&fakeStore{
+		createWithFn: func(domain.Task, store.CreateOptions) (store.CreateResult, error) {
+			return store.CreateResult{}, store.ErrIdempotencyConflict
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }})
+
+	_, err := svc.CreateTask(context.Background(), CreateTaskInput{Title: "t", IdempotencyKey: "k"})
+	if !errors.Is(err, ErrIdempotencyConflict) {
+		t.Fatalf("CreateTask() err=%v, want %v", err, ErrIdempotencyConflict)
+	}
+}
+
+func TestCreateTask_Dedupe_AttachedNoEnqueue(t *testing.T) {
+	var gotPolicy store.DedupePolicy
+	svc, _ := New(&fakeStore{
+		createWithFn: func(task domain.Task, opts store.CreateOptions) (store.CreateResult, error) {
+			gotPolicy = opts.DedupePolicy
+			if task.DedupeKey != "job-1" {
+				t.Fatalf("task.DedupeKey=%q, want %q", task.DedupeKey, "job-1")
+			}
+			return store.CreateResult{Task: domain.Task{ID: 3, Status: domain.StatusRunning, DedupeKey: "job-1"}, Attached: true}, nil
+		},
+	}, &fakePool{enqueueFn: func(int64) error {
+		t.Fatalf("Enqueue() should not be called when attached")
+		return nil
+	}}, WithDedupePolicy(store.DedupeReturnExisting))
+
+	res, err := svc.CreateTask(context.Background(), CreateTaskInput{Title: "t", DedupeKey: "job-1"})
+	if err != nil {
+		t.Fatalf("CreateTask() err=%v, want nil", err)
+	}
+	if !res.Attached || res.Task.ID != 3 {
+		t.Fatalf("CreateTask() got=%+v, want attached to task 3", res)
+	}
+	if gotPolicy != store.DedupeReturnExisting {
+		t.Fatalf("opts.DedupePolicy=%q, want %q", gotPolicy, store.DedupeReturnExisting)
+	}
+}
+
+func TestCreateTask_Dedupe_Rejected(t *testing.T) {
+	svc, _ := New(&fakeStore{
+		createWithFn: func(domain.Task, store.CreateOptions) (store.CreateResult, error) {
+			return store.CreateResult{}, store.ErrDuplicate
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }}, WithDedupePolicy(store.DedupeReject))
+
+	_, err := svc.CreateTask(context.Background(), CreateTaskInput{Title: "t", DedupeKey: "job-1"})
+	if !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("CreateTask() err=%v, want %v", err, ErrDuplicate)
+	}
+}
+
+func TestCreateTask_ConcurrencyKey_DefaultLimitAndValidation(t *testing.T) {
+	var created domain.Task
+	svc, _ := New(&fakeStore{
+		createFn: func(task domain.Task) (domain.Task, error) {
+			created = task
+			task.ID = 1
+			return task, nil
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }})
+
+	if _, err := svc.CreateTask(context.Background(), CreateTaskInput{Title: "t", ConcurrencyKey: "db"}); err != nil {
+		t.Fatalf("CreateTask() err=%v, want nil", err)
+	}
+	if created.ConcurrencyKey != "db" || created.ConcurrencyLimit != 1 {
+		t.Fatalf("created key=%q limit=%d, want db/1", created.ConcurrencyKey, created.ConcurrencyLimit)
+	}
+
+	for _, in := range []CreateTaskInput{
+		{Title: "t", ConcurrencyKey: "db", ConcurrencyLimit: -1},
+		{Title: "t", ConcurrencyLimit: 2},
+	} {
+		if _, err := svc.CreateTask(context.Background(), in); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("CreateTask(%+v) err=%v, want %v", in, err, ErrInvalidInput)
+		}
+	}
+}
+
+func TestCreateTask_DependsOn_WithoutResolver(t *testing.T) {
+	svc, _ := New(&fakeStore{
+		createFn: func(task domain.Task) (domain.Task, error) {
+			t.Fatalf("Create() should not be called on invalid input")
+			return domain.Task{}, nil
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }})
+
+	_, err := svc.CreateTask(context.Background(), CreateTaskInput{Title: "t", DependsOn: []int64{1}})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("CreateTask() err=%v, want %v", err, ErrInvalidInput)
+	}
+}
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(span tracing.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+func TestCreateTask_Tracing_SpansAndTraceParent(t *testing.T) {
+	var stored domain.Task
+	store := &fakeStore{
+		createFn: func(task domain.Task) (domain.Task, error) {
+			stored = task
+			task.ID = 1
+			task.Status = domain.StatusPending
+			return task, nil
+		},
+	}
+	pool := &fakePool{enqueueFn: func(int64) error { return nil }}
+
+	rec := &spanRecorder{}
+	tracer := tracing.NewTracer("test", rec)
+	svc, err := New(store, pool, WithTracer(tracer))
+	if err != nil {
+		t.Fatalf("New() err=%v, want nil", err)
+	}
+
+	ctx, root := tracer.Start(context.Background(), "HTTP POST /tasks")
+	if _, err := svc.CreateTask(ctx, CreateTaskInput{Title: "a"}); err != nil {
+		t.Fatalf("CreateTask() err=%v, want nil", err)
+	}
+	root.End()
+
+	byName := make(map[string]tracing.SpanData)
+	for _, s := range rec.spans {
+		byName[s.Name] = s
+		if s.SpanContext.TraceID != root.SpanContext().TraceID {
+			t.Fatalf("span %q trace=%s, want %s", s.Name, s.SpanContext.TraceID, root.SpanContext().TraceID)
+		}
+	}
+	create, ok := byName["TaskService.CreateTask"]
+	if !ok || create.Parent != root.SpanContext().SpanID {
+		t.Fatalf("TaskService.CreateTask span=%+v, want child of the request span", create)
+	}
+	for _, name := range []string{"store.CreateWith", "pool.Enqueue"} {
+		if byName[name].Parent != create.SpanContext.SpanID {
+			t.Fatalf("%s span parent=%s, want %s", name, byName[name].Parent, create.SpanContext.SpanID)
+		}
+	}
+
+	// the worker continues the trace from the CreateTask span
+	if stored.TraceParent != create.SpanContext.Traceparent() {
+		t.Fatalf("TraceParent=%q, want %q", stored.TraceParent, create.SpanContext.Traceparent())
+	}
+}
+
+func TestCreateTask_RequestID_CarriedOntoTask(t *testing.T) {
+	var stored domain.Task
+	store := &fakeStore{
+		createFn: func(task domain.Task) (domain.Task, error) {
+			stored = task
+			task.ID = 1
+			return task, nil
+		},
+	}
+	svc, err := New(store, &fakePool{enqueueFn: func(int64) error { return nil }})
+	if err != nil {
+		t.Fatalf("New() err=%v, want nil", err)
+	}
+
+	ctx := logging.ContextWithRequestID(context.Background(), "req-1")
+	if _, err := svc.CreateTask(ctx, CreateTaskInput{Title: "a"}); err != nil {
+		t.Fatalf("CreateTask() err=%v, want nil", err)
+	}
+	if stored.RequestID != "req-1" {
+		t.Fatalf("RequestID=%q, want req-1", stored.RequestID)
+	}
+}
+
+func TestTasks_ScopedToOwner(t *testing.T) {
+	tasks := map[int64]domain.Task{
+		1: {ID: 1, Title: "a", Owner: "alice"},
+		2: {ID: 2, Title: "b", Owner: "bob"},
+	}
+	var keys []string
+	store := &fakeStore{
+		createWithFn: func(task domain.Task, opts store.CreateOptions) (store.CreateResult, error) {
+			keys = append(keys, opts.IdempotencyKey)
+			task.ID = 3
+			return store.CreateResult{Task: task}, nil
+		},
+		getFn: func(id int64) (domain.Task, bool) {
+			task, ok := tasks[id]
+			return task, ok
+		},
+		listFn: func() ([]domain.Task, error) { return []domain.Task{tasks[1], tasks[2]}, nil },
+	}
+	svc, err := New(store, &fakePool{enqueueFn: func(int64) error { return nil }})
+	if err != nil {
+		t.Fatalf("New() err=%v, want nil", err)
+	}
+
+	alice := auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: "alice"})
+	bob := auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: "bob"})
+	admin := auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: "ops", Scopes: []auth.Scope{auth.ScopeAdmin}})
+
+	if _, err := svc.GetTask(alice, 2); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetTask(other owner) err=%v, want %v", err, ErrNotFound)
+	}
+	if _, err := svc.GetTask(admin, 2); err != nil {
+		t.Fatalf("GetTask(admin) err=%v, want nil", err)
+	}
+
+	list, _ := svc.ListTasks(alice, TaskFilter{})
+	if len(list) != 1 || list[0].ID != 1 {
+		t.Fatalf("ListTasks(alice)=%v, want only task 1", list)
+	}
+	list, _ = svc.ListTasks(admin, TaskFilter{Owner: "bob"})
+	if len(list) != 1 || list[0].ID != 2 {
+		t.Fatalf("ListTasks(admin, owner=bob)=%v, want only task 2", list)
+	}
+
+	res, err := svc.CreateTask(alice, CreateTaskInput{Title: "c", IdempotencyKey: "k"})
+	if err != nil || res.Task.Owner != "alice" {
+		t.Fatalf("CreateTask() task=%+v err=%v, want owner alice", res.Task, err)
+	}
+	if _, err := svc.CreateTask(bob, CreateTaskInput{Title: "c", IdempotencyKey: "k"}); err != nil {
+		t.Fatalf("CreateTask() err=%v, want nil", err)
+	}
+	if len(keys) != 2 || keys[0] == keys[1] {
+		t.Fatalf("idempotency keys=%q, want one per owner", keys)
+	}
+}
+
+func TestCreateTask_Quota_PassedForOwner(t *testing.T) {
+	var maxActive []int
+	store := &fakeStore{
+		createWithFn: func(task domain.Task, opts store.CreateOptions) (store.CreateResult, error) {
+			maxActive = append(maxActive, opts.MaxActive)
+			return store.CreateResult{}, store.ErrQuotaExceeded
+		},
+	}
+	svc, err := New(store, &fakePool{enqueueFn: func(int64) error { return nil }},
+		WithQuota(func(owner string) int { return len(owner) }))
+	if err != nil {
+		t.Fatalf("New() err=%v, want nil", err)
+	}
+
+	alice := auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: "alice"})
+	if _, err := svc.CreateTask(alice, CreateTaskInput{Title: "a"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("CreateTask() err=%v, want %v", err, ErrQuotaExceeded)
+	}
+	_, _ = svc.CreateTask(context.Background(), CreateTaskInput{Title: "a"})
+
+	if len(maxActive) != 2 || maxActive[0] != 5 || maxActive[1] != 0 {
+		t.Fatalf("MaxActive=%v, want [5 0] (anonymous callers have no quota)", maxActive)
+	}
+}
+
+func TestCancelTask(t *testing.T) {
+	tasks := map[int64]domain.Task{
+		1: {ID: 1, Status: domain.StatusRunning, Owner: "alice"},
+		2: {ID: 2, Status: domain.StatusDone, Owner: "alice"},
+		3: {ID: 3, Status: domain.StatusPending, Owner: "bob"},
+	}
+	pool := &fakePool{enqueueFn: func(int64) error { return nil }}
+	svc, _ := New(&fakeStore{
+		getFn: func(id int64) (domain.Task, bool) {
+			task, ok := tasks[id]
+			return task, ok
+		},
+		cancelFn: func(id int64, reason string) (domain.Task, error) {
+			if tasks[id].Status.Terminal() {
+				return domain.Task{}, store.ErrInvalidTransition
+			}
+			return domain.Task{ID: id, Status: domain.StatusCanceled, Error: reason}, nil
+		},
+	}, pool)
+
+	alice := auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: "alice"})
+
+	task, err := svc.CancelTask(alice, 1)
+	if err != nil || task.Status != domain.StatusCanceled {
+		t.Fatalf("CancelTask(1)=%+v err=%v, want canceled", task, err)
+	}
+	if len(pool.aborted) != 1 || pool.aborted[0] != 1 {
+		t.Fatalf("aborted=%v, want [1]", pool.aborted)
+	}
+	if _, err := svc.CancelTask(alice, 2); !errors.Is(err, ErrTaskFinished) {
+		t.Fatalf("CancelTask(done) err=%v, want %v", err, ErrTaskFinished)
+	}
+	if _, err := svc.CancelTask(alice, 3); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("CancelTask(other owner) err=%v, want %v", err, ErrNotFound)
+	}
+	if _, err := svc.CancelTask(alice, 0); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("CancelTask(0) err=%v, want %v", err, ErrInvalidID)
+	}
+}
+
+func TestUpdateTask(t *testing.T) {
+	var patched store.TaskPatch
+	svc, _ := New(&fakeStore{
+		getFn: func(id int64) (domain.Task, bool) {
+			return domain.Task{ID: id, Status: domain.StatusPending, Owner: "alice"}, id == 1
+		},
+		updateFn: func(id int64, version int64, patch store.TaskPatch) (domain.Task, error) {
+			patched = patch
+			if version == 3 {
+				return domain.Task{}, store.ErrVersionConflict
+			}
+			return patch.Apply(domain.Task{ID: id, Version: 2}), nil
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }})
+
+	alice := auth.ContextWithPrincipal(context.Background(), auth.Principal{ID: "alice"})
+	title := "  renamed "
+	task, err := svc.UpdateTask(alice, 1, UpdateTaskInput{Title: &title})
+	if err != nil || task.Title != "renamed" || patched.Description != nil {
+		t.Fatalf("UpdateTask()=%+v err=%v, want the trimmed title and nothing else", task, err)
+	}
+
+	empty := " "
+	if _, err := svc.UpdateTask(alice, 1, UpdateTaskInput{Title: &empty}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("UpdateTask(empty title) err=%v, want %v", err, ErrInvalidInput)
+	}
+	if _, err := svc.UpdateTask(alice, 1, UpdateTaskInput{Metadata: map[string]string{"": "x"}}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("UpdateTask(empty metadata key) err=%v, want %v", err, ErrInvalidInput)
+	}
+	if _, err := svc.UpdateTask(alice, 1, UpdateTaskInput{Title: &title, Version: 3}); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("UpdateTask(stale version) err=%v, want %v", err, ErrVersionConflict)
+	}
+	if _, err := svc.UpdateTask(alice, 2, UpdateTaskInput{Title: &title}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("UpdateTask(missing) err=%v, want %v", err, ErrNotFound)
+	}
+}
+
+type fakeLogs struct{ deleted []int64 }
+
+func (l *fakeLogs) Delete(id int64) { l.deleted = append(l.deleted, id) }
+
+func TestDeleteTask_CancelsUnfinishedFirst(t *testing.T) {
+	tasks := map[int64]domain.Task{
+		1: {ID: 1, Status: domain.StatusDone},
+		2: {ID: 2, Status: domain.StatusPending},
+	}
+	var canceled []int64
+	logs := &fakeLogs{}
+	svc, _ := New(&fakeStore{
+		getFn: func(id int64) (domain.Task, bool) {
+			task, ok := tasks[id]
+			return task, ok
+		},
+		cancelFn: func(id int64, reason string) (domain.Task, error) {
+			canceled = append(canceled, id)
+			task := tasks[id]
+			task.Status = domain.StatusCanceled
+			tasks[id] = task
+			return task, nil
+		},
+		deleteFn: func(id, _ int64) (domain.Task, error) {
+			if !tasks[id].Status.Terminal() {
+				return domain.Task{}, store.ErrNotFinished
+			}
+			task := tasks[id]
+			delete(tasks, id)
+			return task, nil
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }}, WithTaskLogs(logs))
+
+	for _, id := range []int64{1, 2} {
+		if err := svc.DeleteTask(context.Background(), id, 0); err != nil {
+			t.Fatalf("DeleteTask(%d) err=%v, want nil", id, err)
+		}
+	}
+	if len(canceled) != 1 || canceled[0] != 2 {
+		t.Fatalf("canceled=%v, want only the pending task", canceled)
+	}
+	if len(logs.deleted) != 2 || len(tasks) != 0 {
+		t.Fatalf("deleted logs=%v tasks left=%v, want both gone", logs.deleted, tasks)
+	}
+	if err := svc.DeleteTask(context.Background(), 1, 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("DeleteTask(deleted) err=%v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestDeleteTask_FinishedWhileCanceling_ChecksVersion(t *testing.T) {
+	// the task was pending when read, done by the time it's canceled
+	pending := domain.Task{ID: 1, Status: domain.StatusPending, Version: 1}
+	done := domain.Task{ID: 1, Status: domain.StatusDone, Version: 2}
+	svc, _ := New(&fakeStore{
+		getFn: func(int64) (domain.Task, bool) { return pending, true },
+		cancelFn: func(int64, string) (domain.Task, error) {
+			return domain.Task{}, store.ErrInvalidTransition
+		},
+		deleteFn: func(_ int64, version int64) (domain.Task, error) {
+			if version != 0 && version != done.Version {
+				return domain.Task{}, store.ErrVersionConflict
+			}
+			return done, nil
+		},
+	}, &fakePool{enqueueFn: func(int64) error { return nil }})
+
+	if err := svc.DeleteTask(context.Background(), 1, pending.Version); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("DeleteTask(stale version) err=%v, want %v", err, ErrVersionConflict)
+	}
+	if err := svc.DeleteTask(context.Background(), 1, 0); err != nil {
+		t.Fatalf("DeleteTask(no version) err=%v, want nil", err)
+	}
+}
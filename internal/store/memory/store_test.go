@@ -1,174 +1,44 @@
 package memory
 
 import (
-	"errors"
 	"interview-task-worker-pool/internal/domain"
-	"sync"
+	"interview-task-worker-pool/internal/store"
+	"interview-task-worker-pool/internal/store/storetest"
+	"slices"
 	"testing"
 )
 
-func TestTaskStore_CreateAndGet(t *testing.T) {
-	ts := New()
-
-	in := domain.Task{
-		Title:       "t1",
-		Description: "d1",
-		Status:      domain.StatusDone,
-	}
-
-	created, err := ts.Create(in)
-	if err != nil {
-		t.Fatalf("Create() err = %v, want nil", err)
-	}
-	if created.ID <= 0 {
-		t.Fatalf("Create() err = %d, want > 0", created.ID)
-	}
-	if created.Status != domain.StatusPending {
-		t.Fatalf("Create() err = %s, want %s", created.Status, domain.StatusPending)
-	}
-
-	got, ok := ts.Get(created.ID)
-	if !ok {
-		t.Fatal("Get() ok = false, want ok = true")
-	}
-	if got.ID != created.ID || got.Title != in.Title || got.Description != in.Description {
-		t.Fatalf("Get() returned unxepected task: %+v", got)
-	}
-	if got.Status != domain.StatusPending {
-		t.Fatalf("Get() status = %s, want %s", got.Status, domain.StatusPending)
-	}
-}
-
-func TestTaskStore_Get_NotFound(t *testing.T) {
-	ts := New()
-
-	_, ok := ts.Get(9999)
-	if ok {
-		t.Fatal("Get() ok = true, want ok = false")
-	}
+func TestTaskStore_Conformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Backend { return New() })
 }
 
-func TestTaskStore_List(t *testing.T) {
+func TestTaskStore_IDsFollowStoredTasks(t *testing.T) {
 	ts := New()
 
-	t1, _ := ts.Create(domain.Task{Title: "t1"})
-	t2, _ := ts.Create(domain.Task{Title: "t2"})
-
-	list, err := ts.List()
-	if err != nil {
-		t.Fatalf("List() err = %v, want nil", err)
-	}
-	if len(list) != 2 {
-		t.Fatalf("List() len = %d, want 2", len(list))
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		created, _ := ts.Create(domain.Task{Title: "t"})
+		_, _ = ts.UpdateStatus(created.ID, domain.StatusDone)
+		ids = append(ids, created.ID)
 	}
 
-	// not sorted because of map
-	if !containsID(list, t1.ID) || !containsID(list, t2.ID) {
-		t.Fatalf("List() does not contain created tasks in a list with length of %v", len(list))
+	_, _ = ts.Delete(ids[1], 0)
+	if !slices.Equal(ts.ids, []int64{ids[0], ids[2]}) {
+		t.Fatalf("ids = %v after deleting %d, want %v", ts.ids, ids[1], []int64{ids[0], ids[2]})
 	}
-}
 
-func TestTaskStore_Fail(t *testing.T) {
-	ts := New()
-
-	created, _ := ts.Create(domain.Task{Title: "t"})
-	reason := "task pool is full"
-
-	failed, err := ts.Fail(created.ID, reason)
-	if err != nil {
-		t.Fatalf("Fail() err = %v, want nil", err)
-	}
-	if failed.Status != domain.StatusFailed {
-		t.Fatalf("Fail() status = %s, want %s", failed.Status, domain.StatusFailed)
+	// a task put back lands in order, a far id doesn't make listings walk the gap
+	_, _ = ts.Put(domain.Task{ID: ids[1], Title: "t", Status: domain.StatusDone}, false)
+	_, _ = ts.Put(domain.Task{ID: 1 << 62, Title: "t", Status: domain.StatusDone}, false)
+	if !slices.Equal(ts.ids, append(slices.Clone(ids), 1<<62)) {
+		t.Fatalf("ids = %v after Put, want %v and %d", ts.ids, ids, int64(1<<62))
 	}
-	if failed.Error != reason {
-		t.Fatalf("Fail() error = %s, want %s", failed.Error, reason)
+	page, _ := ts.ListFinished(ids[2], 10)
+	if len(page) != 1 || page[0].ID != 1<<62 {
+		t.Fatalf("ListFinished(after %d) = %v, want only the far task", ids[2], page)
 	}
-
-	got, ok := ts.Get(created.ID)
-	if !ok {
-		t.Fatalf("Get() ok = false, want ok = true")
-	}
-	if got.Status != domain.StatusFailed || got.Error != reason {
-		t.Fatalf("Get() after Fail got= %+v, want status=failed error=%q", got, reason)
-	}
-}
-
-func TestTaskStore_Fail_NotFound(t *testing.T) {
-	ts := New()
-
-	_, err := ts.Fail(123, "x")
-	if err == nil {
-		t.Fatalf("Fail() err = nil, want non-nil")
-	}
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("Fail() err = %v, want %v", err, ErrNotFound)
-	}
-}
-
-func TestTaskStore_UpdateStatus(t *testing.T) {
-	ts := New()
-
-	created, _ := ts.Create(domain.Task{Title: "t"})
-	updated, err := ts.UpdateStatus(created.ID, domain.StatusRunning)
-	if err != nil {
-		t.Fatalf("UpdateStatus() err = %v, want nil", err)
-	}
-	if updated.Status != domain.StatusRunning {
-		t.Fatalf("UpdateStatus() Status = %s, want %s", updated.Status, domain.StatusRunning)
-	}
-
-	got, ok := ts.Get(created.ID)
-	if !ok {
-		t.Fatalf("Get() ok = false, want true")
-	}
-	if got.Status != domain.StatusRunning {
-		t.Fatalf("Get() Status = %s, want %s", got.Status, domain.StatusRunning)
-	}
-}
-
-func TestTaskStore_UpdateStatus_NotFound(t *testing.T) {
-	ts := New()
-
-	_, err := ts.UpdateStatus(321, domain.StatusDone)
-	if err == nil {
-		t.Fatalf("UpdateStatus() err = nil, want non-nil")
-	}
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("UpdateStatus() err = %v, want %v", err, ErrNotFound)
-	}
-}
-
-func TestTaskStore_ConcurrentCreate(t *testing.T) {
-	ts := New()
-
-	const n = 200
-	var wg sync.WaitGroup
-	wg.Add(n)
-
-	for i := 0; i < n; i++ {
-		go func() {
-			defer wg.Done()
-			_, _ = ts.Create(domain.Task{Title: "x"})
-		}()
-	}
-
-	wg.Wait()
-
-	list, err := ts.List()
-	if err != nil {
-		t.Fatalf("List() err = %v, want nil", err)
-	}
-	if len(list) != n {
-		t.Fatalf("List() len = %d, want %d", len(list), n)
-	}
-}
-
-func containsID(tasks []domain.Task, id int64) bool {
-	for _, t := range tasks {
-		if t.ID == id {
-			return true
-		}
+	found, _ := ts.Find(store.Filter{After: ids[0]})
+	if len(found) != 3 {
+		t.Fatalf("Find(after %d) returned %d tasks, want 3", ids[0], len(found))
 	}
-	return false
 }
@@ -1,42 +1,318 @@
 package memory
 
 import (
+	"context"
 	"errors"
 	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/store"
+	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 var (
-	ErrNotFound      = errors.New("task not found")
+	ErrNotFound      = store.ErrNotFound
 	ErrInvalidTaskID = errors.New("invalid task id")
 )
 
+type idempotencyKey struct {
+	taskID      int64
+	fingerprint string
+	attached    bool // the original call attached to an in-flight task
+	expiresAt   time.Time
+}
+
 type TaskStore struct {
 	mu     sync.RWMutex
 	nextID int64
 	tasks  map[int64]domain.Task
+	ids    []int64 // ids of the stored tasks, ascending, listings page over them
+	keys   map[string]idempotencyKey
+	dedupe map[string]int64 // owner + dedupe key -> id of the pending/running task
+	active map[string]int   // owner -> unfinished tasks
+
+	nextBatchID int64
+	batches     map[int64]store.Batch
 }
 
 func New() *TaskStore {
 	return &TaskStore{
-		tasks: make(map[int64]domain.Task),
+		tasks:  make(map[int64]domain.Task),
+		keys:   make(map[string]idempotencyKey),
+		dedupe: make(map[string]int64),
+		active: make(map[string]int),
+
+		batches: make(map[int64]store.Batch),
 	}
 }
 
 func (ts *TaskStore) Create(task domain.Task) (domain.Task, error) {
-	id := atomic.AddInt64(&ts.nextID, 1)
+	res, err := ts.CreateWith(task, store.CreateOptions{})
+	return res.Task, err
+}
 
-	task.ID = id
+// CreateWith creates a task. The idempotency key lookup, the dedupe index check and
+// the insert happen under the same lock, so concurrent retries or producers can't
+// both create.
+func (ts *TaskStore) CreateWith(task domain.Task, opts store.CreateOptions) (store.CreateResult, error) {
+	now := time.Now()
 
-	// status is not definable by user, so here we set its init value
-	task.Status = domain.StatusPending
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if opts.IdempotencyKey != "" {
+		if key, ok := ts.keys[opts.IdempotencyKey]; ok && now.Before(key.expiresAt) {
+			if key.fingerprint != opts.Fingerprint {
+				return store.CreateResult{}, store.ErrIdempotencyConflict
+			}
+			if existing, ok := ts.tasks[key.taskID]; ok {
+				return store.CreateResult{Task: existing, Replayed: true, Attached: key.attached}, nil
+			}
+		}
+	}
+
+	res, err := ts.create(task, opts.DedupePolicy, opts.MaxActive)
+	if err != nil {
+		return store.CreateResult{}, err
+	}
+	ts.rememberKey(opts, res, now)
+
+	return res, nil
+}
+
+// create applies the dedupe policy and the owner's quota, then inserts the task.
+// caller must hold ts.mu
+func (ts *TaskStore) create(task domain.Task, policy store.DedupePolicy, maxActive int) (store.CreateResult, error) {
+	var res store.CreateResult
+
+	if task.DedupeKey != "" {
+		if existingID, ok := ts.dedupe[dedupeKey(task)]; ok {
+			existing := ts.tasks[existingID]
+
+			switch policy {
+			case store.DedupeReturnExisting:
+				return store.CreateResult{Task: existing, Attached: true}, nil
+			case store.DedupeReplace:
+				// only work that hasn't started can be replaced
+				if existing.Status != domain.StatusPending {
+					return store.CreateResult{}, store.ErrDuplicate
+				}
+				existing.Status = domain.StatusCanceled
+				existing.Error = "replaced by a newer task"
+				existing.FinishedAt = time.Now()
+				existing.Version++
+				ts.tasks[existing.ID] = existing
+				ts.countActive(existing.Owner, -1)
+				res.ReplacedID = existing.ID
+			default:
+				return store.CreateResult{}, store.ErrDuplicate
+			}
+		}
+	}
+
+	if maxActive > 0 && ts.active[task.Owner] >= maxActive {
+		if res.ReplacedID != 0 {
+			ts.undoReplace(res.ReplacedID)
+		}
+		return store.CreateResult{}, store.ErrQuotaExceeded
+	}
+
+	task.ID = atomic.AddInt64(&ts.nextID, 1)
+	task.Version = 1
 
+	// status is not definable by user, so here we set its init value,
+	// only a task waiting on dependencies starts blocked
+	if task.Status != domain.StatusBlocked {
+		task.Status = domain.StatusPending
+	}
+
+	ts.tasks[task.ID] = task
+	ts.ids = append(ts.ids, task.ID) // above every id handed out so far
+	ts.countActive(task.Owner, 1)
+	if task.DedupeKey != "" {
+		ts.dedupe[dedupeKey(task)] = task.ID
+	}
+
+	res.Task = task
+	return res, nil
+}
+
+// CreateBatch inserts all tasks under a single lock and groups them in a new batch.
+// With atomic set, nothing is inserted unless every task can be: the dedupe index and
+// the quota are checked for the whole batch first and the per-item errors come back
+// with store.ErrBatchRejected.
+func (ts *TaskStore) CreateBatch(owner string, tasks []domain.Task, policy store.DedupePolicy, atomic bool, maxActive int) (store.Batch, []store.BatchItem, error) {
 	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	items := make([]store.BatchItem, len(tasks))
+
+	if atomic {
+		rejected := false
+		for i, err := range ts.checkBatch(tasks, policy, maxActive) {
+			items[i].Err = err
+			rejected = rejected || err != nil
+		}
+		if rejected {
+			return store.Batch{}, items, store.ErrBatchRejected
+		}
+	}
+
+	ts.nextBatchID++
+	batch := store.Batch{ID: ts.nextBatchID, Owner: owner, CreatedAt: time.Now()}
+
+	for i, task := range tasks {
+		task.BatchID = batch.ID
+
+		res, err := ts.create(task, policy, maxActive)
+		items[i] = store.BatchItem{Result: res, Err: err}
+		if err == nil && !res.Attached {
+			batch.TaskIDs = append(batch.TaskIDs, res.Task.ID)
+		}
+	}
+
+	ts.batches[batch.ID] = batch
+	return batch, items, nil
+}
+
+// checkBatch dry-runs the dedupe policy and the quota over tasks in order,
+// including the keys taken and the tasks added by earlier tasks of the batch.
+// caller must hold ts.mu
+func (ts *TaskStore) checkBatch(tasks []domain.Task, policy store.DedupePolicy, maxActive int) []error {
+	errs := make([]error, len(tasks))
+	taken := make(map[string]bool) // keys claimed by this batch, always pending
+	added := make(map[string]int)  // owner -> tasks this batch adds
+
+	for i, task := range tasks {
+		if task.DedupeKey == "" {
+			errs[i] = ts.checkQuota(task, maxActive, added)
+			continue
+		}
+
+		inFlight := taken[dedupeKey(task)]
+		startedElsewhere := false
+		if id, ok := ts.dedupe[dedupeKey(task)]; ok {
+			inFlight = true
+			startedElsewhere = !taken[dedupeKey(task)] && ts.tasks[id].Status != domain.StatusPending
+		}
+
+		switch {
+		case !inFlight, policy == store.DedupeReturnExisting:
+		case policy == store.DedupeReplace && !startedElsewhere:
+		default:
+			errs[i] = store.ErrDuplicate
+			continue
+		}
+		// attaching adds nothing, a replace swaps one unfinished task for another
+		if !inFlight {
+			if errs[i] = ts.checkQuota(task, maxActive, added); errs[i] != nil {
+				continue
+			}
+		}
+		if policy != store.DedupeReturnExisting || !inFlight {
+			taken[dedupeKey(task)] = true
+		}
+	}
+	return errs
+}
+
+// checkQuota counts task into added unless its owner would go over maxActive.
+// caller must hold ts.mu
+func (ts *TaskStore) checkQuota(task domain.Task, maxActive int, added map[string]int) error {
+	if maxActive > 0 && ts.active[task.Owner]+added[task.Owner] >= maxActive {
+		return store.ErrQuotaExceeded
+	}
+	added[task.Owner]++
+	return nil
+}
+
+func (ts *TaskStore) GetBatch(id int64) (store.Batch, bool) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	batch, ok := ts.batches[id]
+	return batch, ok
+}
+
+func (ts *TaskStore) rememberKey(opts store.CreateOptions, res store.CreateResult, now time.Time) {
+	if opts.IdempotencyKey == "" {
+		return
+	}
+	ts.keys[opts.IdempotencyKey] = idempotencyKey{
+		taskID:      res.Task.ID,
+		fingerprint: opts.Fingerprint,
+		attached:    res.Attached,
+		expiresAt:   now.Add(opts.KeyTTL),
+	}
+}
+
+// dedupeKey scopes a dedupe key to the task owner, clients never attach to
+// each other's tasks.
+func dedupeKey(task domain.Task) string {
+	return task.Owner + "\x00" + task.DedupeKey
+}
+
+// undoReplace puts back the pending task a create replaced before failing.
+// caller must hold ts.mu
+func (ts *TaskStore) undoReplace(id int64) {
+	task := ts.tasks[id]
+	task.Status = domain.StatusPending
+	task.Error = ""
+	task.FinishedAt = time.Time{}
+	task.Version--
 	ts.tasks[id] = task
-	ts.mu.Unlock()
+	ts.countActive(task.Owner, 1)
+}
 
-	return task, nil
+// countActive adjusts the owner's number of unfinished tasks.
+// caller must hold ts.mu
+func (ts *TaskStore) countActive(owner string, delta int) {
+	ts.active[owner] += delta
+	if ts.active[owner] <= 0 {
+		delete(ts.active, owner)
+	}
+}
+
+// ActiveCount returns the owner's unfinished (blocked, pending or running) tasks.
+func (ts *TaskStore) ActiveCount(owner string) int {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.active[owner]
+}
+
+// releaseDedupe frees the dedupe key once its task can't run anymore.
+// caller must hold ts.mu
+func (ts *TaskStore) releaseDedupe(task domain.Task) {
+	if task.DedupeKey == "" || !task.Status.Terminal() {
+		return
+	}
+	if ts.dedupe[dedupeKey(task)] == task.ID {
+		delete(ts.dedupe, dedupeKey(task))
+	}
+}
+
+// ExpireIdempotencyKeys drops keys whose retention window ended before now.
+func (ts *TaskStore) ExpireIdempotencyKeys(now time.Time) int {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	expired := 0
+	for k, key := range ts.keys {
+		if !now.Before(key.expiresAt) {
+			delete(ts.keys, k)
+			expired++
+		}
+	}
+	return expired
+}
+
+// Ping reports whether the store can serve reads, for the readiness probe.
+// A wedged lock shows up as a probe timeout.
+func (ts *TaskStore) Ping(ctx context.Context) error {
+	ts.mu.RLock()
+	ts.mu.RUnlock()
+	return ctx.Err()
 }
 
 func (ts *TaskStore) Get(id int64) (domain.Task, bool) {
@@ -61,6 +337,174 @@ func (ts *TaskStore) List() ([]domain.Task, error) {
 	return tasks, nil
 }
 
+// Find walks the ids in order like ListAfter, filtering on the way.
+func (ts *TaskStore) Find(filter store.Filter) ([]domain.Task, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	tasks := make([]domain.Task, 0)
+	for _, id := range ts.idsAfter(filter.After) {
+		if filter.Limit > 0 && len(tasks) >= filter.Limit {
+			break
+		}
+		if task := ts.tasks[id]; filter.Match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// idsAfter returns the stored ids above after, ascending.
+// caller must hold ts.mu
+func (ts *TaskStore) idsAfter(after int64) []int64 {
+	i, _ := slices.BinarySearch(ts.ids, after+1)
+	return ts.ids[i:]
+}
+
+// lookup returns the task when version is zero or its current one.
+// caller must hold ts.mu
+func (ts *TaskStore) lookup(id int64, version int64) (domain.Task, error) {
+	task, ok := ts.tasks[id]
+	if !ok {
+		return domain.Task{}, ErrNotFound
+	}
+	if version != 0 && version != task.Version {
+		return domain.Task{}, store.ErrVersionConflict
+	}
+	return task, nil
+}
+
+// Update patches a task that hasn't started yet. The version compare happens
+// under the same lock as the write, so of two clients editing the same
+// version only the first one wins.
+func (ts *TaskStore) Update(id int64, version int64, patch store.TaskPatch) (domain.Task, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	task, err := ts.lookup(id, version)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if task.Status != domain.StatusBlocked && task.Status != domain.StatusPending {
+		return domain.Task{}, store.ErrNotPending
+	}
+
+	task = patch.Apply(task)
+	task.Version++
+	ts.tasks[id] = task
+	return task, nil
+}
+
+// Delete removes a finished task. Batches keep listing its id, their progress
+// just doesn't count it anymore.
+func (ts *TaskStore) Delete(id int64, version int64) (domain.Task, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	task, err := ts.lookup(id, version)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if !task.Status.Terminal() {
+		return domain.Task{}, store.ErrNotFinished
+	}
+
+	delete(ts.tasks, id)
+	if i, ok := slices.BinarySearch(ts.ids, id); ok {
+		ts.ids = slices.Delete(ts.ids, i, i+1)
+	}
+	return task, nil
+}
+
+// Put stores a finished task under its own id, or a new one when the id is 0,
+// e.g. from an archive or an import. It keeps its version, so ETags handed
+// out before still match. An existing task is ErrExists unless replace is set,
+// only finished tasks can be replaced.
+func (ts *TaskStore) Put(task domain.Task, replace bool) (domain.Task, error) {
+	if task.ID < 0 {
+		return domain.Task{}, ErrInvalidTaskID
+	}
+	if !task.Status.Terminal() {
+		return domain.Task{}, store.ErrNotFinished
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if task.ID == 0 {
+		task.ID = atomic.AddInt64(&ts.nextID, 1)
+	}
+	if existing, ok := ts.tasks[task.ID]; ok {
+		if !replace {
+			return domain.Task{}, store.ErrExists
+		}
+		if !existing.Status.Terminal() {
+			return domain.Task{}, store.ErrNotFinished
+		}
+	}
+
+	ts.tasks[task.ID] = task
+	if i, ok := slices.BinarySearch(ts.ids, task.ID); !ok {
+		ts.ids = slices.Insert(ts.ids, i, task.ID)
+	}
+	if task.ID > ts.nextID {
+		atomic.StoreInt64(&ts.nextID, task.ID)
+	}
+	return task, nil
+}
+
+// LastID is the highest id handed out so far, deleted tasks included.
+func (ts *TaskStore) LastID() int64 {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.nextID
+}
+
+// ReserveIDs makes the store hand out ids above id only, so an import keeps
+// the ids of deleted tasks from being reused.
+func (ts *TaskStore) ReserveIDs(id int64) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if id > ts.nextID {
+		atomic.StoreInt64(&ts.nextID, id)
+	}
+}
+
+// Count returns the number of stored tasks, finished ones included.
+func (ts *TaskStore) Count() (int, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return len(ts.tasks), nil
+}
+
+// ListFinished returns up to limit finished tasks with an id above after, by
+// ascending id. The lock is held for one page only, so a caller walking every
+// finished task doesn't block writers for the whole walk.
+func (ts *TaskStore) ListFinished(after int64, limit int) ([]domain.Task, error) {
+	return ts.page(after, limit, domain.TaskStatus.Terminal), nil
+}
+
+// ListAfter is ListFinished for every task.
+func (ts *TaskStore) ListAfter(after int64, limit int) ([]domain.Task, error) {
+	return ts.page(after, limit, func(domain.TaskStatus) bool { return true }), nil
+}
+
+func (ts *TaskStore) page(after int64, limit int, keep func(domain.TaskStatus) bool) []domain.Task {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	var tasks []domain.Task
+	for _, id := range ts.idsAfter(after) {
+		if len(tasks) >= limit {
+			break
+		}
+		if task := ts.tasks[id]; keep(task.Status) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
 func (ts *TaskStore) Fail(id int64, reason string) (domain.Task, error) {
 	ts.mu.Lock()
 	defer ts.mu.Unlock()
@@ -69,10 +513,40 @@ func (ts *TaskStore) Fail(id int64, reason string) (domain.Task, error) {
 	if !ok {
 		return domain.Task{}, ErrNotFound
 	}
+	// like UpdateStatus, a finished task keeps how it finished
+	if task.Status.Terminal() {
+		return domain.Task{}, store.ErrInvalidTransition
+	}
 
+	ts.countActive(task.Owner, -1)
 	task.Status = domain.StatusFailed
 	task.Error = reason
+	task.FinishedAt = time.Now()
+	task.Version++
 	ts.tasks[id] = task
+	ts.releaseDedupe(task)
+	return task, nil
+}
+
+func (ts *TaskStore) Cancel(id int64, version int64, reason string) (domain.Task, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	task, err := ts.lookup(id, version)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if task.Status.Terminal() {
+		return domain.Task{}, store.ErrInvalidTransition
+	}
+
+	task.Status = domain.StatusCanceled
+	task.Error = reason
+	task.FinishedAt = time.Now()
+	task.Version++
+	ts.tasks[id] = task
+	ts.countActive(task.Owner, -1)
+	ts.releaseDedupe(task)
 	return task, nil
 }
 
@@ -85,8 +559,18 @@ func (ts *TaskStore) UpdateStatus(id int64, status domain.TaskStatus) (domain.Ta
 	if !ok {
 		return domain.Task{}, ErrNotFound
 	}
+	// finished tasks stay finished, e.g. a worker must not revive a canceled task
+	if task.Status.Terminal() {
+		return domain.Task{}, store.ErrInvalidTransition
+	}
 	task.Status = status
+	task.Version++
+	if status.Terminal() {
+		task.FinishedAt = time.Now()
+		ts.countActive(task.Owner, -1)
+	}
 	ts.tasks[id] = task
+	ts.releaseDedupe(task)
 
 	return task, nil
 }
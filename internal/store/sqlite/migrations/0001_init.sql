@@ -0,0 +1,51 @@
+-- times are unix nanoseconds, 0 when unset
+CREATE TABLE tasks (
+	id                INTEGER PRIMARY KEY,
+	title             TEXT    NOT NULL,
+	description       TEXT    NOT NULL DEFAULT '',
+	error             TEXT    NOT NULL DEFAULT '',
+	status            TEXT    NOT NULL,
+	priority          INTEGER NOT NULL DEFAULT 0,
+	metadata          TEXT,             -- JSON object
+	version           INTEGER NOT NULL,
+	owner             TEXT    NOT NULL DEFAULT '',
+	dedupe_key        TEXT    NOT NULL DEFAULT '',
+	concurrency_key   TEXT    NOT NULL DEFAULT '',
+	concurrency_limit INTEGER NOT NULL DEFAULT 0,
+	depends_on        TEXT,             -- JSON array of ids
+	batch_id          INTEGER NOT NULL DEFAULT 0,
+	trace_parent      TEXT    NOT NULL DEFAULT '',
+	request_id        TEXT    NOT NULL DEFAULT '',
+	created_at        INTEGER NOT NULL DEFAULT 0,
+	finished_at       INTEGER NOT NULL DEFAULT 0,
+	retain_until      INTEGER NOT NULL DEFAULT 0,
+	work_duration     INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX tasks_status ON tasks (status, id);
+CREATE INDEX tasks_created_at ON tasks (created_at);
+CREATE INDEX tasks_owner ON tasks (owner, status);
+
+-- a dedupe key belongs to one unfinished task of its owner
+CREATE UNIQUE INDEX tasks_dedupe ON tasks (owner, dedupe_key)
+	WHERE dedupe_key != '' AND status IN ('blocked', 'pending', 'running');
+
+CREATE TABLE idempotency_keys (
+	key         TEXT    PRIMARY KEY,
+	task_id     INTEGER NOT NULL,
+	fingerprint TEXT    NOT NULL,
+	expires_at  INTEGER NOT NULL
+);
+
+CREATE TABLE batches (
+	id         INTEGER PRIMARY KEY,
+	task_ids   TEXT    NOT NULL, -- JSON array of ids
+	created_at INTEGER NOT NULL
+);
+
+-- last id handed out, deleted rows included, so ids are never reused
+CREATE TABLE sequences (
+	name  TEXT    PRIMARY KEY,
+	value INTEGER NOT NULL
+);
+INSERT INTO sequences (name, value) VALUES ('tasks', 0), ('batches', 0);
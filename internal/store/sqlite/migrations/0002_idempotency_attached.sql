@@ -0,0 +1,2 @@
+-- a replayed key reports whether the original call attached to an in-flight task
+ALTER TABLE idempotency_keys ADD COLUMN attached INTEGER NOT NULL DEFAULT 0;
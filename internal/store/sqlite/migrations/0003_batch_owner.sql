@@ -0,0 +1,4 @@
+-- a batch keeps its owner, GetBatch checks it even once its tasks are gone;
+-- existing batches take the owner of their tasks
+ALTER TABLE batches ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+UPDATE batches SET owner = COALESCE((SELECT owner FROM tasks WHERE tasks.batch_id = batches.id LIMIT 1), '');
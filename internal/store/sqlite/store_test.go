@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/store"
+	"interview-task-worker-pool/internal/store/storetest"
+	"path/filepath"
+	"testing"
+)
+
+func open(t *testing.T, path string) *TaskStore {
+	t.Helper()
+	ts, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() err = %v, want nil", err)
+	}
+	t.Cleanup(func() { _ = ts.Close() })
+	return ts
+}
+
+func TestOpen_MigratesAndPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	ts := open(t, path)
+	created, _ := ts.Create(domain.Task{Title: "t"})
+	done, _ := ts.UpdateStatus(created.ID, domain.StatusDone)
+	_, _ = ts.Create(domain.Task{Title: "deleted"})
+	_, _ = ts.Fail(2, "x")
+	_, _ = ts.Delete(2, 0)
+	_ = ts.Close()
+
+	// the second open finds the schema migrated and the tasks kept
+	reopened := open(t, path)
+	list, _ := loadMigrations()
+	var migrations int
+	if err := reopened.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&migrations); err != nil || migrations != len(list) {
+		t.Fatalf("schema_migrations rows = %d err = %v, want %d", migrations, err, len(list))
+	}
+	if got, ok := reopened.Get(done.ID); !ok || got.Status != domain.StatusDone || got.Version != done.Version {
+		t.Fatalf("Get() after reopen = %+v ok = %v, want the done task", got, ok)
+	}
+	if next, _ := reopened.Create(domain.Task{Title: "new"}); next.ID != 3 {
+		t.Fatalf("Create() id = %d after reopen, want 3", next.ID)
+	}
+}
+
+func TestOpen_RefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	ts := open(t, path)
+	if _, err := ts.db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (999, 'future.sql', 0)`); err != nil {
+		t.Fatalf("insert migration err = %v", err)
+	}
+	_ = ts.Close()
+
+	if _, err := Open(path); err == nil {
+		t.Fatal("Open() err = nil for a schema of a newer binary, want an error")
+	}
+}
+
+func TestTaskStore_Conformance(t *testing.T) {
+	// a file runs in WAL mode with a connection pool, :memory: on one connection
+	t.Run("file", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Backend {
+			return open(t, filepath.Join(t.TempDir(), "tasks.db"))
+		})
+	})
+	t.Run("memory", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) store.Backend { return open(t, ":memory:") })
+	})
+}
+
+func TestTaskStore_CancelUnfinished(t *testing.T) {
+	ts := open(t, ":memory:")
+
+	pending, _ := ts.Create(domain.Task{Title: "p", DedupeKey: "job"})
+	running, _ := ts.Create(domain.Task{Title: "r"})
+	_, _ = ts.UpdateStatus(running.ID, domain.StatusRunning)
+	done, _ := ts.Create(domain.Task{Title: "d"})
+	done, _ = ts.UpdateStatus(done.ID, domain.StatusDone)
+
+	if n, err := ts.CancelUnfinished("restarted"); err != nil || n != 2 {
+		t.Fatalf("CancelUnfinished() = %d err = %v, want 2", n, err)
+	}
+	if got, _ := ts.Get(pending.ID); got.Status != domain.StatusCanceled || got.Error != "restarted" || got.Version != 2 {
+		t.Fatalf("Get(pending) = %+v, want canceled", got)
+	}
+	if got, _ := ts.Get(done.ID); got.Version != done.Version {
+		t.Fatalf("Get(done) = %+v, want it untouched", got)
+	}
+	// its dedupe key is free again
+	if _, err := ts.Create(domain.Task{Title: "p", DedupeKey: "job"}); err != nil {
+		t.Fatalf("Create(same key) err = %v, want nil", err)
+	}
+}
@@ -0,0 +1,839 @@
+// Package sqlite keeps tasks in a SQLite database file, a persistent store
+// that still runs inside the single binary (pure Go driver, no cgo).
+//
+// It behaves like memory.TaskStore. Every mutation runs in a transaction,
+// writes are serialized in the process as SQLite allows one writer anyway.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/store"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var (
+	ErrNotFound      = store.ErrNotFound
+	ErrInvalidTaskID = errors.New("invalid task id")
+)
+
+var columns = []string{
+	"id", "title", "description", "error", "status", "priority", "metadata", "version",
+	"owner", "dedupe_key", "concurrency_key", "concurrency_limit", "depends_on", "batch_id",
+	"trace_parent", "request_id", "created_at", "finished_at", "retain_until", "work_duration",
+}
+
+var (
+	selectTasks = "SELECT " + strings.Join(columns, ", ") + " FROM tasks"
+
+	// saveTask inserts a task or overwrites every column of the stored one
+	saveTask = func() string {
+		updates := make([]string, 0, len(columns)-1)
+		for _, c := range columns[1:] {
+			updates = append(updates, c+" = excluded."+c)
+		}
+		return "INSERT INTO tasks (" + strings.Join(columns, ", ") + ") VALUES (?" +
+			strings.Repeat(", ?", len(columns)-1) + ") ON CONFLICT (id) DO UPDATE SET " + strings.Join(updates, ", ")
+	}()
+)
+
+// unfinished is the IN list of the statuses a task can still leave.
+const unfinished = "('blocked', 'pending', 'running')"
+
+type TaskStore struct {
+	db     *sql.DB
+	mu     sync.Mutex // serializes write transactions
+	logger *slog.Logger
+}
+
+type Option func(*TaskStore)
+
+// WithLogger sets where errors of methods that can't return one are logged
+// (Get, LastID, ...), slog.Default() by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(ts *TaskStore) {
+		if logger != nil {
+			ts.logger = logger
+		}
+	}
+}
+
+// Open opens or creates the database at path and migrates it to the latest
+// schema. ":memory:" is a private in-memory database.
+func Open(path string, opts ...Option) (*TaskStore, error) {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)") // other processes holding the file
+	params.Add("_pragma", "journal_mode(WAL)")  // readers don't wait for the writer
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if path == ":memory:" {
+		// every connection would get a database of its own
+		db.SetMaxOpenConns(1)
+	}
+
+	if _, err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("migrating %s: %w", path, err)
+	}
+
+	ts := &TaskStore{db: db, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(ts)
+	}
+	return ts, nil
+}
+
+func (ts *TaskStore) Close() error {
+	return ts.db.Close()
+}
+
+// queryer is a *sql.DB or a *sql.Tx.
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// write runs fn in a transaction, committed when fn returns nil.
+func (ts *TaskStore) write(fn func(tx *sql.Tx) error) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tx, err := ts.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (ts *TaskStore) Create(task domain.Task) (domain.Task, error) {
+	res, err := ts.CreateWith(task, store.CreateOptions{})
+	return res.Task, err
+}
+
+// CreateWith creates a task. The idempotency key lookup, the dedupe check and
+// the insert share a transaction, so concurrent retries or producers can't
+// both create.
+func (ts *TaskStore) CreateWith(task domain.Task, opts store.CreateOptions) (store.CreateResult, error) {
+	now := time.Now()
+
+	var res store.CreateResult
+	err := ts.write(func(tx *sql.Tx) error {
+		if opts.IdempotencyKey != "" {
+			var taskID, expiresAt int64
+			var fingerprint string
+			var attached bool
+			err := tx.QueryRow(`SELECT task_id, fingerprint, attached, expires_at FROM idempotency_keys WHERE key = ?`,
+				opts.IdempotencyKey).Scan(&taskID, &fingerprint, &attached, &expiresAt)
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+			case err != nil:
+				return err
+			case now.UnixNano() < expiresAt:
+				if fingerprint != opts.Fingerprint {
+					return store.ErrIdempotencyConflict
+				}
+				existing, err := getTask(tx, taskID)
+				if err == nil {
+					res = store.CreateResult{Task: existing, Replayed: true, Attached: attached}
+					return nil
+				}
+				if !errors.Is(err, ErrNotFound) {
+					return err
+				}
+			}
+		}
+
+		var err error
+		if res, err = create(tx, task, opts.DedupePolicy, opts.MaxActive); err != nil {
+			return err
+		}
+		if opts.IdempotencyKey == "" {
+			return nil
+		}
+		_, err = tx.Exec(`INSERT INTO idempotency_keys (key, task_id, fingerprint, attached, expires_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (key) DO UPDATE SET task_id = excluded.task_id, fingerprint = excluded.fingerprint,
+				attached = excluded.attached, expires_at = excluded.expires_at`,
+			opts.IdempotencyKey, res.Task.ID, opts.Fingerprint, res.Attached, now.Add(opts.KeyTTL).UnixNano())
+		return err
+	})
+	if err != nil {
+		return store.CreateResult{}, err
+	}
+	return res, nil
+}
+
+// create applies the dedupe policy and the owner's quota, then inserts the task.
+func create(tx *sql.Tx, task domain.Task, policy store.DedupePolicy, maxActive int) (store.CreateResult, error) {
+	var res store.CreateResult
+
+	var replaced domain.Task
+	if task.DedupeKey != "" {
+		existing, ok, err := inFlight(tx, task)
+		if err != nil {
+			return res, err
+		}
+		if ok {
+			switch policy {
+			case store.DedupeReturnExisting:
+				return store.CreateResult{Task: existing, Attached: true}, nil
+			case store.DedupeReplace:
+				// only work that hasn't started can be replaced
+				if existing.Status != domain.StatusPending {
+					return res, store.ErrDuplicate
+				}
+				replaced = existing
+			default:
+				return res, store.ErrDuplicate
+			}
+		}
+	}
+
+	if maxActive > 0 {
+		active, err := activeCount(tx, task.Owner)
+		if err != nil {
+			return res, err
+		}
+		if replaced.ID != 0 {
+			active--
+		}
+		if active >= maxActive {
+			return res, store.ErrQuotaExceeded
+		}
+	}
+
+	if replaced.ID != 0 {
+		replaced.Status = domain.StatusCanceled
+		replaced.Error = "replaced by a newer task"
+		replaced.FinishedAt = time.Now()
+		replaced.Version++
+		if err := save(tx, replaced); err != nil {
+			return res, err
+		}
+		res.ReplacedID = replaced.ID
+	}
+
+	id, err := nextID(tx, "tasks")
+	if err != nil {
+		return res, err
+	}
+	task.ID = id
+	task.Version = 1
+
+	// status is not definable by user, only a task waiting on dependencies starts blocked
+	if task.Status != domain.StatusBlocked {
+		task.Status = domain.StatusPending
+	}
+
+	if err := save(tx, task); err != nil {
+		return res, err
+	}
+	res.Task = task
+	return res, nil
+}
+
+// CreateBatch inserts all tasks in one transaction and groups them in a new
+// batch. With atomic set, nothing is inserted unless every task can be: the
+// dedupe keys and the quota are checked for the whole batch first and the
+// per-item errors come back with store.ErrBatchRejected.
+func (ts *TaskStore) CreateBatch(owner string, tasks []domain.Task, policy store.DedupePolicy, atomic bool, maxActive int) (store.Batch, []store.BatchItem, error) {
+	items := make([]store.BatchItem, len(tasks))
+	var batch store.Batch
+
+	err := ts.write(func(tx *sql.Tx) error {
+		if atomic {
+			errs, err := checkBatch(tx, tasks, policy, maxActive)
+			if err != nil {
+				return err
+			}
+			rejected := false
+			for i, err := range errs {
+				items[i].Err = err
+				rejected = rejected || err != nil
+			}
+			if rejected {
+				return store.ErrBatchRejected
+			}
+		}
+
+		id, err := nextID(tx, "batches")
+		if err != nil {
+			return err
+		}
+		batch = store.Batch{ID: id, Owner: owner, CreatedAt: time.Now()}
+
+		for i, task := range tasks {
+			task.BatchID = batch.ID
+
+			res, err := create(tx, task, policy, maxActive)
+			if err != nil && !isItemError(err) {
+				return err
+			}
+			items[i] = store.BatchItem{Result: res, Err: err}
+			if err == nil && !res.Attached {
+				batch.TaskIDs = append(batch.TaskIDs, res.Task.ID)
+			}
+		}
+
+		taskIDs, err := json.Marshal(nonNil(batch.TaskIDs))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO batches (id, owner, task_ids, created_at) VALUES (?, ?, ?, ?)`,
+			batch.ID, batch.Owner, string(taskIDs), batch.CreatedAt.UnixNano())
+		return err
+	})
+	if errors.Is(err, store.ErrBatchRejected) {
+		return store.Batch{}, items, err
+	}
+	if err != nil {
+		return store.Batch{}, nil, err
+	}
+	return batch, items, nil
+}
+
+// isItemError tells the errors that fail a single task of a batch from the
+// ones of the database, which fail the whole batch.
+func isItemError(err error) bool {
+	return errors.Is(err, store.ErrDuplicate) || errors.Is(err, store.ErrQuotaExceeded)
+}
+
+// checkBatch dry-runs the dedupe policy and the quota over tasks in order,
+// including the keys taken and the tasks added by earlier tasks of the batch.
+func checkBatch(tx *sql.Tx, tasks []domain.Task, policy store.DedupePolicy, maxActive int) ([]error, error) {
+	errs := make([]error, len(tasks))
+	taken := make(map[string]bool) // keys claimed by this batch, always pending
+	quota := batchQuota{tx: tx, maxActive: maxActive, active: make(map[string]int)}
+
+	for i, task := range tasks {
+		if task.DedupeKey == "" {
+			ok, err := quota.take(task.Owner)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				errs[i] = store.ErrQuotaExceeded
+			}
+			continue
+		}
+		key := task.Owner + "\x00" + task.DedupeKey
+
+		existing, ok, err := inFlight(tx, task)
+		if err != nil {
+			return nil, err
+		}
+		inFlight := taken[key] || ok
+		startedElsewhere := ok && !taken[key] && existing.Status != domain.StatusPending
+
+		switch {
+		case !inFlight, policy == store.DedupeReturnExisting:
+		case policy == store.DedupeReplace && !startedElsewhere:
+		default:
+			errs[i] = store.ErrDuplicate
+			continue
+		}
+		// attaching adds nothing, a replace swaps one unfinished task for another
+		if !inFlight {
+			ok, err := quota.take(task.Owner)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				errs[i] = store.ErrQuotaExceeded
+				continue
+			}
+		}
+		if policy != store.DedupeReturnExisting || !inFlight {
+			taken[key] = true
+		}
+	}
+	return errs, nil
+}
+
+// batchQuota counts the unfinished tasks of the owners of a batch as checkBatch
+// adds to them.
+type batchQuota struct {
+	tx        *sql.Tx
+	maxActive int
+	active    map[string]int
+}
+
+// take adds a task of owner, ok is false when the owner is at the quota.
+func (q batchQuota) take(owner string) (bool, error) {
+	if q.maxActive <= 0 {
+		return true, nil
+	}
+	n, ok := q.active[owner]
+	if !ok {
+		var err error
+		if n, err = activeCount(q.tx, owner); err != nil {
+			return false, err
+		}
+	}
+	if n >= q.maxActive {
+		q.active[owner] = n
+		return false, nil
+	}
+	q.active[owner] = n + 1
+	return true, nil
+}
+
+// inFlight returns the unfinished task of the owner holding task's dedupe key.
+func inFlight(q queryer, task domain.Task) (domain.Task, bool, error) {
+	existing, err := scanTask(q.QueryRow(selectTasks+` WHERE owner = ? AND dedupe_key = ? AND status IN `+unfinished,
+		task.Owner, task.DedupeKey))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Task{}, false, nil
+	}
+	return existing, err == nil, err
+}
+
+func activeCount(q queryer, owner string) (int, error) {
+	var n int
+	err := q.QueryRow(`SELECT COUNT(*) FROM tasks WHERE owner = ? AND status IN `+unfinished, owner).Scan(&n)
+	return n, err
+}
+
+// nextID hands out the next id of the sequence.
+func nextID(tx *sql.Tx, sequence string) (int64, error) {
+	var id int64
+	err := tx.QueryRow(`UPDATE sequences SET value = value + 1 WHERE name = ? RETURNING value`, sequence).Scan(&id)
+	return id, err
+}
+
+func (ts *TaskStore) GetBatch(id int64) (store.Batch, bool) {
+	var owner, taskIDs string
+	var createdAt int64
+	err := ts.db.QueryRow(`SELECT owner, task_ids, created_at FROM batches WHERE id = ?`, id).Scan(&owner, &taskIDs, &createdAt)
+	if err != nil {
+		ts.logError("get batch", err)
+		return store.Batch{}, false
+	}
+
+	batch := store.Batch{ID: id, Owner: owner, CreatedAt: fromUnixNano(createdAt)}
+	if err := json.Unmarshal([]byte(taskIDs), &batch.TaskIDs); err != nil {
+		ts.logError("get batch", err)
+		return store.Batch{}, false
+	}
+	if len(batch.TaskIDs) == 0 {
+		batch.TaskIDs = nil
+	}
+	return batch, true
+}
+
+// ActiveCount returns the owner's unfinished (blocked, pending or running) tasks.
+func (ts *TaskStore) ActiveCount(owner string) int {
+	n, err := activeCount(ts.db, owner)
+	ts.logError("active count", err)
+	return n
+}
+
+// ExpireIdempotencyKeys drops keys whose retention window ended before now.
+func (ts *TaskStore) ExpireIdempotencyKeys(now time.Time) int {
+	var n int64
+	err := ts.write(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= ?`, now.UnixNano())
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	ts.logError("expire idempotency keys", err)
+	return int(n)
+}
+
+// Ping reports whether the database answers, for the readiness probe.
+func (ts *TaskStore) Ping(ctx context.Context) error {
+	return ts.db.PingContext(ctx)
+}
+
+func (ts *TaskStore) Get(id int64) (domain.Task, bool) {
+	task, err := getTask(ts.db, id)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			ts.logError("get task", err)
+		}
+		return domain.Task{}, false
+	}
+	return task, true
+}
+
+func (ts *TaskStore) List() ([]domain.Task, error) {
+	return queryTasks(ts.db, selectTasks+` ORDER BY id`)
+}
+
+// Find builds the query from the filter, the owner, status and created_at
+// conditions each have an index.
+func (ts *TaskStore) Find(filter store.Filter) ([]domain.Task, error) {
+	where := []string{"id > ?"}
+	args := []any{filter.After}
+	if filter.Owner != "" {
+		where = append(where, "owner = ?")
+		args = append(args, filter.Owner)
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, string(filter.Status))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.CreatedFrom.UnixNano())
+	}
+	if !filter.CreatedTo.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.CreatedTo.UnixNano())
+	}
+
+	query := selectTasks + " WHERE " + strings.Join(where, " AND ") + " ORDER BY id"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	return queryTasks(ts.db, query, args...)
+}
+
+// Update patches a task that hasn't started yet. The version compare happens
+// in the same transaction as the write, so of two clients editing the same
+// version only the first one wins.
+func (ts *TaskStore) Update(id int64, version int64, patch store.TaskPatch) (domain.Task, error) {
+	var task domain.Task
+	err := ts.write(func(tx *sql.Tx) error {
+		var err error
+		if task, err = lookup(tx, id, version); err != nil {
+			return err
+		}
+		if task.Status != domain.StatusBlocked && task.Status != domain.StatusPending {
+			return store.ErrNotPending
+		}
+
+		task = patch.Apply(task)
+		task.Version++
+		return save(tx, task)
+	})
+	if err != nil {
+		return domain.Task{}, err
+	}
+	return task, nil
+}
+
+// Delete removes a finished task. Batches keep listing its id, their progress
+// just doesn't count it anymore.
+func (ts *TaskStore) Delete(id int64, version int64) (domain.Task, error) {
+	var task domain.Task
+	err := ts.write(func(tx *sql.Tx) error {
+		var err error
+		if task, err = lookup(tx, id, version); err != nil {
+			return err
+		}
+		if !task.Status.Terminal() {
+			return store.ErrNotFinished
+		}
+		_, err = tx.Exec(`DELETE FROM tasks WHERE id = ?`, id)
+		return err
+	})
+	if err != nil {
+		return domain.Task{}, err
+	}
+	return task, nil
+}
+
+// Put stores a finished task under its own id, or a new one when the id is 0,
+// e.g. from an archive or an import. It keeps its version. An existing task is
+// ErrExists unless replace is set, only finished tasks can be replaced.
+func (ts *TaskStore) Put(task domain.Task, replace bool) (domain.Task, error) {
+	if task.ID < 0 {
+		return domain.Task{}, ErrInvalidTaskID
+	}
+	if !task.Status.Terminal() {
+		return domain.Task{}, store.ErrNotFinished
+	}
+
+	err := ts.write(func(tx *sql.Tx) error {
+		if task.ID == 0 {
+			id, err := nextID(tx, "tasks")
+			if err != nil {
+				return err
+			}
+			task.ID = id
+		}
+
+		existing, err := getTask(tx, task.ID)
+		switch {
+		case errors.Is(err, ErrNotFound):
+		case err != nil:
+			return err
+		case !replace:
+			return store.ErrExists
+		case !existing.Status.Terminal():
+			return store.ErrNotFinished
+		}
+
+		if err := save(tx, task); err != nil {
+			return err
+		}
+		return reserveIDs(tx, task.ID)
+	})
+	if err != nil {
+		return domain.Task{}, err
+	}
+	return task, nil
+}
+
+// LastID is the highest id handed out so far, deleted tasks included.
+func (ts *TaskStore) LastID() int64 {
+	var id int64
+	err := ts.db.QueryRow(`SELECT value FROM sequences WHERE name = 'tasks'`).Scan(&id)
+	ts.logError("last id", err)
+	return id
+}
+
+// ReserveIDs makes the store hand out ids above id only, so an import keeps
+// the ids of deleted tasks from being reused.
+func (ts *TaskStore) ReserveIDs(id int64) {
+	err := ts.write(func(tx *sql.Tx) error {
+		return reserveIDs(tx, id)
+	})
+	ts.logError("reserve ids", err)
+}
+
+func reserveIDs(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec(`UPDATE sequences SET value = MAX(value, ?) WHERE name = 'tasks'`, id)
+	return err
+}
+
+// Count returns the number of stored tasks, finished ones included.
+func (ts *TaskStore) Count() (int, error) {
+	var n int
+	err := ts.db.QueryRow(`SELECT COUNT(*) FROM tasks`).Scan(&n)
+	return n, err
+}
+
+// ListFinished returns up to limit finished tasks with an id above after, by
+// ascending id.
+func (ts *TaskStore) ListFinished(after int64, limit int) ([]domain.Task, error) {
+	return queryTasks(ts.db, selectTasks+` WHERE id > ? AND status NOT IN `+unfinished+` ORDER BY id LIMIT ?`, after, limit)
+}
+
+// ListAfter is ListFinished for every task.
+func (ts *TaskStore) ListAfter(after int64, limit int) ([]domain.Task, error) {
+	return queryTasks(ts.db, selectTasks+` WHERE id > ? ORDER BY id LIMIT ?`, after, limit)
+}
+
+func (ts *TaskStore) Fail(id int64, reason string) (domain.Task, error) {
+	return ts.transition(id, 0, func(task *domain.Task) error {
+		if task.Status.Terminal() {
+			return store.ErrInvalidTransition
+		}
+		task.Status = domain.StatusFailed
+		task.Error = reason
+		return nil
+	})
+}
+
+func (ts *TaskStore) Cancel(id int64, version int64, reason string) (domain.Task, error) {
+	return ts.transition(id, version, func(task *domain.Task) error {
+		if task.Status.Terminal() {
+			return store.ErrInvalidTransition
+		}
+		task.Status = domain.StatusCanceled
+		task.Error = reason
+		return nil
+	})
+}
+
+func (ts *TaskStore) UpdateStatus(id int64, status domain.TaskStatus) (domain.Task, error) {
+	return ts.transition(id, 0, func(task *domain.Task) error {
+		// finished tasks stay finished, e.g. a worker must not revive a canceled task
+		if task.Status.Terminal() {
+			return store.ErrInvalidTransition
+		}
+		task.Status = status
+		return nil
+	})
+}
+
+// transition reads the task, lets change move it to its new status and writes
+// it back in one transaction. A task reaching a terminal status gets its
+// FinishedAt.
+func (ts *TaskStore) transition(id int64, version int64, change func(task *domain.Task) error) (domain.Task, error) {
+	var task domain.Task
+	err := ts.write(func(tx *sql.Tx) error {
+		var err error
+		if task, err = lookup(tx, id, version); err != nil {
+			return err
+		}
+		if err := change(&task); err != nil {
+			return err
+		}
+		task.Version++
+		if task.Status.Terminal() {
+			task.FinishedAt = time.Now()
+		}
+		return save(tx, task)
+	})
+	if err != nil {
+		return domain.Task{}, err
+	}
+	return task, nil
+}
+
+// CancelUnfinished cancels every blocked, pending or running task, e.g. the
+// ones left over by a process that stopped: the new pool doesn't know them.
+func (ts *TaskStore) CancelUnfinished(reason string) (int, error) {
+	var n int64
+	err := ts.write(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`UPDATE tasks SET status = ?, error = ?, finished_at = ?, version = version + 1
+			WHERE status IN `+unfinished, string(domain.StatusCanceled), reason, time.Now().UnixNano())
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return int(n), err
+}
+
+func (ts *TaskStore) logError(op string, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ts.logger.Error("sqlite store: "+op+" failed", "error", err)
+	}
+}
+
+// lookup returns the task when version is zero or its current one.
+func lookup(q queryer, id int64, version int64) (domain.Task, error) {
+	task, err := getTask(q, id)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	if version != 0 && version != task.Version {
+		return domain.Task{}, store.ErrVersionConflict
+	}
+	return task, nil
+}
+
+func getTask(q queryer, id int64) (domain.Task, error) {
+	task, err := scanTask(q.QueryRow(selectTasks+` WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Task{}, ErrNotFound
+	}
+	return task, err
+}
+
+func queryTasks(q queryer, query string, args ...any) ([]domain.Task, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]domain.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func scanTask(row interface{ Scan(dest ...any) error }) (domain.Task, error) {
+	var task domain.Task
+	var status string
+	var metadata, dependsOn sql.NullString
+	var createdAt, finishedAt, retainUntil, workDuration int64
+
+	err := row.Scan(&task.ID, &task.Title, &task.Description, &task.Error, &status, &task.Priority,
+		&metadata, &task.Version, &task.Owner, &task.DedupeKey, &task.ConcurrencyKey, &task.ConcurrencyLimit,
+		&dependsOn, &task.BatchID, &task.TraceParent, &task.RequestID,
+		&createdAt, &finishedAt, &retainUntil, &workDuration)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	task.Status = domain.TaskStatus(status)
+	if metadata.Valid {
+		if err := json.Unmarshal([]byte(metadata.String), &task.Metadata); err != nil {
+			return domain.Task{}, fmt.Errorf("task %d metadata: %w", task.ID, err)
+		}
+	}
+	if dependsOn.Valid {
+		if err := json.Unmarshal([]byte(dependsOn.String), &task.DependsOn); err != nil {
+			return domain.Task{}, fmt.Errorf("task %d depends_on: %w", task.ID, err)
+		}
+	}
+	task.CreatedAt = fromUnixNano(createdAt)
+	task.FinishedAt = fromUnixNano(finishedAt)
+	task.RetainUntil = fromUnixNano(retainUntil)
+	task.WorkDuration = time.Duration(workDuration)
+	return task, nil
+}
+
+func save(tx *sql.Tx, task domain.Task) error {
+	metadata, err := jsonColumn(task.Metadata, task.Metadata == nil)
+	if err != nil {
+		return err
+	}
+	dependsOn, err := jsonColumn(task.DependsOn, task.DependsOn == nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(saveTask,
+		task.ID, task.Title, task.Description, task.Error, string(task.Status), task.Priority,
+		metadata, task.Version, task.Owner, task.DedupeKey, task.ConcurrencyKey, task.ConcurrencyLimit,
+		dependsOn, task.BatchID, task.TraceParent, task.RequestID,
+		unixNano(task.CreatedAt), unixNano(task.FinishedAt), unixNano(task.RetainUntil), int64(task.WorkDuration))
+	return err
+}
+
+// jsonColumn encodes v, NULL when it is nil so it reads back as nil.
+func jsonColumn(v any, isNil bool) (sql.NullString, error) {
+	if isNil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	return sql.NullString{String: string(b), Valid: true}, err
+}
+
+func nonNil(ids []int64) []int64 {
+	if ids == nil {
+		return []int64{}
+	}
+	return ids
+}
+
+// unixNano stores the zero time as 0, its UnixNano is out of range.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -1,14 +1,163 @@
 package store
 
 import (
+	"context"
 	"errors"
 	"interview-task-worker-pool/internal/domain"
+	"time"
 )
 
-var ErrNotFound = errors.New("task not found")
+var (
+	ErrNotFound            = errors.New("task not found")
+	ErrIdempotencyConflict = errors.New("idempotency key already used with a different request")
+	ErrDuplicate           = errors.New("a task with the same dedupe key is already in flight")
+	ErrInvalidTransition   = errors.New("invalid task status transition")
+	ErrBatchRejected       = errors.New("batch rejected")
+	ErrQuotaExceeded       = errors.New("pending task quota exceeded")
+	ErrNotPending          = errors.New("task is no longer pending")
+	ErrNotFinished         = errors.New("task is not finished")
+	ErrVersionConflict     = errors.New("task version does not match")
+	ErrExists              = errors.New("a task with this id already exists")
+)
+
+// DedupePolicy decides what happens when a task is created with a dedupe key
+// that already belongs to a pending or running task.
+type DedupePolicy string
+
+const (
+	DedupeReject         DedupePolicy = "reject"          // fail with ErrDuplicate
+	DedupeReturnExisting DedupePolicy = "return_existing" // attach to the in-flight task
+	DedupeReplace        DedupePolicy = "replace"         // cancel the pending task and create a new one
+)
+
+func (p DedupePolicy) Valid() bool {
+	return p == DedupeReject || p == DedupeReturnExisting || p == DedupeReplace
+}
+
+// CreateOptions tunes a single create call, the zero value is a plain Create.
+type CreateOptions struct {
+	IdempotencyKey string
+	Fingerprint    string        // identifies the request body bound to the key
+	KeyTTL         time.Duration // how long the key is remembered
+
+	DedupePolicy DedupePolicy // zero value means DedupeReject
+
+	// MaxActive caps the unfinished (blocked, pending or running) tasks of the
+	// task's owner, zero means no cap. Replays and attached tasks don't count.
+	MaxActive int
+}
+
+type CreateResult struct {
+	Task       domain.Task
+	Replayed   bool  // an earlier task was returned for the same idempotency key
+	Attached   bool  // an in-flight task with the same dedupe key was returned, replays keep it
+	ReplacedID int64 // pending task canceled in favor of this one (DedupeReplace)
+}
+
+// Batch groups tasks submitted together.
+type Batch struct {
+	ID        int64
+	Owner     string  // the caller who created it, empty without authentication
+	TaskIDs   []int64 // tasks created by the batch, attached ones are not included
+	CreatedAt time.Time
+}
+
+type BatchItem struct {
+	Result CreateResult
+	Err    error
+}
 
+// TaskPatch lists the fields an update changes, nil ones are kept.
+type TaskPatch struct {
+	Title       *string
+	Description *string
+	Priority    *int
+	Metadata    map[string]string // replaces the whole metadata when non-nil
+}
+
+// Apply returns task with the patch applied, the metadata is copied.
+func (p TaskPatch) Apply(task domain.Task) domain.Task {
+	if p.Title != nil {
+		task.Title = *p.Title
+	}
+	if p.Description != nil {
+		task.Description = *p.Description
+	}
+	if p.Priority != nil {
+		task.Priority = *p.Priority
+	}
+	if p.Metadata != nil {
+		task.Metadata = make(map[string]string, len(p.Metadata))
+		for k, v := range p.Metadata {
+			task.Metadata[k] = v
+		}
+	}
+	return task
+}
+
+// Filter selects tasks for TaskStore.Find, zero fields match every task.
+type Filter struct {
+	Owner  string
+	Status domain.TaskStatus
+
+	CreatedFrom time.Time // inclusive
+	CreatedTo   time.Time // exclusive
+
+	// a page is Limit tasks by id, the next one starts After the last id seen
+	After int64
+	Limit int // zero is no limit
+}
+
+// Match reports whether task passes the filter, the page aside.
+func (f Filter) Match(task domain.Task) bool {
+	return (f.Owner == "" || task.Owner == f.Owner) &&
+		(f.Status == "" || task.Status == f.Status) &&
+		(f.CreatedFrom.IsZero() || !task.CreatedAt.Before(f.CreatedFrom)) &&
+		(f.CreatedTo.IsZero() || task.CreatedAt.Before(f.CreatedTo))
+}
+
+// TaskStore keeps tasks. Every mutation bumps the task's Version (1 on create).
+// The ones a client asks for take the version it acted on and fail with
+// ErrVersionConflict when the task changed since, zero skips the check. The
+// status transitions of the pool are guarded by the status instead.
 type TaskStore interface {
 	Create(t domain.Task) (domain.Task, error)
+	CreateWith(t domain.Task, opts CreateOptions) (CreateResult, error)
+	// CreateBatch applies maxActive like CreateOptions.MaxActive, per task in
+	// order. With atomic set a task over the quota rejects the batch. The batch
+	// is recorded as owner's, whatever becomes of its tasks.
+	CreateBatch(owner string, tasks []domain.Task, policy DedupePolicy, atomic bool, maxActive int) (Batch, []BatchItem, error)
 	Get(id int64) (domain.Task, bool)
+	GetBatch(id int64) (Batch, bool)
 	List() ([]domain.Task, error)
+	// Find returns the tasks matching filter by ascending id.
+	Find(filter Filter) ([]domain.Task, error)
+
+	// UpdateStatus moves an unfinished task to status, ErrInvalidTransition once finished.
+	UpdateStatus(id int64, status domain.TaskStatus) (domain.Task, error)
+	Fail(id int64, reason string) (domain.Task, error)
+
+	// Update patches a blocked or pending task, ErrNotPending otherwise.
+	Update(id int64, version int64, patch TaskPatch) (domain.Task, error)
+	// Cancel cancels an unfinished task, ErrInvalidTransition once finished.
+	Cancel(id int64, version int64, reason string) (domain.Task, error)
+	// Delete removes a finished task (ErrNotFinished otherwise) and returns it.
+	Delete(id int64, version int64) (domain.Task, error)
+}
+
+// Backend is a complete store implementation, the process runs on one of
+// them (STORE in the config). Next to TaskStore it serves the retention
+// janitor, the export and import, the quota and the readiness probe.
+type Backend interface {
+	TaskStore
+	ActiveCount(owner string) int
+	ExpireIdempotencyKeys(now time.Time) int
+	Ping(ctx context.Context) error
+
+	Count() (int, error)
+	ListFinished(after int64, limit int) ([]domain.Task, error)
+	ListAfter(after int64, limit int) ([]domain.Task, error)
+	Put(task domain.Task, replace bool) (domain.Task, error)
+	LastID() int64
+	ReserveIDs(id int64)
 }
@@ -0,0 +1,867 @@
+// Package storetest is the behavior every store.Backend has to show, run by
+// the test of each implementation:
+//
+//	func TestTaskStore_Conformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Backend { return New() })
+//	}
+//
+// A new backend gets the whole suite by calling Run, a new store capability
+// gets its cases here instead of in one backend's tests.
+package storetest
+
+import (
+	"errors"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/store"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Factory returns an empty store for one test, closed by the factory through
+// t.Cleanup when it needs closing.
+type Factory func(t *testing.T) store.Backend
+
+// Run runs every case as a subtest, each on a store of its own.
+func Run(t *testing.T, newStore Factory) {
+	for _, tc := range []struct {
+		name string
+		run  func(t *testing.T, ts store.Backend)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"GetNotFound", testGetNotFound},
+		{"KeepsEveryField", testKeepsEveryField},
+		{"List", testList},
+		{"Fail", testFail},
+		{"NotFound", testNotFound},
+		{"UpdateStatus", testUpdateStatus},
+		{"UpdateStatusTerminalIsFinal", testUpdateStatusTerminalIsFinal},
+		{"FailTerminalIsFinal", testFailTerminalIsFinal},
+		{"IDsIncrease", testIDsIncrease},
+		{"IdempotentReplay", testIdempotentReplay},
+		{"IdempotentReplayOfAttached", testIdempotentReplayOfAttached},
+		{"IdempotencyConflict", testIdempotencyConflict},
+		{"ExpireIdempotencyKeys", testExpireIdempotencyKeys},
+		{"DedupeReject", testDedupeReject},
+		{"DedupeReturnExisting", testDedupeReturnExisting},
+		{"DedupeReplacePending", testDedupeReplacePending},
+		{"DedupeReplaceRunningRejected", testDedupeReplaceRunningRejected},
+		{"DedupeReleasedOnTerminal", testDedupeReleasedOnTerminal},
+		{"CreateBatch", testCreateBatch},
+		{"CreateBatchAtomicConflictInsertsNothing", testCreateBatchAtomicConflict},
+		{"CreateBatchDuplicateKeyWithinBatch", testCreateBatchDuplicateKeyWithinBatch},
+		{"CreateBatchQuota", testCreateBatchQuota},
+		{"MaxActive", testMaxActive},
+		{"MaxActiveReplaceAtQuota", testMaxActiveReplaceAtQuota},
+		{"Update", testUpdate},
+		{"Delete", testDelete},
+		{"VersionsAndCompareAndSwap", testVersionsAndCompareAndSwap},
+		{"ListFinished", testListFinished},
+		{"Put", testPut},
+		{"Find", testFind},
+		{"Ping", testPing},
+		{"ConcurrentCreate", testConcurrentCreate},
+		{"ConcurrentSameIdempotencyKey", testConcurrentSameIdempotencyKey},
+		{"ConcurrentSameDedupeKey", testConcurrentSameDedupeKey},
+		{"ConcurrentQuota", testConcurrentQuota},
+		{"ConcurrentBatchQuota", testConcurrentBatchQuota},
+		{"ConcurrentCompareAndSwap", testConcurrentCompareAndSwap},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newStore(t))
+		})
+	}
+}
+
+func testCreateAndGet(t *testing.T, ts store.Backend) {
+	in := domain.Task{Title: "t1", Description: "d1", Status: domain.StatusDone}
+
+	created, err := ts.Create(in)
+	if err != nil {
+		t.Fatalf("Create() err = %v, want nil", err)
+	}
+	if created.ID <= 0 {
+		t.Fatalf("Create() id = %d, want > 0", created.ID)
+	}
+	// status is not definable by the caller
+	if created.Status != domain.StatusPending || created.Version != 1 {
+		t.Fatalf("Create() = %+v, want pending at version 1", created)
+	}
+
+	got, ok := ts.Get(created.ID)
+	if !ok {
+		t.Fatal("Get() ok = false, want ok = true")
+	}
+	if got.ID != created.ID || got.Title != in.Title || got.Description != in.Description || got.Status != domain.StatusPending {
+		t.Fatalf("Get() = %+v, want the created task", got)
+	}
+
+	blocked, _ := ts.Create(domain.Task{Title: "b", Status: domain.StatusBlocked})
+	if blocked.Status != domain.StatusBlocked {
+		t.Fatalf("Create(blocked) status = %s, want %s", blocked.Status, domain.StatusBlocked)
+	}
+}
+
+func testGetNotFound(t *testing.T, ts store.Backend) {
+	if _, ok := ts.Get(9999); ok {
+		t.Fatal("Get() ok = true, want ok = false")
+	}
+	if _, ok := ts.GetBatch(9999); ok {
+		t.Fatal("GetBatch() ok = true, want ok = false")
+	}
+}
+
+func testKeepsEveryField(t *testing.T, ts store.Backend) {
+	in := domain.Task{
+		Title: "t", Description: "d", Priority: 3,
+		Metadata: map[string]string{"k": "v"}, Owner: "alice", DedupeKey: "job",
+		ConcurrencyKey: "c", ConcurrencyLimit: 2, DependsOn: []int64{7}, Status: domain.StatusBlocked,
+		TraceParent: "00-abc-def-01", RequestID: "req-1",
+		CreatedAt: time.Now(), RetainUntil: time.Now().Add(time.Hour), WorkDuration: 3 * time.Second,
+	}
+	created, err := ts.Create(in)
+	if err != nil {
+		t.Fatalf("Create() err = %v, want nil", err)
+	}
+
+	got, _ := ts.Get(created.ID)
+	if !got.CreatedAt.Equal(in.CreatedAt) || !got.RetainUntil.Equal(in.RetainUntil) || !got.FinishedAt.IsZero() {
+		t.Fatalf("Get() times = %v %v %v, want them as created", got.CreatedAt, got.RetainUntil, got.FinishedAt)
+	}
+	// times only compare with Equal, a backend may drop the location
+	got.CreatedAt, got.RetainUntil = created.CreatedAt, created.RetainUntil
+	if !reflect.DeepEqual(got, created) {
+		t.Fatalf("Get() = %+v, want %+v", got, created)
+	}
+
+	plain, _ := ts.Create(domain.Task{Title: "plain"})
+	if got, _ := ts.Get(plain.ID); got.Metadata != nil || got.DependsOn != nil {
+		t.Fatalf("Get() = %+v, want nil metadata and dependencies", got)
+	}
+}
+
+func testList(t *testing.T, ts store.Backend) {
+	t1, _ := ts.Create(domain.Task{Title: "t1"})
+	t2, _ := ts.Create(domain.Task{Title: "t2"})
+
+	list, err := ts.List()
+	if err != nil {
+		t.Fatalf("List() err = %v, want nil", err)
+	}
+	// the order isn't part of List
+	if len(list) != 2 || !containsID(list, t1.ID) || !containsID(list, t2.ID) {
+		t.Fatalf("List() = %+v, want tasks %d and %d", list, t1.ID, t2.ID)
+	}
+}
+
+func testFail(t *testing.T, ts store.Backend) {
+	created, _ := ts.Create(domain.Task{Title: "t"})
+	reason := "task pool is full"
+
+	failed, err := ts.Fail(created.ID, reason)
+	if err != nil {
+		t.Fatalf("Fail() err = %v, want nil", err)
+	}
+	if failed.Status != domain.StatusFailed || failed.Error != reason || failed.FinishedAt.IsZero() {
+		t.Fatalf("Fail() = %+v, want failed with %q and FinishedAt", failed, reason)
+	}
+
+	got, _ := ts.Get(created.ID)
+	if got.Status != domain.StatusFailed || got.Error != reason {
+		t.Fatalf("Get() after Fail = %+v, want status=failed error=%q", got, reason)
+	}
+}
+
+func testNotFound(t *testing.T, ts store.Backend) {
+	for name, call := range map[string]func() error{
+		"Fail":         func() error { _, err := ts.Fail(123, "x"); return err },
+		"UpdateStatus": func() error { _, err := ts.UpdateStatus(123, domain.StatusDone); return err },
+		"Cancel":       func() error { _, err := ts.Cancel(123, 0, "x"); return err },
+		"Update":       func() error { _, err := ts.Update(123, 0, store.TaskPatch{}); return err },
+		"Delete":       func() error { _, err := ts.Delete(123, 0); return err },
+	} {
+		if err := call(); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("%s() err = %v, want %v", name, err, store.ErrNotFound)
+		}
+	}
+}
+
+func testUpdateStatus(t *testing.T, ts store.Backend) {
+	created, _ := ts.Create(domain.Task{Title: "t"})
+	updated, err := ts.UpdateStatus(created.ID, domain.StatusRunning)
+	if err != nil {
+		t.Fatalf("UpdateStatus() err = %v, want nil", err)
+	}
+	if updated.Status != domain.StatusRunning || updated.Version != 2 || !updated.FinishedAt.IsZero() {
+		t.Fatalf("UpdateStatus() = %+v, want running at version 2", updated)
+	}
+	if got, _ := ts.Get(created.ID); got.Status != domain.StatusRunning {
+		t.Fatalf("Get() status = %s, want %s", got.Status, domain.StatusRunning)
+	}
+
+	done, _ := ts.UpdateStatus(created.ID, domain.StatusDone)
+	if done.FinishedAt.IsZero() {
+		t.Fatal("UpdateStatus(done) FinishedAt is zero, want set")
+	}
+}
+
+func testUpdateStatusTerminalIsFinal(t *testing.T, ts store.Backend) {
+	created, _ := ts.Create(domain.Task{Title: "t"})
+	_, _ = ts.Fail(created.ID, "x")
+
+	if _, err := ts.UpdateStatus(created.ID, domain.StatusRunning); !errors.Is(err, store.ErrInvalidTransition) {
+		t.Fatalf("UpdateStatus() err = %v, want %v", err, store.ErrInvalidTransition)
+	}
+	if _, err := ts.Cancel(created.ID, 0, "stop"); !errors.Is(err, store.ErrInvalidTransition) {
+		t.Fatalf("Cancel() err = %v, want %v", err, store.ErrInvalidTransition)
+	}
+}
+
+func testFailTerminalIsFinal(t *testing.T, ts store.Backend) {
+	for _, finish := range []func(id int64) (domain.Task, error){
+		func(id int64) (domain.Task, error) { return ts.UpdateStatus(id, domain.StatusDone) },
+		func(id int64) (domain.Task, error) { return ts.Cancel(id, 0, "stop") },
+		func(id int64) (domain.Task, error) { return ts.Fail(id, "first") },
+	} {
+		created, _ := ts.Create(domain.Task{Title: "t"})
+		finished, _ := finish(created.ID)
+
+		if _, err := ts.Fail(created.ID, "again"); !errors.Is(err, store.ErrInvalidTransition) {
+			t.Fatalf("Fail(%s) err = %v, want %v", finished.Status, err, store.ErrInvalidTransition)
+		}
+		if got, _ := ts.Get(created.ID); got.Status != finished.Status || got.Error != finished.Error || got.Version != finished.Version {
+			t.Fatalf("Get() after Fail(%s) = %+v, want it unchanged", finished.Status, got)
+		}
+	}
+}
+
+// ids go up and are never handed out twice, deleted tasks included
+func testIDsIncrease(t *testing.T, ts store.Backend) {
+	var last int64
+	for i := 0; i < 5; i++ {
+		created, _ := ts.Create(domain.Task{Title: "t"})
+		if created.ID <= last {
+			t.Fatalf("Create() id = %d after %d, want it higher", created.ID, last)
+		}
+		last = created.ID
+	}
+	if ts.LastID() != last {
+		t.Fatalf("LastID() = %d, want %d", ts.LastID(), last)
+	}
+
+	_, _ = ts.UpdateStatus(last, domain.StatusDone)
+	_, _ = ts.Delete(last, 0)
+	if created, _ := ts.Create(domain.Task{Title: "t"}); created.ID != last+1 {
+		t.Fatalf("Create() id = %d after deleting %d, want %d", created.ID, last, last+1)
+	}
+
+	// a batch and a quota refusal don't skip ids either
+	_, _, _ = ts.CreateBatch("", []domain.Task{{Title: "b"}}, store.DedupeReject, false, 0)
+	_, _ = ts.CreateWith(domain.Task{Title: "q"}, store.CreateOptions{MaxActive: 1})
+	if ts.LastID() != last+2 {
+		t.Fatalf("LastID() = %d, want %d", ts.LastID(), last+2)
+	}
+}
+
+func testIdempotentReplay(t *testing.T, ts store.Backend) {
+	opts := store.CreateOptions{IdempotencyKey: "k1", Fingerprint: "fp", KeyTTL: time.Minute}
+
+	first, err := ts.CreateWith(domain.Task{Title: "t"}, opts)
+	if err != nil || first.Replayed {
+		t.Fatalf("CreateWith() = %+v err = %v, want a new task", first, err)
+	}
+
+	second, err := ts.CreateWith(domain.Task{Title: "t"}, opts)
+	if err != nil {
+		t.Fatalf("CreateWith() err = %v, want nil", err)
+	}
+	if !second.Replayed || second.Task.ID != first.Task.ID {
+		t.Fatalf("CreateWith() got = %+v, want replay of id %d", second, first.Task.ID)
+	}
+
+	if n, _ := ts.Count(); n != 1 {
+		t.Fatalf("Count() = %d, want 1", n)
+	}
+}
+
+func testIdempotentReplayOfAttached(t *testing.T, ts store.Backend) {
+	running, _ := ts.Create(domain.Task{Title: "t", DedupeKey: "job"})
+	opts := store.CreateOptions{IdempotencyKey: "k1", Fingerprint: "fp", KeyTTL: time.Minute, DedupePolicy: store.DedupeReturnExisting}
+
+	first, err := ts.CreateWith(domain.Task{Title: "t", DedupeKey: "job"}, opts)
+	if err != nil || !first.Attached || first.Task.ID != running.ID {
+		t.Fatalf("CreateWith() = %+v err = %v, want attached to id %d", first, err, running.ID)
+	}
+	// the replay reports the original outcome, attached and not created
+	second, err := ts.CreateWith(domain.Task{Title: "t", DedupeKey: "job"}, opts)
+	if err != nil || !second.Replayed || !second.Attached || second.Task.ID != running.ID {
+		t.Fatalf("CreateWith() = %+v err = %v, want an attached replay of id %d", second, err, running.ID)
+	}
+}
+
+func testIdempotencyConflict(t *testing.T, ts store.Backend) {
+	_, _ = ts.CreateWith(domain.Task{Title: "a"}, store.CreateOptions{IdempotencyKey: "k1", Fingerprint: "a", KeyTTL: time.Minute})
+	_, err := ts.CreateWith(domain.Task{Title: "b"}, store.CreateOptions{IdempotencyKey: "k1", Fingerprint: "b", KeyTTL: time.Minute})
+	if !errors.Is(err, store.ErrIdempotencyConflict) {
+		t.Fatalf("CreateWith() err = %v, want %v", err, store.ErrIdempotencyConflict)
+	}
+}
+
+func testExpireIdempotencyKeys(t *testing.T, ts store.Backend) {
+	opts := store.CreateOptions{IdempotencyKey: "k1", Fingerprint: "fp", KeyTTL: time.Minute}
+
+	first, _ := ts.CreateWith(domain.Task{Title: "t"}, opts)
+
+	if n := ts.ExpireIdempotencyKeys(time.Now()); n != 0 {
+		t.Fatalf("ExpireIdempotencyKeys(now) = %d, want 0", n)
+	}
+	if n := ts.ExpireIdempotencyKeys(time.Now().Add(2 * time.Minute)); n != 1 {
+		t.Fatalf("ExpireIdempotencyKeys() = %d, want 1", n)
+	}
+
+	second, err := ts.CreateWith(domain.Task{Title: "t"}, opts)
+	if err != nil {
+		t.Fatalf("CreateWith() err = %v, want nil", err)
+	}
+	if second.Replayed || second.Task.ID == first.Task.ID {
+		t.Fatalf("CreateWith() after expiry got = %+v, want a new task", second)
+	}
+}
+
+func testDedupeReject(t *testing.T, ts store.Backend) {
+	if _, err := ts.Create(domain.Task{Title: "a", DedupeKey: "job"}); err != nil {
+		t.Fatalf("Create() err = %v, want nil", err)
+	}
+	_, err := ts.CreateWith(domain.Task{Title: "b", DedupeKey: "job"}, store.CreateOptions{DedupePolicy: store.DedupeReject})
+	if !errors.Is(err, store.ErrDuplicate) {
+		t.Fatalf("CreateWith() err = %v, want %v", err, store.ErrDuplicate)
+	}
+
+	// keys are scoped to the owner
+	if _, err := ts.Create(domain.Task{Title: "c", DedupeKey: "job", Owner: "bob"}); err != nil {
+		t.Fatalf("Create(other owner) err = %v, want nil", err)
+	}
+}
+
+func testDedupeReturnExisting(t *testing.T, ts store.Backend) {
+	first, _ := ts.Create(domain.Task{Title: "a", DedupeKey: "job"})
+	res, err := ts.CreateWith(domain.Task{Title: "b", DedupeKey: "job"}, store.CreateOptions{DedupePolicy: store.DedupeReturnExisting})
+	if err != nil {
+		t.Fatalf("CreateWith() err = %v, want nil", err)
+	}
+	if !res.Attached || res.Task.ID != first.ID {
+		t.Fatalf("CreateWith() got = %+v, want attached to %d", res, first.ID)
+	}
+}
+
+func testDedupeReplacePending(t *testing.T, ts store.Backend) {
+	first, _ := ts.Create(domain.Task{Title: "a", DedupeKey: "job"})
+	res, err := ts.CreateWith(domain.Task{Title: "b", DedupeKey: "job"}, store.CreateOptions{DedupePolicy: store.DedupeReplace})
+	if err != nil {
+		t.Fatalf("CreateWith() err = %v, want nil", err)
+	}
+	if res.ReplacedID != first.ID || res.Task.ID == first.ID {
+		t.Fatalf("CreateWith() got = %+v, want new task replacing %d", res, first.ID)
+	}
+
+	old, _ := ts.Get(first.ID)
+	if old.Status != domain.StatusCanceled || old.Version != 2 || old.FinishedAt.IsZero() {
+		t.Fatalf("replaced task = %+v, want canceled at version 2", old)
+	}
+}
+
+func testDedupeReplaceRunningRejected(t *testing.T, ts store.Backend) {
+	first, _ := ts.Create(domain.Task{Title: "a", DedupeKey: "job"})
+	_, _ = ts.UpdateStatus(first.ID, domain.StatusRunning)
+
+	_, err := ts.CreateWith(domain.Task{Title: "b", DedupeKey: "job"}, store.CreateOptions{DedupePolicy: store.DedupeReplace})
+	if !errors.Is(err, store.ErrDuplicate) {
+		t.Fatalf("CreateWith() err = %v, want %v", err, store.ErrDuplicate)
+	}
+}
+
+func testDedupeReleasedOnTerminal(t *testing.T, ts store.Backend) {
+	for _, finish := range []func(id int64){
+		func(id int64) { _, _ = ts.UpdateStatus(id, domain.StatusDone) },
+		func(id int64) { _, _ = ts.Fail(id, "x") },
+		func(id int64) { _, _ = ts.Cancel(id, 0, "stop") },
+	} {
+		first, err := ts.Create(domain.Task{Title: "a", DedupeKey: "job"})
+		if err != nil {
+			t.Fatalf("Create() err = %v, want nil", err)
+		}
+		finish(first.ID)
+
+		second, err := ts.Create(domain.Task{Title: "b", DedupeKey: "job"})
+		if err != nil || second.ID == first.ID {
+			t.Fatalf("Create() = %+v err = %v, want a new task", second, err)
+		}
+		_, _ = ts.Cancel(second.ID, 0, "stop")
+	}
+}
+
+func testCreateBatch(t *testing.T, ts store.Backend) {
+	batch, items, err := ts.CreateBatch("alice", []domain.Task{{Title: "a", Owner: "alice"}, {Title: "b", Owner: "alice"}}, store.DedupeReject, false, 0)
+	if err != nil {
+		t.Fatalf("CreateBatch() err = %v, want nil", err)
+	}
+	if batch.ID <= 0 || len(batch.TaskIDs) != 2 || len(items) != 2 {
+		t.Fatalf("CreateBatch() batch = %+v items = %d, want 2 tasks", batch, len(items))
+	}
+	for _, item := range items {
+		if item.Err != nil || item.Result.Task.BatchID != batch.ID {
+			t.Fatalf("CreateBatch() item = %+v, want created in batch %d", item, batch.ID)
+		}
+	}
+
+	got, ok := ts.GetBatch(batch.ID)
+	if !ok || !reflect.DeepEqual(got.TaskIDs, batch.TaskIDs) || got.Owner != "alice" {
+		t.Fatalf("GetBatch() = %+v ok = %v, want the batch", got, ok)
+	}
+
+	// without atomic, a duplicate only fails its own item
+	_, _ = ts.Create(domain.Task{Title: "x", DedupeKey: "job"})
+	next, items, err := ts.CreateBatch("", []domain.Task{{Title: "c", DedupeKey: "job"}, {Title: "d"}}, store.DedupeReject, false, 0)
+	if err != nil || !errors.Is(items[0].Err, store.ErrDuplicate) || items[1].Err != nil || len(next.TaskIDs) != 1 {
+		t.Fatalf("CreateBatch() batch = %+v items = %+v err = %v, want item 0 duplicate, item 1 created", next, items, err)
+	}
+	if next.ID <= batch.ID {
+		t.Fatalf("CreateBatch() id = %d after %d, want it higher", next.ID, batch.ID)
+	}
+}
+
+func testCreateBatchAtomicConflict(t *testing.T, ts store.Backend) {
+	_, _ = ts.Create(domain.Task{Title: "x", DedupeKey: "job"})
+
+	_, items, err := ts.CreateBatch("", []domain.Task{{Title: "a"}, {Title: "b", DedupeKey: "job"}}, store.DedupeReject, true, 0)
+	if !errors.Is(err, store.ErrBatchRejected) {
+		t.Fatalf("CreateBatch() err = %v, want %v", err, store.ErrBatchRejected)
+	}
+	if items[0].Err != nil || !errors.Is(items[1].Err, store.ErrDuplicate) {
+		t.Fatalf("CreateBatch() items = %+v, want only item 1 duplicate", items)
+	}
+
+	if n, _ := ts.Count(); n != 1 {
+		t.Fatalf("Count() = %d, want 1", n)
+	}
+}
+
+func testCreateBatchDuplicateKeyWithinBatch(t *testing.T, ts store.Backend) {
+	_, items, err := ts.CreateBatch("", []domain.Task{{Title: "a", DedupeKey: "k"}, {Title: "b", DedupeKey: "k"}}, store.DedupeReject, true, 0)
+	if !errors.Is(err, store.ErrBatchRejected) || !errors.Is(items[1].Err, store.ErrDuplicate) {
+		t.Fatalf("CreateBatch() err = %v items = %+v, want item 1 duplicate", err, items)
+	}
+}
+
+func testCreateBatchQuota(t *testing.T, ts store.Backend) {
+	_, _ = ts.Create(domain.Task{Title: "running", Owner: "alice", DedupeKey: "job"})
+	tasks := []domain.Task{
+		{Title: "a", Owner: "alice"},
+		{Title: "attached", Owner: "alice", DedupeKey: "job"},
+		{Title: "b", Owner: "alice"},
+		{Title: "c", Owner: "alice"},
+	}
+
+	// atomic: the task over the quota rejects the batch, attaching takes no room
+	_, items, err := ts.CreateBatch("alice", tasks, store.DedupeReturnExisting, true, 3)
+	if !errors.Is(err, store.ErrBatchRejected) || items[2].Err != nil || !errors.Is(items[3].Err, store.ErrQuotaExceeded) {
+		t.Fatalf("CreateBatch(atomic) err = %v items = %+v, want item 3 over the quota", err, items)
+	}
+	if got := ts.ActiveCount("alice"); got != 1 {
+		t.Fatalf("ActiveCount() = %d after a rejected batch, want 1", got)
+	}
+
+	_, items, err = ts.CreateBatch("alice", tasks, store.DedupeReturnExisting, false, 3)
+	if err != nil || items[0].Err != nil || !items[1].Result.Attached || items[2].Err != nil || !errors.Is(items[3].Err, store.ErrQuotaExceeded) {
+		t.Fatalf("CreateBatch() err = %v items = %+v, want item 3 over the quota", err, items)
+	}
+	if got := ts.ActiveCount("alice"); got != 3 {
+		t.Fatalf("ActiveCount() = %d, want 3", got)
+	}
+}
+
+func testMaxActive(t *testing.T, ts store.Backend) {
+	opts := store.CreateOptions{MaxActive: 2}
+
+	a, _ := ts.CreateWith(domain.Task{Title: "a", Owner: "alice"}, opts)
+	_, _ = ts.CreateWith(domain.Task{Title: "b", Owner: "alice"}, opts)
+	if _, err := ts.CreateWith(domain.Task{Title: "c", Owner: "alice"}, opts); !errors.Is(err, store.ErrQuotaExceeded) {
+		t.Fatalf("CreateWith() over quota err = %v, want %v", err, store.ErrQuotaExceeded)
+	}
+	if _, err := ts.CreateWith(domain.Task{Title: "c", Owner: "bob"}, opts); err != nil {
+		t.Fatalf("CreateWith() other owner err = %v, want nil", err)
+	}
+
+	_, _ = ts.UpdateStatus(a.Task.ID, domain.StatusRunning)
+	if n := ts.ActiveCount("alice"); n != 2 {
+		t.Fatalf("ActiveCount() = %d, want 2 with a running task", n)
+	}
+	_, _ = ts.UpdateStatus(a.Task.ID, domain.StatusDone)
+	if n := ts.ActiveCount("alice"); n != 1 {
+		t.Fatalf("ActiveCount() = %d, want 1 after done", n)
+	}
+	if _, err := ts.CreateWith(domain.Task{Title: "c", Owner: "alice"}, opts); err != nil {
+		t.Fatalf("CreateWith() after done err = %v, want nil", err)
+	}
+}
+
+func testMaxActiveReplaceAtQuota(t *testing.T, ts store.Backend) {
+	opts := store.CreateOptions{MaxActive: 1, DedupePolicy: store.DedupeReplace}
+
+	first, _ := ts.CreateWith(domain.Task{Title: "a", Owner: "alice", DedupeKey: "job"}, opts)
+	res, err := ts.CreateWith(domain.Task{Title: "b", Owner: "alice", DedupeKey: "job"}, opts)
+	if err != nil || res.ReplacedID != first.Task.ID {
+		t.Fatalf("CreateWith() got = %+v err = %v, want replacing %d", res, err, first.Task.ID)
+	}
+	if n := ts.ActiveCount("alice"); n != 1 {
+		t.Fatalf("ActiveCount() = %d, want 1", n)
+	}
+
+	// a refused create leaves the task it would have replaced alone
+	_, _ = ts.CreateWith(domain.Task{Title: "c", Owner: "alice", DedupeKey: "other"}, store.CreateOptions{})
+	if _, err := ts.CreateWith(domain.Task{Title: "d", Owner: "alice", DedupeKey: "job"}, opts); !errors.Is(err, store.ErrQuotaExceeded) {
+		t.Fatalf("CreateWith() over quota err = %v, want %v", err, store.ErrQuotaExceeded)
+	}
+	if got, _ := ts.Get(res.Task.ID); got.Status != domain.StatusPending || got.Version != 1 {
+		t.Fatalf("Get() = %+v after a refused replace, want it pending at version 1", got)
+	}
+}
+
+func testUpdate(t *testing.T, ts store.Backend) {
+	created, _ := ts.Create(domain.Task{Title: "t", Metadata: map[string]string{"a": "1"}})
+
+	title, priority := "renamed", 5
+	metadata := map[string]string{"b": "2"}
+	updated, err := ts.Update(created.ID, 1, store.TaskPatch{Title: &title, Priority: &priority, Metadata: metadata})
+	if err != nil {
+		t.Fatalf("Update() err = %v, want nil", err)
+	}
+	if updated.Title != "renamed" || updated.Priority != 5 || updated.Metadata["b"] != "2" || updated.Metadata["a"] != "" || updated.Version != 2 {
+		t.Fatalf("Update() = %+v, want renamed, priority 5, metadata replaced, version 2", updated)
+	}
+	metadata["b"] = "changed"
+	if got, _ := ts.Get(created.ID); got.Metadata["b"] != "2" || got.Title != "renamed" {
+		t.Fatalf("Get() = %+v, want the patch stored as a copy", got)
+	}
+
+	// the second client edited version 1 too
+	if _, err := ts.Update(created.ID, 1, store.TaskPatch{Title: &title}); !errors.Is(err, store.ErrVersionConflict) {
+		t.Fatalf("Update(stale version) err = %v, want %v", err, store.ErrVersionConflict)
+	}
+	if _, err := ts.Update(created.ID, 0, store.TaskPatch{Title: &title}); err != nil {
+		t.Fatalf("Update(version 0) err = %v, want nil", err)
+	}
+
+	_, _ = ts.UpdateStatus(created.ID, domain.StatusRunning)
+	if _, err := ts.Update(created.ID, 0, store.TaskPatch{Title: &title}); !errors.Is(err, store.ErrNotPending) {
+		t.Fatalf("Update(running) err = %v, want %v", err, store.ErrNotPending)
+	}
+}
+
+func testDelete(t *testing.T, ts store.Backend) {
+	created, _ := ts.Create(domain.Task{Title: "t"})
+	if _, err := ts.Delete(created.ID, 0); !errors.Is(err, store.ErrNotFinished) {
+		t.Fatalf("Delete(pending) err = %v, want %v", err, store.ErrNotFinished)
+	}
+
+	_, _ = ts.UpdateStatus(created.ID, domain.StatusDone)
+	deleted, err := ts.Delete(created.ID, 0)
+	if err != nil || deleted.ID != created.ID {
+		t.Fatalf("Delete() = %+v err = %v, want the task", deleted, err)
+	}
+	if _, ok := ts.Get(created.ID); ok {
+		t.Fatal("Get() ok = true after Delete, want false")
+	}
+	if _, err := ts.Delete(created.ID, 0); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("Delete(twice) err = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testVersionsAndCompareAndSwap(t *testing.T, ts store.Backend) {
+	created, _ := ts.Create(domain.Task{Title: "t"})
+	running, _ := ts.UpdateStatus(created.ID, domain.StatusRunning)
+	if running.Version != 2 {
+		t.Fatalf("UpdateStatus() version = %d, want 2", running.Version)
+	}
+
+	if _, err := ts.Cancel(created.ID, 1, "stop"); !errors.Is(err, store.ErrVersionConflict) {
+		t.Fatalf("Cancel(stale version) err = %v, want %v", err, store.ErrVersionConflict)
+	}
+	canceled, err := ts.Cancel(created.ID, 2, "stop")
+	if err != nil || canceled.Version != 3 || canceled.Error != "stop" || canceled.FinishedAt.IsZero() {
+		t.Fatalf("Cancel() = %+v err = %v, want canceled at version 3", canceled, err)
+	}
+
+	if _, err := ts.Delete(created.ID, 2); !errors.Is(err, store.ErrVersionConflict) {
+		t.Fatalf("Delete(stale version) err = %v, want %v", err, store.ErrVersionConflict)
+	}
+	if _, err := ts.Delete(created.ID, 3); err != nil {
+		t.Fatalf("Delete() err = %v, want nil", err)
+	}
+
+	other, _ := ts.Create(domain.Task{Title: "o"})
+	if failed, _ := ts.Fail(other.ID, "x"); failed.Version != 2 {
+		t.Fatalf("Fail() version = %d, want 2", failed.Version)
+	}
+}
+
+func testListFinished(t *testing.T, ts store.Backend) {
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		created, _ := ts.Create(domain.Task{Title: "t"})
+		ids = append(ids, created.ID)
+	}
+	_, _ = ts.UpdateStatus(ids[0], domain.StatusDone)
+	_, _ = ts.Fail(ids[2], "x")
+	_, _ = ts.Cancel(ids[3], 0, "stop")
+
+	page, err := ts.ListFinished(0, 2)
+	if err != nil || len(page) != 2 || page[0].ID != ids[0] || page[1].ID != ids[2] {
+		t.Fatalf("ListFinished(0, 2) = %+v err = %v, want tasks %d and %d", page, err, ids[0], ids[2])
+	}
+	page, _ = ts.ListFinished(page[1].ID, 2)
+	if len(page) != 1 || page[0].ID != ids[3] || page[0].FinishedAt.IsZero() {
+		t.Fatalf("ListFinished(next page) = %+v, want task %d", page, ids[3])
+	}
+
+	_, _ = ts.Delete(ids[0], 0)
+	if n, _ := ts.Count(); n != 4 {
+		t.Fatalf("Count() = %d, want 4", n)
+	}
+	if page, _ := ts.ListFinished(0, 10); len(page) != 2 || page[0].ID != ids[2] {
+		t.Fatalf("ListFinished() after Delete = %+v, want tasks %d and %d", page, ids[2], ids[3])
+	}
+}
+
+func testPut(t *testing.T, ts store.Backend) {
+	if _, err := ts.Put(domain.Task{ID: 1, Status: domain.StatusPending}, false); !errors.Is(err, store.ErrNotFinished) {
+		t.Fatalf("Put(pending) err = %v, want %v", err, store.ErrNotFinished)
+	}
+	restored, err := ts.Put(domain.Task{ID: 7, Title: "old", Status: domain.StatusDone, Version: 3}, false)
+	if err != nil || restored.Version != 3 {
+		t.Fatalf("Put() = %+v err = %v, want the task with its version", restored, err)
+	}
+	if got, ok := ts.Get(7); !ok || got.Title != "old" || got.Version != 3 {
+		t.Fatalf("Get() = %+v ok = %v, want the put task", got, ok)
+	}
+	if _, err := ts.Put(restored, false); !errors.Is(err, store.ErrExists) {
+		t.Fatalf("Put(twice) err = %v, want %v", err, store.ErrExists)
+	}
+	restored.Title = "replaced"
+	if replaced, err := ts.Put(restored, true); err != nil || replaced.Title != "replaced" {
+		t.Fatalf("Put(replace) = %+v err = %v, want the new task", replaced, err)
+	}
+
+	// new ids continue after the stored one
+	created, _ := ts.Create(domain.Task{Title: "new"})
+	if created.ID != 8 {
+		t.Fatalf("Create() id = %d after Put, want 8", created.ID)
+	}
+	if _, err := ts.Put(domain.Task{ID: created.ID, Status: domain.StatusDone}, true); !errors.Is(err, store.ErrNotFinished) {
+		t.Fatalf("Put(replace pending) err = %v, want %v", err, store.ErrNotFinished)
+	}
+	if renumbered, _ := ts.Put(domain.Task{Status: domain.StatusDone}, false); renumbered.ID != 9 {
+		t.Fatalf("Put(id 0) id = %d, want 9", renumbered.ID)
+	}
+
+	ts.ReserveIDs(20)
+	ts.ReserveIDs(10) // never goes back
+	if ts.LastID() != 20 {
+		t.Fatalf("LastID() = %d after ReserveIDs(20), want 20", ts.LastID())
+	}
+	if page, _ := ts.ListAfter(7, 10); len(page) != 2 || page[0].ID != 8 || page[1].ID != 9 {
+		t.Fatalf("ListAfter(7) = %+v, want tasks 8 and 9", page)
+	}
+	if created, _ := ts.Create(domain.Task{Title: "new"}); created.ID != 21 {
+		t.Fatalf("Create() id = %d after ReserveIDs(20), want 21", created.ID)
+	}
+}
+
+func testFind(t *testing.T, ts store.Backend) {
+	start := time.Now()
+	var ids []int64
+	for i, owner := range []string{"alice", "bob", "alice", "alice"} {
+		created, _ := ts.Create(domain.Task{Title: "t", Owner: owner, CreatedAt: start.Add(time.Duration(i) * time.Minute)})
+		ids = append(ids, created.ID)
+	}
+	_, _ = ts.UpdateStatus(ids[2], domain.StatusDone)
+
+	for _, tc := range []struct {
+		filter store.Filter
+		want   []int64
+	}{
+		{store.Filter{}, ids},
+		{store.Filter{Owner: "alice"}, []int64{ids[0], ids[2], ids[3]}},
+		{store.Filter{Owner: "nobody"}, []int64{}},
+		{store.Filter{Owner: "alice", Status: domain.StatusPending}, []int64{ids[0], ids[3]}},
+		{store.Filter{CreatedFrom: start.Add(time.Minute), CreatedTo: start.Add(3 * time.Minute)}, []int64{ids[1], ids[2]}},
+		{store.Filter{Owner: "alice", Limit: 2}, []int64{ids[0], ids[2]}},
+		{store.Filter{Owner: "alice", After: ids[2], Limit: 2}, []int64{ids[3]}},
+	} {
+		got, err := ts.Find(tc.filter)
+		if err != nil || got == nil || !reflect.DeepEqual(taskIDs(got), tc.want) {
+			t.Fatalf("Find(%+v) = %v err = %v, want %v", tc.filter, taskIDs(got), err, tc.want)
+		}
+	}
+}
+
+func testPing(t *testing.T, ts store.Backend) {
+	if err := ts.Ping(t.Context()); err != nil {
+		t.Fatalf("Ping() err = %v, want nil", err)
+	}
+}
+
+func testConcurrentCreate(t *testing.T, ts store.Backend) {
+	const n = 100
+	ids := make([]int64, n)
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			created, _ := ts.Create(domain.Task{Title: "x"})
+			ids[i] = created.ID
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool)
+	for _, id := range ids {
+		if id <= 0 || seen[id] {
+			t.Fatalf("Create() ids = %v, want %d distinct ids", ids, n)
+		}
+		seen[id] = true
+	}
+	if list, _ := ts.List(); len(list) != n {
+		t.Fatalf("List() len = %d, want %d", len(list), n)
+	}
+}
+
+func testConcurrentSameIdempotencyKey(t *testing.T, ts store.Backend) {
+	opts := store.CreateOptions{IdempotencyKey: "k1", Fingerprint: "fp", KeyTTL: time.Minute}
+
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			_, _ = ts.CreateWith(domain.Task{Title: "x"}, opts)
+		}()
+	}
+	wg.Wait()
+
+	if list, _ := ts.List(); len(list) != 1 {
+		t.Fatalf("List() len = %d, want 1", len(list))
+	}
+}
+
+func testConcurrentSameDedupeKey(t *testing.T, ts store.Backend) {
+	const n = 50
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			if _, err := ts.Create(domain.Task{Title: "x", DedupeKey: "job"}); err == nil {
+				created.Add(1)
+			} else if !errors.Is(err, store.ErrDuplicate) {
+				t.Errorf("Create() err = %v, want nil or %v", err, store.ErrDuplicate)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if created.Load() != 1 {
+		t.Fatalf("created %d tasks with the same dedupe key, want 1", created.Load())
+	}
+}
+
+func testConcurrentQuota(t *testing.T, ts store.Backend) {
+	const n, quota = 30, 5
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			_, _ = ts.CreateWith(domain.Task{Title: "x", Owner: "alice"}, store.CreateOptions{MaxActive: quota})
+		}()
+	}
+	wg.Wait()
+
+	if got := ts.ActiveCount("alice"); got != quota {
+		t.Fatalf("ActiveCount() = %d, want %d", got, quota)
+	}
+}
+
+func testConcurrentBatchQuota(t *testing.T, ts store.Backend) {
+	const n, quota = 10, 5
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			tasks := []domain.Task{{Title: "x", Owner: "alice"}, {Title: "y", Owner: "alice"}}
+			_, _, _ = ts.CreateBatch("", tasks, store.DedupeReject, i%2 == 0, quota)
+		}()
+	}
+	wg.Wait()
+
+	if got := ts.ActiveCount("alice"); got != quota {
+		t.Fatalf("ActiveCount() = %d, want %d", got, quota)
+	}
+}
+
+// of many clients acting on the same version exactly one wins
+func testConcurrentCompareAndSwap(t *testing.T, ts store.Backend) {
+	created, _ := ts.Create(domain.Task{Title: "t"})
+
+	const n = 20
+	var won atomic.Int32
+	var wg sync.WaitGroup
+	wg.Add(2 * n)
+	for i := 0; i < n; i++ {
+		title := "edit"
+		go func() {
+			defer wg.Done()
+			if _, err := ts.Update(created.ID, 1, store.TaskPatch{Title: &title}); err == nil {
+				won.Add(1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := ts.Cancel(created.ID, 1, "stop"); err == nil {
+				won.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if won.Load() != 1 {
+		t.Fatalf("%d writes won on version 1, want 1", won.Load())
+	}
+	if got, _ := ts.Get(created.ID); got.Version != 2 {
+		t.Fatalf("Get() version = %d, want 2", got.Version)
+	}
+}
+
+func containsID(tasks []domain.Task, id int64) bool {
+	for _, t := range tasks {
+		if t.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func taskIDs(tasks []domain.Task) []int64 {
+	ids := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
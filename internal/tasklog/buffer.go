@@ -0,0 +1,139 @@
+package tasklog
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxLines     = 1000
+	DefaultMaxBytes     = 256 << 10
+	DefaultMaxLineBytes = 4 << 10
+)
+
+// Limits caps what is kept per task. When a buffer is over MaxLines or MaxBytes
+// the oldest lines are dropped, a line longer than MaxLineBytes is cut.
+type Limits struct {
+	MaxLines     int
+	MaxBytes     int
+	MaxLineBytes int
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxLines <= 0 {
+		l.MaxLines = DefaultMaxLines
+	}
+	if l.MaxBytes <= 0 {
+		l.MaxBytes = DefaultMaxBytes
+	}
+	if l.MaxLineBytes <= 0 {
+		l.MaxLineBytes = DefaultMaxLineBytes
+	}
+	return l
+}
+
+type Entry struct {
+	Seq       int64 // 1-based position in the task's log, kept when older lines are dropped
+	Time      time.Time
+	Level     string
+	Message   string
+	Attrs     map[string]any
+	Truncated bool // Message was cut to MaxLineBytes
+}
+
+func (e Entry) size() int {
+	n := len(e.Message) + len(e.Level)
+	for k, v := range e.Attrs {
+		n += len(k)
+		if s, ok := v.(string); ok {
+			n += len(s)
+		} else {
+			n += 8
+		}
+	}
+	return n
+}
+
+// Buffer holds the log of one task execution.
+type Buffer struct {
+	limits Limits
+
+	mu      sync.Mutex
+	entries []Entry
+	bytes   int
+	nextSeq int64
+	dropped int
+	closed  bool
+	changed chan struct{} // closed and replaced on every append and on Close
+}
+
+func newBuffer(limits Limits) *Buffer {
+	return &Buffer{limits: limits, nextSeq: 1, changed: make(chan struct{})}
+}
+
+// Append adds a line, it is ignored once the buffer is closed.
+func (b *Buffer) Append(e Entry) {
+	if len(e.Message) > b.limits.MaxLineBytes {
+		e.Message = e.Message[:b.limits.MaxLineBytes]
+		e.Truncated = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	e.Seq = b.nextSeq
+	b.nextSeq++
+	b.entries = append(b.entries, e)
+	b.bytes += e.size()
+
+	for len(b.entries) > 1 && (len(b.entries) > b.limits.MaxLines || b.bytes > b.limits.MaxBytes) {
+		b.bytes -= b.entries[0].size()
+		b.entries[0] = Entry{}
+		b.entries = b.entries[1:]
+		b.dropped++
+	}
+
+	b.notify()
+}
+
+// Close marks the execution as finished, followers stop after the last line.
+func (b *Buffer) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		b.notify()
+	}
+}
+
+func (b *Buffer) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+type Snapshot struct {
+	Entries []Entry
+	Dropped int  // lines dropped to stay within the limits
+	Closed  bool // the execution has finished
+}
+
+// Since returns the lines after seq (0 for all of them), and a channel that is
+// closed on the next change.
+func (b *Buffer) Since(seq int64) (Snapshot, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var entries []Entry
+	for i := range b.entries {
+		if b.entries[i].Seq > seq {
+			entries = append(entries, b.entries[i:]...)
+			break
+		}
+	}
+	return Snapshot{Entries: entries, Dropped: b.dropped, Closed: b.closed}, b.changed
+}
@@ -0,0 +1,94 @@
+package tasklog
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewLogger returns a logger whose records go into b. It is handed to the
+// executor of a task, so what the task logs can be read back over the API.
+func NewLogger(b *Buffer) *slog.Logger {
+	return slog.New(&handler{buf: b})
+}
+
+type handler struct {
+	buf    *Buffer
+	attrs  []slog.Attr // from WithAttrs, already prefixed with their group
+	prefix string      // open groups, "a.b."
+}
+
+func (h *handler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *handler) Handle(_ context.Context, r slog.Record) error {
+	attrs := make(map[string]any, len(h.attrs)+r.NumAttrs())
+	for _, a := range h.attrs {
+		addAttr(attrs, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(attrs, h.prefix, a)
+		return true
+	})
+	if len(attrs) == 0 {
+		attrs = nil
+	}
+
+	h.buf.Append(Entry{
+		Time:    r.Time,
+		Level:   r.Level.String(),
+		Message: r.Message,
+		Attrs:   attrs,
+	})
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		next.attrs = append(next.attrs, a)
+	}
+	return &next
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+// addAttr flattens groups into dotted keys.
+func addAttr(dst map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		for _, g := range v.Group() {
+			addAttr(dst, p, g)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+
+	switch v.Kind() {
+	case slog.KindDuration:
+		dst[prefix+a.Key] = v.Duration().String()
+	case slog.KindTime:
+		dst[prefix+a.Key] = v.Time()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			dst[prefix+a.Key] = err.Error()
+			return
+		}
+		dst[prefix+a.Key] = v.Any()
+	default:
+		dst[prefix+a.Key] = v.Any()
+	}
+}
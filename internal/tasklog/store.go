@@ -0,0 +1,49 @@
+package tasklog
+
+import "sync"
+
+// Store keeps the execution log of each task, in memory next to the task store.
+type Store struct {
+	limits Limits
+
+	mu      sync.Mutex
+	buffers map[int64]*Buffer
+}
+
+func NewStore(limits Limits) *Store {
+	return &Store{limits: limits.withDefaults(), buffers: make(map[int64]*Buffer)}
+}
+
+// Buffer returns the log of task id, creating an empty one on first use.
+func (s *Store) Buffer(id int64) *Buffer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buffers[id]
+	if !ok {
+		b = newBuffer(s.limits)
+		s.buffers[id] = b
+	}
+	return b
+}
+
+// Lookup returns the log of task id if it has one.
+func (s *Store) Lookup(id int64) (*Buffer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buffers[id]
+	return b, ok
+}
+
+// Delete drops the log of task id, followers see it as closed.
+func (s *Store) Delete(id int64) {
+	s.mu.Lock()
+	b, ok := s.buffers[id]
+	delete(s.buffers, id)
+	s.mu.Unlock()
+
+	if ok {
+		b.Close()
+	}
+}
@@ -0,0 +1,106 @@
+package tasklog
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuffer_DropsOldestOverLimits(t *testing.T) {
+	s := NewStore(Limits{MaxLines: 3, MaxLineBytes: 5})
+	b := s.Buffer(1)
+
+	for _, msg := range []string{"one", "two", "three", "four", "fivesix"} {
+		b.Append(Entry{Message: msg})
+	}
+
+	snap, _ := b.Since(0)
+	if len(snap.Entries) != 3 || snap.Dropped != 2 {
+		t.Fatalf("entries=%d dropped=%d, want 3/2", len(snap.Entries), snap.Dropped)
+	}
+	if snap.Entries[0].Seq != 3 || snap.Entries[0].Message != "three" {
+		t.Fatalf("first entry=%+v, want seq 3 three", snap.Entries[0])
+	}
+	if last := snap.Entries[2]; last.Message != "fives" || !last.Truncated {
+		t.Fatalf("last entry=%+v, want truncated to 5 bytes", last)
+	}
+
+	// only the lines after seq
+	snap, _ = b.Since(4)
+	if len(snap.Entries) != 1 || snap.Entries[0].Seq != 5 {
+		t.Fatalf("Since(4)=%+v, want seq 5 only", snap.Entries)
+	}
+}
+
+func TestBuffer_MaxBytes(t *testing.T) {
+	b := NewStore(Limits{MaxBytes: 20}).Buffer(1)
+
+	b.Append(Entry{Message: strings.Repeat("a", 10)})
+	b.Append(Entry{Message: strings.Repeat("b", 10)})
+	b.Append(Entry{Message: strings.Repeat("c", 10)})
+
+	snap, _ := b.Since(0)
+	if snap.Dropped == 0 || snap.Entries[len(snap.Entries)-1].Message[0] != 'c' {
+		t.Fatalf("snapshot=%+v, want oldest dropped and newest kept", snap)
+	}
+}
+
+func TestBuffer_CloseNotifiesAndStopsAppends(t *testing.T) {
+	b := NewStore(Limits{}).Buffer(1)
+
+	_, changed := b.Since(0)
+	b.Append(Entry{Message: "x"})
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatalf("append did not notify")
+	}
+
+	_, changed = b.Since(1)
+	b.Close()
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatalf("close did not notify")
+	}
+
+	b.Append(Entry{Message: "late"})
+	snap, _ := b.Since(0)
+	if !snap.Closed || len(snap.Entries) != 1 {
+		t.Fatalf("snapshot=%+v, want closed with 1 entry", snap)
+	}
+}
+
+func TestLogger_CapturesAttrs(t *testing.T) {
+	s := NewStore(Limits{})
+	logger := NewLogger(s.Buffer(7)).With("step", 1).WithGroup("http")
+
+	logger.Warn("request failed", "status", 502, "error", errors.New("bad gateway"), slog.Duration("took", time.Second))
+
+	b, ok := s.Lookup(7)
+	if !ok {
+		t.Fatalf("Lookup(7) ok=false, want true")
+	}
+	snap, _ := b.Since(0)
+	if len(snap.Entries) != 1 {
+		t.Fatalf("entries=%d, want 1", len(snap.Entries))
+	}
+
+	e := snap.Entries[0]
+	if e.Level != "WARN" || e.Message != "request failed" {
+		t.Fatalf("entry=%+v", e)
+	}
+	want := map[string]any{"step": int64(1), "http.status": int64(502), "http.error": "bad gateway", "http.took": "1s"}
+	for k, v := range want {
+		if e.Attrs[k] != v {
+			t.Fatalf("attrs[%q]=%v (%T), want %v; attrs=%v", k, e.Attrs[k], e.Attrs[k], v, e.Attrs)
+		}
+	}
+
+	s.Delete(7)
+	if _, ok := s.Lookup(7); ok {
+		t.Fatalf("Lookup(7) after Delete ok=true, want false")
+	}
+}
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"net/http"
+)
+
+// Middleware starts a server span per request, continuing the trace of an
+// incoming W3C traceparent header. The span is named after the matched route.
+func Middleware(t *Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if t == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if sc, err := ParseTraceparent(r.Header.Get("traceparent")); err == nil {
+				ctx = ContextWithRemote(ctx, sc)
+			}
+
+			ctx, span := t.Start(ctx, "HTTP "+r.Method, WithKind(KindServer), WithAttributes(
+				String("http.method", r.Method),
+				String("http.target", r.URL.Path),
+			))
+			defer span.End()
+
+			w.Header().Set("traceparent", span.SpanContext().Traceparent())
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			r = r.WithContext(ctx)
+			next.ServeHTTP(sw, r)
+
+			if r.Pattern != "" {
+				span.SetName("HTTP " + r.Pattern)
+				span.SetAttributes(String("http.route", r.Pattern))
+			}
+			span.SetAttributes(Int("http.status_code", int64(sw.status)))
+			if sw.status >= http.StatusInternalServerError {
+				span.RecordError(http.ErrAbortHandler)
+			}
+		})
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
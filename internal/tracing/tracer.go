@@ -0,0 +1,223 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type SpanKind string
+
+const (
+	KindInternal SpanKind = "internal"
+	KindServer   SpanKind = "server"
+	KindConsumer SpanKind = "consumer"
+)
+
+type Attr struct {
+	Key   string
+	Value any // string, int64, bool or float64
+}
+
+func String(key, value string) Attr    { return Attr{Key: key, Value: value} }
+func Int(key string, value int64) Attr { return Attr{Key: key, Value: value} }
+func Bool(key string, value bool) Attr { return Attr{Key: key, Value: value} }
+
+// SpanData is a finished span, as handed to the exporter.
+type SpanData struct {
+	SpanContext SpanContext
+	Parent      SpanID
+	Name        string
+	Kind        SpanKind
+	Start       time.Time
+	End         time.Time
+	Attrs       []Attr
+	Links       []SpanContext
+	Error       string
+}
+
+type Exporter interface {
+	Export(span SpanData)
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer creates spans and hands the sampled ones to its exporter.
+// A nil *Tracer is valid and creates no spans.
+type Tracer struct {
+	service  string
+	exporter Exporter
+}
+
+func NewTracer(service string, exporter Exporter) *Tracer {
+	return &Tracer{service: service, exporter: exporter}
+}
+
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil || t.exporter == nil {
+		return nil
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+type spanConfig struct {
+	parent    SpanContext
+	hasParent bool
+	start     time.Time
+	kind      SpanKind
+	attrs     []Attr
+	links     []SpanContext
+}
+
+type SpanOption func(*spanConfig)
+
+// WithParent overrides the parent taken from the context, e.g. a span context
+// stored on a task and picked up by a worker.
+func WithParent(sc SpanContext) SpanOption {
+	return func(c *spanConfig) {
+		c.parent = sc
+		c.hasParent = true
+	}
+}
+
+func WithStartTime(t time.Time) SpanOption {
+	return func(c *spanConfig) { c.start = t }
+}
+
+func WithKind(kind SpanKind) SpanOption {
+	return func(c *spanConfig) { c.kind = kind }
+}
+
+func WithAttributes(attrs ...Attr) SpanOption {
+	return func(c *spanConfig) { c.attrs = append(c.attrs, attrs...) }
+}
+
+func WithLinks(links ...SpanContext) SpanOption {
+	return func(c *spanConfig) { c.links = append(c.links, links...) }
+}
+
+// Start starts a span, child of the span in ctx unless WithParent says otherwise.
+func (t *Tracer) Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	cfg := spanConfig{kind: KindInternal}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if !cfg.hasParent {
+		cfg.parent = SpanContextFromContext(ctx)
+	}
+	if cfg.start.IsZero() {
+		cfg.start = time.Now()
+	}
+
+	sc := SpanContext{SpanID: newSpanID(), Sampled: true}
+	var parent SpanID
+	if cfg.parent.IsValid() {
+		sc.TraceID = cfg.parent.TraceID
+		sc.Sampled = cfg.parent.Sampled
+		parent = cfg.parent.SpanID
+	} else {
+		sc.TraceID = newTraceID()
+	}
+
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			SpanContext: sc,
+			Parent:      parent,
+			Name:        name,
+			Kind:        cfg.kind,
+			Start:       cfg.start,
+			Attrs:       append([]Attr{String("service.name", t.service)}, cfg.attrs...),
+			Links:       cfg.links,
+		},
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// Span is an operation in progress. All methods are safe on a nil *Span.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Name = name
+	s.mu.Unlock()
+}
+
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Attrs = append(s.data.Attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed, a nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span, only the first call counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.SpanContext.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(data)
+	}
+}
+
+type spanKey struct{}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// ContextWithRemote carries a span context parsed from an incoming request.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, &Span{data: SpanData{SpanContext: sc}, ended: true})
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	return SpanFromContext(ctx).SpanContext()
+}
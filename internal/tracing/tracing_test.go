@@ -0,0 +1,202 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingExporter) Export(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error { return nil }
+
+func (e *recordingExporter) byName(t *testing.T, name string) SpanData {
+	t.Helper()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range e.spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("no span named %q in %+v", name, e.spans)
+	return SpanData{}
+}
+
+func TestTraceparent_RoundTrip(t *testing.T) {
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc, err := ParseTraceparent(header)
+	if err != nil {
+		t.Fatalf("ParseTraceparent() err=%v, want nil", err)
+	}
+	if !sc.Sampled || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("ParseTraceparent()=%+v", sc)
+	}
+	if got := sc.Traceparent(); got != header {
+		t.Fatalf("Traceparent()=%q, want %q", got, header)
+	}
+
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(bad); !errors.Is(err, ErrInvalidTraceparent) {
+			t.Fatalf("ParseTraceparent(%q) err=%v, want %v", bad, err, ErrInvalidTraceparent)
+		}
+	}
+}
+
+func TestTracer_ChildSpansShareTrace(t *testing.T) {
+	exp := &recordingExporter{}
+	tracer := NewTracer("test", exp)
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child", WithAttributes(Int("task.id", 7)))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	root.End()
+	root.End() // only the first End counts
+
+	if len(exp.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(exp.spans))
+	}
+	r, c := exp.byName(t, "root"), exp.byName(t, "child")
+	if r.Parent.IsValid() {
+		t.Fatalf("root parent=%s, want none", r.Parent)
+	}
+	if c.SpanContext.TraceID != r.SpanContext.TraceID || c.Parent != r.SpanContext.SpanID {
+		t.Fatalf("child=%+v is not a child of root=%+v", c.SpanContext, r.SpanContext)
+	}
+	if c.Error != "boom" {
+		t.Fatalf("child error=%q, want boom", c.Error)
+	}
+}
+
+func TestTracer_Nil_IsNoop(t *testing.T) {
+	var tracer *Tracer
+
+	ctx, span := tracer.Start(context.Background(), "x")
+	span.SetAttributes(String("k", "v"))
+	span.End()
+
+	if SpanContextFromContext(ctx).IsValid() {
+		t.Fatalf("nil tracer put a span in the context")
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() err=%v, want nil", err)
+	}
+}
+
+func TestWriterExporter_WritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer("test", NewWriterExporter(&buf))
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child", WithAttributes(String("k", "v")))
+	child.End()
+	root.End()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+
+	var got jsonSpan
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("line is not JSON: %v", err)
+	}
+	if got.Name != "child" || got.ParentID != root.SpanContext().SpanID.String() || got.Attributes["k"] != "v" {
+		t.Fatalf("child line=%+v", got)
+	}
+}
+
+func TestOTLPExporter_PostsToCollector(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		path string
+		body map[string]any
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		path = r.URL.Path
+		_ = json.Unmarshal(raw, &body)
+	}))
+	defer collector.Close()
+
+	tracer := NewTracer("svc", NewOTLPExporter(collector.URL, "svc"))
+	_, span := tracer.Start(context.Background(), "op", WithKind(KindServer), WithAttributes(Int("n", 3)))
+	span.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() err=%v, want nil", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if path != "/v1/traces" {
+		t.Fatalf("path=%q, want /v1/traces", path)
+	}
+
+	// resourceSpans[0].scopeSpans[0].spans[0]
+	rs := body["resourceSpans"].([]any)[0].(map[string]any)
+	ss := rs["scopeSpans"].([]any)[0].(map[string]any)
+	got := ss["spans"].([]any)[0].(map[string]any)
+
+	if got["name"] != "op" || got["traceId"] != span.SpanContext().TraceID.String() || got["kind"] != float64(2) {
+		t.Fatalf("span=%v", got)
+	}
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	exp := &recordingExporter{}
+	tracer := NewTracer("test", exp)
+
+	var inHandler SpanContext
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		inHandler = SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h := Middleware(tracer)(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/tasks/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	span := exp.byName(t, "HTTP GET /tasks/{id}")
+	if span.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.String() != "00f067aa0ba902b7" {
+		t.Fatalf("server span=%+v parent=%s, want remote parent", span.SpanContext, span.Parent)
+	}
+	if inHandler != span.SpanContext {
+		t.Fatalf("handler context=%+v, want server span %+v", inHandler, span.SpanContext)
+	}
+	if got := rr.Header().Get("traceparent"); got != span.SpanContext.Traceparent() {
+		t.Fatalf("traceparent header=%q, want %q", got, span.SpanContext.Traceparent())
+	}
+}
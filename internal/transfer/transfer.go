@@ -0,0 +1,304 @@
+// Package transfer moves the whole task store out and back in as NDJSON, e.g.
+// to migrate between store backends or to reproduce a bug with real data.
+//
+// A stream starts with a Header line carrying the id state, then one
+// archive.Record per task by ascending id. Archive files have no header and
+// can be imported as they are.
+package transfer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"interview-task-worker-pool/internal/archive"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/store"
+	"io"
+	"time"
+)
+
+const (
+	Format = "tasks/v1"
+
+	pageSize     = 500
+	maxLineBytes = 4 << 20
+	maxErrors    = 100 // line errors listed in a Report, the rest is only counted
+
+	// MaxID is the highest id or last_id an import takes. The store continues
+	// after imported ids, and JSON clients reading ids as doubles lose them above.
+	MaxID = 1 << 53
+)
+
+type Header struct {
+	Format     string    `json:"format"`
+	LastID     int64     `json:"last_id"` // highest id the source handed out, deleted tasks included
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// Source is a store that can be exported.
+type Source interface {
+	// ListAfter returns up to limit tasks with an id above after, by id.
+	ListAfter(after int64, limit int) ([]domain.Task, error)
+	LastID() int64
+}
+
+// Export writes the header and every task of src. Tasks are read a page at a
+// time, the store isn't locked for the whole export. It returns the number of
+// tasks written.
+func Export(w io.Writer, src Source) (int, error) {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(Header{Format: Format, LastID: src.LastID(), ExportedAt: time.Now().UTC()}); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	var after int64
+	for {
+		page, err := src.ListAfter(after, pageSize)
+		if err != nil {
+			return n, err
+		}
+		if len(page) == 0 {
+			return n, nil
+		}
+		for _, task := range page {
+			if err := enc.Encode(archive.NewRecord(task)); err != nil {
+				return n, err
+			}
+			after = task.ID
+			n++
+		}
+	}
+}
+
+// Policy decides what happens to an imported task whose id is taken.
+type Policy string
+
+const (
+	PolicySkip      Policy = "skip"      // keep the stored task
+	PolicyOverwrite Policy = "overwrite" // replace it, only finished tasks can be replaced
+	PolicyRenumber  Policy = "renumber"  // import the task under a new id
+)
+
+func (p Policy) Valid() bool {
+	return p == PolicySkip || p == PolicyOverwrite || p == PolicyRenumber
+}
+
+// Target is a store that can be imported into.
+type Target interface {
+	Get(id int64) (domain.Task, bool)
+	// Put stores a finished task under its id, a new one when the id is 0.
+	Put(task domain.Task, replace bool) (domain.Task, error)
+	ReserveIDs(id int64)
+	LastID() int64
+}
+
+type Options struct {
+	Policy Policy // PolicySkip when empty
+	DryRun bool   // validate and report, write nothing
+}
+
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Report tells what an import did, or would do on a dry run.
+type Report struct {
+	DryRun      bool            `json:"dry_run"`
+	Created     int             `json:"created"`
+	Overwritten int             `json:"overwritten"`
+	Renumbered  map[int64]int64 `json:"renumbered"` // imported id -> new id
+	Skipped     int             `json:"skipped"`
+	Interrupted int             `json:"interrupted"` // unfinished in the stream, imported canceled
+	Failed      int             `json:"failed"`
+	Errors      []LineError     `json:"errors"`  // the first 100 failed lines
+	LastID      int64           `json:"last_id"` // of the store after the import
+}
+
+func (r *Report) fail(line int, err error) {
+	r.Failed++
+	if len(r.Errors) < maxErrors {
+		r.Errors = append(r.Errors, LineError{Line: line, Error: err.Error()})
+	}
+}
+
+// line is either the header or a task record.
+type line struct {
+	archive.Record
+	Format string `json:"format"`
+	LastID int64  `json:"last_id"`
+}
+
+// Import loads the stream into dst. Lines that can't be imported are
+// reported and skipped, the error is only for a stream that can't be read.
+//
+// Unfinished tasks are imported canceled: nothing would run them, the pool
+// only picks up tasks submitted through the service.
+func Import(dst Target, r io.Reader, opts Options) (Report, error) {
+	if opts.Policy == "" {
+		opts.Policy = PolicySkip
+	}
+	if !opts.Policy.Valid() {
+		return Report{}, fmt.Errorf("unknown policy %q", opts.Policy)
+	}
+
+	im := importer{
+		dst:        dst,
+		opts:       opts,
+		lastID:     dst.LastID(),
+		seen:       make(map[int64]bool),
+		renumbered: make(map[int64]int64),
+	}
+	im.report.DryRun = opts.DryRun
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxLineBytes)
+	for n := 1; sc.Scan(); n++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		if err := im.line(n, sc.Bytes()); err != nil {
+			im.report.fail(n, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return im.report, err
+	}
+
+	if !opts.DryRun {
+		im.lastID = dst.LastID()
+	}
+	im.report.Renumbered = im.renumbered
+	im.report.LastID = im.lastID
+	if im.report.Errors == nil {
+		im.report.Errors = []LineError{}
+	}
+	return im.report, nil
+}
+
+type importer struct {
+	dst    Target
+	opts   Options
+	report Report
+
+	lastID     int64          // the store's, simulated on a dry run
+	seen       map[int64]bool // ids imported so far, the dry run's store doesn't have them
+	renumbered map[int64]int64
+	tasks      bool // a task line was read, a header must come first
+}
+
+var errHeaderNotFirst = errors.New("header after the first task")
+
+func (im *importer) line(n int, data []byte) error {
+	var l line
+	if err := json.Unmarshal(data, &l); err != nil {
+		return err
+	}
+
+	if l.Format != "" {
+		if l.Format != Format {
+			return fmt.Errorf("unknown format %q", l.Format)
+		}
+		if im.tasks {
+			return errHeaderNotFirst
+		}
+		if l.LastID < 0 || l.LastID > MaxID {
+			return fmt.Errorf("last_id %d out of range", l.LastID)
+		}
+		// ids of deleted tasks aren't reused after the import either
+		if !im.opts.DryRun {
+			im.dst.ReserveIDs(l.LastID)
+		}
+		im.lastID = max(im.lastID, l.LastID)
+		return nil
+	}
+	im.tasks = true
+
+	task, err := im.task(l.Record)
+	if err != nil {
+		return err
+	}
+	return im.put(task)
+}
+
+// task validates the record and turns it into the task to store.
+func (im *importer) task(rec archive.Record) (domain.Task, error) {
+	task := rec.Task()
+	if task.ID <= 0 || task.ID > MaxID {
+		return domain.Task{}, fmt.Errorf("invalid id %d", task.ID)
+	}
+	if task.Title == "" {
+		return domain.Task{}, fmt.Errorf("task %d has no title", task.ID)
+	}
+
+	switch task.Status {
+	case domain.StatusDone, domain.StatusFailed, domain.StatusCanceled:
+	case domain.StatusBlocked, domain.StatusPending, domain.StatusRunning:
+		task.Status = domain.StatusCanceled
+		task.Error = fmt.Sprintf("was %s when exported", rec.Status)
+		task.FinishedAt = time.Now()
+		im.report.Interrupted++
+	default:
+		return domain.Task{}, fmt.Errorf("task %d has unknown status %q", task.ID, rec.Status)
+	}
+
+	// dependencies imported under a new id point to it
+	for i, dep := range task.DependsOn {
+		if id, ok := im.renumbered[dep]; ok {
+			task.DependsOn[i] = id
+		}
+	}
+	return task, nil
+}
+
+func (im *importer) put(task domain.Task) error {
+	oldID := task.ID
+	replace := false
+
+	existing, exists := im.dst.Get(task.ID)
+	if !exists && im.seen[task.ID] {
+		existing, exists = domain.Task{Status: domain.StatusDone}, true
+	}
+	if exists {
+		switch im.opts.Policy {
+		case PolicySkip:
+			im.report.Skipped++
+			return nil
+		case PolicyOverwrite:
+			if !existing.Status.Terminal() {
+				return fmt.Errorf("task %d: %w", task.ID, store.ErrNotFinished)
+			}
+			replace = true
+		case PolicyRenumber:
+			task.ID = 0
+		}
+	}
+
+	if im.opts.DryRun {
+		if task.ID == 0 {
+			im.lastID++
+			task.ID = im.lastID
+		}
+	} else {
+		stored, err := im.dst.Put(task, replace)
+		if err != nil {
+			return fmt.Errorf("task %d: %w", oldID, err)
+		}
+		task = stored
+	}
+	im.seen[task.ID] = true
+	im.lastID = max(im.lastID, task.ID)
+
+	switch {
+	case replace:
+		im.report.Overwritten++
+	case task.ID != oldID:
+		im.renumbered[oldID] = task.ID
+		im.report.Created++
+	default:
+		im.report.Created++
+	}
+	return nil
+}
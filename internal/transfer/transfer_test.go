@@ -0,0 +1,186 @@
+package transfer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"interview-task-worker-pool/internal/archive"
+	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/store/memory"
+	"interview-task-worker-pool/internal/transfer"
+	"strings"
+	"testing"
+	"time"
+)
+
+// source has done tasks 1 and 3 and a pending task 4, task 2 was deleted and 5
+// the last id handed out.
+func source(t *testing.T) *memory.TaskStore {
+	t.Helper()
+	ts := memory.New()
+	for i := 0; i < 5; i++ {
+		created, _ := ts.Create(domain.Task{Title: "t", Metadata: map[string]string{"i": "x"}})
+		if created.ID != 4 {
+			_, _ = ts.UpdateStatus(created.ID, domain.StatusDone)
+		}
+	}
+	_, _ = ts.Delete(2, 0)
+	_, _ = ts.Delete(5, 0)
+	return ts
+}
+
+func export(t *testing.T, src transfer.Source) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := transfer.Export(&buf, src); err != nil {
+		t.Fatalf("Export() err=%v, want nil", err)
+	}
+	return &buf
+}
+
+func TestExport(t *testing.T) {
+	buf := export(t, source(t))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("export has %d lines, want a header and 3 tasks:\n%s", len(lines), buf)
+	}
+
+	var header transfer.Header
+	_ = json.Unmarshal([]byte(lines[0]), &header)
+	if header.Format != transfer.Format || header.LastID != 5 {
+		t.Fatalf("header = %+v, want format %s and last_id 5", header, transfer.Format)
+	}
+	var rec archive.Record
+	_ = json.Unmarshal([]byte(lines[3]), &rec)
+	if rec.ID != 4 || rec.Status != "pending" || rec.Metadata["i"] != "x" {
+		t.Fatalf("last record = %+v, want pending task 4", rec)
+	}
+}
+
+func TestImport_IntoEmptyStoreKeepsIDs(t *testing.T) {
+	dst := memory.New()
+	report, err := transfer.Import(dst, export(t, source(t)), transfer.Options{})
+	if err != nil {
+		t.Fatalf("Import() err=%v, want nil", err)
+	}
+	if report.Created != 3 || report.Interrupted != 1 || report.Failed != 0 || report.LastID != 5 {
+		t.Fatalf("Import() report = %+v, want 3 created, 1 interrupted, last_id 5", report)
+	}
+
+	if task, ok := dst.Get(4); !ok || task.Status != domain.StatusCanceled || task.Error != "was pending when exported" {
+		t.Fatalf("Get(4) = %+v, want the pending task imported canceled", task)
+	}
+	// ids of tasks deleted before the export aren't handed out again
+	if created, _ := dst.Create(domain.Task{Title: "new"}); created.ID != 6 {
+		t.Fatalf("Create() id = %d after import, want 6", created.ID)
+	}
+}
+
+func TestImport_Policies(t *testing.T) {
+	existing := func() *memory.TaskStore {
+		ts := memory.New()
+		created, _ := ts.Create(domain.Task{Title: "live"})
+		_, _ = ts.UpdateStatus(created.ID, domain.StatusFailed)
+		return ts
+	}
+	stream := `{"id":1,"title":"imported","status":"done","version":2}
+{"id":2,"title":"child","status":"done","depends_on":[1]}
+`
+
+	dst := existing()
+	report, _ := transfer.Import(dst, strings.NewReader(stream), transfer.Options{Policy: transfer.PolicySkip})
+	if task, _ := dst.Get(1); report.Skipped != 1 || report.Created != 1 || task.Title != "live" {
+		t.Fatalf("skip: report = %+v task 1 = %+v, want the live task kept", report, task)
+	}
+
+	dst = existing()
+	report, _ = transfer.Import(dst, strings.NewReader(stream), transfer.Options{Policy: transfer.PolicyOverwrite})
+	if task, _ := dst.Get(1); report.Overwritten != 1 || task.Title != "imported" || task.Version != 2 {
+		t.Fatalf("overwrite: report = %+v task 1 = %+v, want the imported task", report, task)
+	}
+
+	dst = existing()
+	report, _ = transfer.Import(dst, strings.NewReader(stream), transfer.Options{Policy: transfer.PolicyRenumber})
+	newID := report.Renumbered[1]
+	if report.Created != 2 || newID != 2 {
+		t.Fatalf("renumber: report = %+v, want task 1 imported as 2", report)
+	}
+	// task 2 of the stream now collides with the renumbered one and points at it
+	child, _ := dst.Get(report.Renumbered[2])
+	if live, _ := dst.Get(1); live.Title != "live" || child.Title != "child" || child.DependsOn[0] != newID {
+		t.Fatalf("renumber: live = %+v child = %+v, want both kept and the dependency remapped", live, child)
+	}
+}
+
+func TestImport_DryRunAndBadLines(t *testing.T) {
+	dst := memory.New()
+	created, _ := dst.Create(domain.Task{Title: "running"})
+	_, _ = dst.UpdateStatus(created.ID, domain.StatusRunning)
+
+	stream := `{"format":"tasks/v1","last_id":10,"exported_at":"2026-10-18T00:00:00Z"}
+{"id":1,"title":"overwrites a running task","status":"done"}
+not json
+{"id":3,"title":"","status":"done"}
+{"id":4,"title":"t","status":"unknown"}
+{"id":5,"title":"ok","status":"done"}
+{"id":5,"title":"again","status":"done"}
+{"format":"tasks/v1","last_id":10}
+`
+	report, err := transfer.Import(dst, strings.NewReader(stream), transfer.Options{Policy: transfer.PolicyOverwrite, DryRun: true})
+	if err != nil {
+		t.Fatalf("Import() err=%v, want nil", err)
+	}
+	if !report.DryRun || report.Created != 1 || report.Overwritten != 1 || report.Failed != 5 || report.LastID != 10 {
+		t.Fatalf("Import(dry run) report = %+v, want 1 created, 1 overwritten, 5 failed, last_id 10", report)
+	}
+	if lines := []int{2, 3, 4, 5, 8}; report.Errors[0].Line != lines[0] || report.Errors[4].Line != lines[4] {
+		t.Fatalf("errors = %+v, want lines %v", report.Errors, lines)
+	}
+
+	// nothing was written
+	if _, ok := dst.Get(5); ok || dst.LastID() != 1 {
+		t.Fatalf("dry run wrote to the store, last id = %d", dst.LastID())
+	}
+
+	if _, err := transfer.Import(dst, strings.NewReader(stream), transfer.Options{Policy: "merge"}); err == nil {
+		t.Fatal("Import(unknown policy) err=nil, want an error")
+	}
+}
+
+func TestImport_IDsAboveMaxID(t *testing.T) {
+	dst := memory.New()
+
+	stream := fmt.Sprintf(`{"format":"tasks/v1","last_id":%d}
+{"id":%d,"title":"far","status":"done"}
+{"id":9223372036854775807,"title":"last","status":"done"}
+{"id":%d,"title":"ok","status":"done"}
+`, int64(transfer.MaxID)+1, int64(transfer.MaxID)+1, int64(transfer.MaxID))
+	report, err := transfer.Import(dst, strings.NewReader(stream), transfer.Options{Policy: transfer.PolicySkip})
+	if err != nil {
+		t.Fatalf("Import() err=%v, want nil", err)
+	}
+	if report.Created != 1 || report.Failed != 3 || report.LastID != transfer.MaxID {
+		t.Fatalf("Import() report = %+v, want 1 created, 3 failed, last_id %d", report, int64(transfer.MaxID))
+	}
+
+	// ids keep going after the highest one taken
+	created, err := dst.Create(domain.Task{Title: "next"})
+	if err != nil || created.ID != transfer.MaxID+1 {
+		t.Fatalf("Create() id = %d err=%v, want %d", created.ID, err, int64(transfer.MaxID)+1)
+	}
+}
+
+func TestImport_ArchiveFile(t *testing.T) {
+	a, _ := archive.Open(t.TempDir())
+	_ = a.Write([]domain.Task{{ID: 7, Title: "archived", Status: domain.StatusDone, FinishedAt: time.Now()}})
+
+	// an archive file decompressed is an export without header
+	var buf bytes.Buffer
+	tasks, _ := a.Search(archive.Query{IDs: []int64{7}})
+	_ = json.NewEncoder(&buf).Encode(archive.NewRecord(tasks[0]))
+
+	dst := memory.New()
+	if report, _ := transfer.Import(dst, &buf, transfer.Options{}); report.Created != 1 || report.LastID != 7 {
+		t.Fatalf("Import(archive) report = %+v, want task 7 created", report)
+	}
+}
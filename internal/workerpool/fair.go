@@ -0,0 +1,208 @@
+package workerpool
+
+import (
+	"sort"
+	"sync"
+)
+
+// tenantQueue holds the queued tasks of one tenant (task owner), FIFO.
+type tenantQueue struct {
+	name    string
+	ids     []int64
+	deficit int // tasks it may still take in the current round
+}
+
+// fairQueue is a bounded queue with a sub-queue per tenant, dequeued with
+// deficit round-robin: each round a tenant takes up to its weight in tasks
+// before the next tenant's turn. One tenant's backlog only delays another
+// tenant's tasks by the weights of the tenants in between.
+type fairQueue struct {
+	mu       sync.Mutex
+	ready    *sync.Cond
+	capacity int
+	size     int
+	reserved int // room held for reservations, counts against capacity
+	closed   bool
+
+	weights map[string]int
+	tenants map[string]*tenantQueue
+	active  []*tenantQueue // tenants with queued tasks, in round order
+	next    int            // index in active whose turn it is
+}
+
+func newFairQueue(capacity int, weights map[string]int) *fairQueue {
+	q := &fairQueue{
+		capacity: capacity,
+		weights:  weights,
+		tenants:  make(map[string]*tenantQueue),
+	}
+	q.ready = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *fairQueue) weight(tenant string) int {
+	if w := q.weights[tenant]; w > 0 {
+		return w
+	}
+	return 1
+}
+
+// push queues id for tenant, it fails when the queue is full or closed.
+// A reserved push uses room held by reserve instead.
+func (q *fairQueue) push(tenant string, id int64, reserved bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if reserved {
+		q.reserved--
+	}
+	if q.closed {
+		return ErrPoolClosed
+	}
+	if !reserved && q.size+q.reserved >= q.capacity {
+		return ErrPoolFull
+	}
+
+	t, ok := q.tenants[tenant]
+	if !ok {
+		t = &tenantQueue{name: tenant}
+		q.tenants[tenant] = t
+		q.active = append(q.active, t)
+	}
+	t.ids = append(t.ids, id)
+	q.size++
+
+	q.ready.Signal()
+	return nil
+}
+
+// reserve holds room for n pushes, all of it or none.
+func (q *fairQueue) reserve(n int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrPoolClosed
+	}
+	if q.size+q.reserved+n > q.capacity {
+		return ErrPoolFull
+	}
+	q.reserved += n
+	return nil
+}
+
+// unreserve gives back room that was reserved and not pushed.
+func (q *fairQueue) unreserve(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reserved -= n
+}
+
+// pop waits for a task and returns the next one in round order. ok is false
+// once the queue is closed and empty.
+func (q *fairQueue) pop() (int64, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.size == 0 {
+		if q.closed {
+			return 0, false
+		}
+		q.ready.Wait()
+	}
+	return q.take(), true
+}
+
+// take removes the next task, caller must hold q.mu and q.size > 0.
+func (q *fairQueue) take() int64 {
+	t := q.active[q.next]
+
+	var id int64
+	id, q.active, q.next = q.advance(q.active, q.next)
+	q.size--
+
+	if len(t.ids) == 0 {
+		delete(q.tenants, t.name)
+	}
+	return id
+}
+
+// advance takes the next id in round order off active and returns the updated
+// round. An idle tenant leaves the round and doesn't keep its credit.
+func (q *fairQueue) advance(active []*tenantQueue, next int) (int64, []*tenantQueue, int) {
+	t := active[next]
+	if t.deficit == 0 {
+		t.deficit = q.weight(t.name)
+	}
+
+	id := t.ids[0]
+	t.ids = t.ids[1:]
+	t.deficit--
+
+	switch {
+	case len(t.ids) == 0:
+		t.deficit = 0
+		active = append(active[:next], active[next+1:]...)
+	case t.deficit == 0:
+		next++
+	}
+	if next >= len(active) {
+		next = 0
+	}
+	return id, active, next
+}
+
+// close wakes up the waiting workers, they drain what is left and stop.
+func (q *fairQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	q.ready.Broadcast()
+}
+
+func (q *fairQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// order lists the queued ids in the order they would be dequeued.
+func (q *fairQueue) order() []int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// dry run of take on copies of the round
+	active := make([]*tenantQueue, len(q.active))
+	for i, t := range q.active {
+		active[i] = &tenantQueue{name: t.name, ids: t.ids, deficit: t.deficit}
+	}
+	next := q.next
+
+	ids := make([]int64, 0, q.size)
+	for len(active) > 0 {
+		var id int64
+		id, active, next = q.advance(active, next)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// TenantStats is the queue depth of a tenant, "" is the tasks without owner.
+type TenantStats struct {
+	Tenant string
+	Queued int
+	Weight int
+}
+
+func (q *fairQueue) stats() []TenantStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := make([]TenantStats, 0, len(q.active))
+	for _, t := range q.active {
+		stats = append(stats, TenantStats{Tenant: t.name, Queued: len(t.ids), Weight: q.weight(t.name)})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Tenant < stats[j].Tenant })
+	return stats
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"errors"
 	"interview-task-worker-pool/internal/domain"
-	"log"
+	"interview-task-worker-pool/internal/logging"
+	"interview-task-worker-pool/internal/tasklog"
+	"interview-task-worker-pool/internal/tracing"
+	"log/slog"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,55 +27,242 @@ type TaskPool interface {
 	Enqueue(id int64) error
 }
 
+// Observer is notified about the pool's work, e.g. to export metrics.
+type Observer interface {
+	TaskEnqueued()
+	TaskRejected(err error)
+	TaskStarted(wait time.Duration)
+	TaskFinished(status domain.TaskStatus, took time.Duration)
+}
+
+type nopObserver struct{}
+
+func (nopObserver) TaskEnqueued()                                 {}
+func (nopObserver) TaskRejected(error)                            {}
+func (nopObserver) TaskStarted(time.Duration)                     {}
+func (nopObserver) TaskFinished(domain.TaskStatus, time.Duration) {}
+
+type Option func(*Pool)
+
+func WithObserver(o Observer) Option {
+	return func(p *Pool) {
+		if o != nil {
+			p.observer = o
+		}
+	}
+}
+
+// WithTracer records a queue wait and an execution span per task, continuing
+// the trace stored on the task when it was created.
+func WithTracer(t *tracing.Tracer) Option {
+	return func(p *Pool) {
+		p.tracer = t
+	}
+}
+
+// Executor does the actual work of a task. What it writes to logger is captured
+// as the task's execution log, a non-nil error fails the task with its message.
+type Executor func(ctx context.Context, task domain.Task, logger *slog.Logger) error
+
+// Simulate is the default executor, it sleeps for the task's WorkDuration.
+func Simulate(ctx context.Context, task domain.Task, logger *slog.Logger) error {
+	logger.Info("simulating work", "planned", task.WorkDuration)
+
+	select {
+	case <-time.After(task.WorkDuration):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	logger.Info("work finished")
+	return nil
+}
+
+func WithExecutor(exec Executor) Option {
+	return func(p *Pool) {
+		if exec != nil {
+			p.executor = exec
+		}
+	}
+}
+
+// WithTaskLogs captures the executor's logger output per task into logs.
+func WithTaskLogs(logs *tasklog.Store) Option {
+	return func(p *Pool) {
+		p.taskLogs = logs
+	}
+}
+
+// WithLogger sets the logger for worker events, slog.Default() otherwise.
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Pool) {
+		if logger != nil {
+			p.logger = logger
+		}
+	}
+}
+
+// WithTenantWeights sets how many tasks a tenant (the task owner) may take per
+// round of the fair queue, tenants not listed have weight 1.
+func WithTenantWeights(weights map[string]int) Option {
+	return func(p *Pool) {
+		p.weights = weights
+	}
+}
+
+// groupKey scopes a concurrency key to the task owner, tenants using the same
+// key don't limit each other.
+type groupKey struct {
+	owner string
+	key   string
+}
+
+// keyGroup tracks the tasks sharing a concurrency key.
+type keyGroup struct {
+	running int
+	waiting []int64 // parked task ids, FIFO
+}
+
+type KeyStats struct {
+	Owner   string
+	Key     string
+	Running int
+	Waiting int
+}
+
 type Pool struct {
-	mu sync.RWMutex
+	queue   *fairQueue
+	weights map[string]int // tenant -> share of the queue, 1 by default
+	store   Store
+
+	keysMu sync.Mutex
+	keys   map[groupKey]*keyGroup
+
+	onFinish []func(id int64, status domain.TaskStatus)
+	observer Observer
+	tracer   *tracing.Tracer
+	logger   *slog.Logger
+	executor Executor
+	taskLogs *tasklog.Store
+
+	enqueuedAt sync.Map // task id -> time.Time, for the queue wait time
+	running    sync.Map // task id -> context.CancelFunc of its execution
 
-	queue chan int64
-	store Store
+	statesMu sync.Mutex
+	states   map[int]WorkerState
+
+	pauseMu sync.Mutex
+	paused  bool
+	resumed chan struct{} // closed by Resume while paused, nil otherwise
+
+	workers atomic.Int64
+	busy    atomic.Int64
 
 	wg        sync.WaitGroup
 	closeOnce sync.Once
 	closed    atomic.Bool
 }
 
-func New(poolSize int, store Store) *Pool {
-	return &Pool{
-		queue: make(chan int64, poolSize),
-		store: store,
+func New(poolSize int, store Store, opts ...Option) *Pool {
+	p := &Pool{
+		store:    store,
+		keys:     make(map[groupKey]*keyGroup),
+		states:   make(map[int]WorkerState),
+		observer: nopObserver{},
+		logger:   slog.Default(),
+		executor: Simulate,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	p.queue = newFairQueue(poolSize, p.weights)
+	return p
+}
+
+// OnFinish registers fn to be called after a worker finished a task.
+// It must be called before Start.
+func (p *Pool) OnFinish(fn func(id int64, status domain.TaskStatus)) {
+	p.onFinish = append(p.onFinish, fn)
 }
 
 func (p *Pool) Start(workers int) {
 	for i := 0; i < workers; i++ {
-		workerID := i + 1
+		workerID := int(p.workers.Add(1))
+		p.statesMu.Lock()
+		p.states[workerID] = WorkerState{ID: workerID}
+		p.statesMu.Unlock()
 		p.wg.Add(1)
 		go p.worker(workerID)
 	}
 }
 
+// Enqueue queues the task in its owner's sub-queue, POOL_SIZE bounds all of
+// them together.
 func (p *Pool) Enqueue(id int64) error {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	return p.enqueue(id, false)
+}
 
-	if p.closed.Load() {
-		return ErrPoolClosed
+func (p *Pool) enqueue(id int64, reserved bool) error {
+	var tenant string
+	if task, ok := p.store.Get(id); ok {
+		tenant = task.Owner
 	}
 
-	select {
-	case p.queue <- id:
-		return nil
-	default:
-		return ErrPoolFull
+	p.enqueuedAt.Store(id, time.Now())
+
+	if err := p.queue.push(tenant, id, reserved); err != nil {
+		p.enqueuedAt.Delete(id)
+		p.observer.TaskRejected(err)
+		return err
 	}
+	p.observer.TaskEnqueued()
+	return nil
 }
 
+// Reservation is room held in the queue, see Pool.Reserve. It is a TaskPool
+// whose Enqueue can't fail with ErrPoolFull.
+type Reservation struct {
+	pool *Pool
+
+	mu   sync.Mutex
+	left int
+}
+
+// Reserve holds room for n tasks in the queue, so a batch can find out whether
+// it fits before creating anything. It fails with ErrPoolFull when there is no
+// room for all of them. The caller must Release the reservation when done.
+func (p *Pool) Reserve(n int) (*Reservation, error) {
+	if err := p.queue.reserve(n); err != nil {
+		return nil, err
+	}
+	return &Reservation{pool: p, left: n}, nil
+}
+
+// Enqueue queues the task in the reserved room, once it is used up like Pool.Enqueue.
+func (r *Reservation) Enqueue(id int64) error {
+	r.mu.Lock()
+	reserved := r.left > 0
+	if reserved {
+		r.left--
+	}
+	r.mu.Unlock()
+
+	return r.pool.enqueue(id, reserved)
+}
+
+// Release gives back the room that wasn't used.
+func (r *Reservation) Release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pool.queue.unreserve(r.left)
+	r.left = 0
+}
+
+// Shutdown stops accepting tasks and waits until the queued ones are done.
+// A paused pool is resumed, shutting down always drains the queue.
 func (p *Pool) Shutdown(ctx context.Context) error {
-	p.closeOnce.Do(func() {
-		p.mu.Lock()
-		p.closed.Store(true)
-		close(p.queue)
-		p.mu.Unlock()
-	})
+	p.Drain()
 
 	done := make(chan struct{})
 	go func() {
@@ -87,46 +278,404 @@ func (p *Pool) Shutdown(ctx context.Context) error {
 	}
 }
 
+// Drain closes the pool without waiting: new tasks get ErrPoolClosed and the
+// workers exit once the queue is empty.
+func (p *Pool) Drain() {
+	p.closeOnce.Do(func() {
+		p.closed.Store(true)
+		p.queue.close()
+	})
+	p.Resume()
+}
+
+// Pause stops the workers from taking tasks off the queue, the running ones finish.
+// A closed pool can't be paused, it has to drain.
+func (p *Pool) Pause() error {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+
+	if p.closed.Load() {
+		return ErrPoolClosed
+	}
+	if !p.paused {
+		p.paused = true
+		p.resumed = make(chan struct{})
+	}
+	return nil
+}
+
+func (p *Pool) Resume() {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+
+	if p.paused {
+		p.paused = false
+		close(p.resumed)
+		p.resumed = nil
+	}
+}
+
+func (p *Pool) Paused() bool {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+	return p.paused
+}
+
+// pauseGate returns a channel that is closed once the pool is resumed, nil when
+// the pool isn't paused.
+func (p *Pool) pauseGate() <-chan struct{} {
+	p.pauseMu.Lock()
+	defer p.pauseMu.Unlock()
+	return p.resumed
+}
+
 func (p *Pool) worker(workerID int) {
 	defer p.wg.Done()
 
-	for id := range p.queue {
-		task, ok := p.store.Get(id)
+	for {
+		if gate := p.pauseGate(); gate != nil {
+			<-gate
+			continue
+		}
+
+		id, ok := p.queue.pop()
 		if !ok {
-			log.Printf("[worker= %d] (taskID= %d) not found.", workerID, id)
+			return
+		}
 
-			continue
+		// paused while this worker was waiting on the queue, hold the task until resumed
+		if gate := p.pauseGate(); gate != nil {
+			<-gate
 		}
-		if task.Status == domain.StatusFailed {
-			log.Printf("[worker= %d] (taskID= %d) skipped failed task.", workerID, id)
+		p.process(workerID, id)
+	}
+}
 
-			continue
+func (p *Pool) process(workerID int, id int64) {
+	task, ok := p.load(workerID, id)
+	if !ok {
+		p.enqueuedAt.Delete(id)
+		return
+	}
+
+	// a task whose key is at its limit is parked instead of holding the worker,
+	// the worker running the same key picks it up when it finishes
+	if task.ConcurrencyKey != "" && !p.acquire(task) {
+		p.taskLogger(workerID, task).Info("task waiting on concurrency key", "concurrency_key", task.ConcurrencyKey)
+
+		return
+	}
+
+	for {
+		p.run(workerID, task)
+
+		if task.ConcurrencyKey == "" {
+			return
+		}
+		if task, ok = p.handoff(workerID, groupKey{owner: task.Owner, key: task.ConcurrencyKey}); !ok {
+			return
+		}
+		// a handed off task starts like a queued one, not while paused
+		if gate := p.pauseGate(); gate != nil {
+			<-gate
 		}
+	}
+}
 
-		if _, err := p.store.UpdateStatus(id, domain.StatusRunning); err != nil {
-			log.Printf("[worker= %d] (taskID= %d) updating status to *RUNNING* failed. (error= %v).", workerID, id, err)
+// load reads a task and tells whether it still has to run.
+func (p *Pool) load(workerID int, id int64) (domain.Task, bool) {
+	task, ok := p.store.Get(id)
+	if !ok {
+		p.logger.Warn("task not found", logging.WorkerID, workerID, logging.TaskID, id)
 
-			continue
+		return domain.Task{}, false
+	}
+	if task.Status == domain.StatusFailed || task.Status == domain.StatusCanceled {
+		p.taskLogger(workerID, task).Info("task skipped", logging.Status, task.Status)
+
+		return domain.Task{}, false
+	}
+	return task, true
+}
+
+// taskLogger carries the attributes every log line about a task has.
+func (p *Pool) taskLogger(workerID int, task domain.Task) *slog.Logger {
+	logger := p.logger.With(logging.WorkerID, workerID, logging.TaskID, task.ID)
+	if task.RequestID != "" {
+		logger = logger.With(logging.RequestID, task.RequestID)
+	}
+	return logger
+}
+
+func (p *Pool) run(workerID int, task domain.Task) {
+	id := task.ID
+	logger := p.taskLogger(workerID, task)
+
+	// the task was created by another request, its trace is carried on the task
+	var parent tracing.SpanContext
+	if sc, err := tracing.ParseTraceparent(task.TraceParent); err == nil {
+		parent = sc
+	}
+	attrs := tracing.WithAttributes(tracing.Int("task.id", id), tracing.Int("worker.id", int64(workerID)))
+
+	var wait time.Duration
+	if at, ok := p.enqueuedAt.LoadAndDelete(id); ok {
+		wait = time.Since(at.(time.Time))
+
+		_, span := p.tracer.Start(context.Background(), "queue.wait", tracing.WithParent(parent), tracing.WithStartTime(at.(time.Time)), attrs)
+		span.End()
+	}
+
+	ctx, span := p.tracer.Start(context.Background(), "task.execute", tracing.WithParent(parent), tracing.WithKind(tracing.KindConsumer), attrs)
+	defer span.End()
+
+	ctx, cancel := context.WithCancel(ctx)
+	p.running.Store(id, cancel)
+	defer func() {
+		p.running.Delete(id)
+		cancel()
+	}()
+
+	p.setState(WorkerState{ID: workerID, TaskID: id, Since: time.Now()})
+	defer p.setState(WorkerState{ID: workerID})
+
+	// the task may have been edited while queued, run what is stored now
+	task, err := p.store.UpdateStatus(id, domain.StatusRunning)
+	if err != nil {
+		logger.Error("updating task status failed", logging.Status, domain.StatusRunning, "error", err)
+		span.RecordError(err)
+		return
+	}
+
+	p.busy.Add(1)
+	defer p.busy.Add(-1)
+
+	p.observer.TaskStarted(wait)
+
+	// time is measured after the point that task has got RUNNING status
+	start := time.Now()
+
+	logger.Info("task started", logging.Status, domain.StatusRunning, "planned", task.WorkDuration, "queue_wait", wait)
+
+	if err := p.execute(ctx, task); err != nil {
+		// canceled (or deleted) while running, the executor stopped because of Abort
+		if current, ok := p.store.Get(id); !ok || current.Status == domain.StatusCanceled {
+			logger.Info("task canceled", logging.Status, domain.StatusCanceled, logging.Duration, time.Since(start))
+			return
 		}
+		p.fail(task, logger, err, time.Since(start))
+		span.RecordError(err)
+		return
+	}
 
-		// time is measured after the point that task has got RUNNING status
-		start := time.Now()
+	if _, err := p.store.UpdateStatus(id, domain.StatusDone); err != nil {
+		logger.Error("updating task status failed", logging.Status, domain.StatusDone, "error", err)
+		span.RecordError(err)
+		p.observer.TaskFinished(domain.StatusFailed, time.Since(start))
+		// the dependents must hear of it either way: failed here, or how it
+		// finished meanwhile (e.g. canceled)
+		if _, fErr := p.store.Fail(id, "recording the result failed: "+err.Error()); fErr != nil {
+			p.finishStored(id)
+			return
+		}
+		p.finish(id, domain.StatusFailed)
+		return
+	}
 
-		log.Printf("[worker= %d] (taskID= %d) started task with duration of %s seconds.", workerID, id, task.WorkDuration)
+	elapsed := time.Since(start)
+	p.observer.TaskFinished(domain.StatusDone, elapsed)
 
-		time.Sleep(task.WorkDuration)
+	logger.Info("task completed", logging.Status, domain.StatusDone, logging.Duration, elapsed, "planned", task.WorkDuration)
 
-		if _, err := p.store.UpdateStatus(id, domain.StatusDone); err != nil {
-			log.Printf("[worker= %d] (taskID= %d) updating status to *DONE* failed. (error= %v).", workerID, id, err)
-			continue
+	p.finish(id, domain.StatusDone)
+}
+
+// Abort cancels the context of a running task's executor. It reports false
+// when the task isn't running. The caller marks the task canceled first.
+func (p *Pool) Abort(id int64) bool {
+	cancel, ok := p.running.Load(id)
+	if !ok {
+		return false
+	}
+	cancel.(context.CancelFunc)()
+	return true
+}
+
+// execute runs the executor with a logger writing into the task's log.
+func (p *Pool) execute(ctx context.Context, task domain.Task) error {
+	if p.taskLogs == nil {
+		return p.executor(ctx, task, slog.New(slog.DiscardHandler))
+	}
+
+	buf := p.taskLogs.Buffer(task.ID)
+	defer buf.Close()
+
+	return p.executor(ctx, task, tasklog.NewLogger(buf))
+}
+
+// fail records an executor error on the task.
+func (p *Pool) fail(task domain.Task, logger *slog.Logger, err error, took time.Duration) {
+	p.observer.TaskFinished(domain.StatusFailed, took)
+
+	if _, fErr := p.store.Fail(task.ID, err.Error()); fErr != nil {
+		logger.Error("failing task failed", logging.Status, domain.StatusFailed, "error", fErr)
+		p.finishStored(task.ID)
+		return
+	}
+	logger.Warn("task failed", logging.Status, domain.StatusFailed, logging.Duration, took, "error", err)
+
+	p.finish(task.ID, domain.StatusFailed)
+}
+
+// finish tells the OnFinish callbacks the task ended with status.
+func (p *Pool) finish(id int64, status domain.TaskStatus) {
+	for _, fn := range p.onFinish {
+		fn(id, status)
+	}
+}
+
+// finishStored reports the status the store holds after the one the worker
+// wanted was refused, so blocked dependents aren't left waiting. A task that
+// is gone or still unfinished there can't be reported.
+func (p *Pool) finishStored(id int64) {
+	if task, ok := p.store.Get(id); ok && task.Status.Terminal() {
+		p.finish(id, task.Status)
+	}
+}
+
+// acquire takes a running slot on the task's key, or parks the task when the key
+// is at its limit. Parked tasks keep their order, a new task never overtakes them.
+func (p *Pool) acquire(task domain.Task) bool {
+	limit := task.ConcurrencyLimit
+	if limit <= 0 {
+		limit = 1
+	}
+
+	p.keysMu.Lock()
+	defer p.keysMu.Unlock()
+
+	key := groupKey{owner: task.Owner, key: task.ConcurrencyKey}
+	g, ok := p.keys[key]
+	if !ok {
+		g = &keyGroup{}
+		p.keys[key] = g
+	}
+
+	if g.running < limit && len(g.waiting) == 0 {
+		g.running++
+		return true
+	}
+	g.waiting = append(g.waiting, task.ID)
+	return false
+}
+
+// release frees a slot on key. When a task is parked on it, the slot is handed
+// over to that task (the running count stays the same) and its id is returned.
+func (p *Pool) release(key groupKey) (int64, bool) {
+	p.keysMu.Lock()
+	defer p.keysMu.Unlock()
+
+	g := p.keys[key]
+	if len(g.waiting) > 0 {
+		next := g.waiting[0]
+		g.waiting = g.waiting[1:]
+		return next, true
+	}
+
+	g.running--
+	if g.running == 0 {
+		delete(p.keys, key)
+	}
+	return 0, false
+}
+
+// handoff releases the slot held on key and returns the next parked task to run
+// on the same worker, skipping the ones that were failed or canceled meanwhile.
+func (p *Pool) handoff(workerID int, key groupKey) (domain.Task, bool) {
+	for {
+		id, ok := p.release(key)
+		if !ok {
+			return domain.Task{}, false
+		}
+		if task, ok := p.load(workerID, id); ok {
+			return task, true
 		}
+		p.enqueuedAt.Delete(id)
+	}
+}
 
-		elapsed := time.Since(start)
+// ConcurrencyStats returns running and waiting counts per busy owner and concurrency key.
+func (p *Pool) ConcurrencyStats() []KeyStats {
+	p.keysMu.Lock()
+	defer p.keysMu.Unlock()
 
-		log.Printf("[worker= %d] (taskID= %d) completed task with an actual duration of %s (planned %s)", workerID, id, elapsed, task.WorkDuration)
+	stats := make([]KeyStats, 0, len(p.keys))
+	for key, g := range p.keys {
+		stats = append(stats, KeyStats{Owner: key.owner, Key: key.key, Running: g.running, Waiting: len(g.waiting)})
 	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Owner != stats[j].Owner {
+			return stats[i].Owner < stats[j].Owner
+		}
+		return stats[i].Key < stats[j].Key
+	})
+
+	return stats
+}
+
+func (p *Pool) QueueDepth() int {
+	return p.queue.len()
+}
+
+func (p *Pool) QueueCapacity() int {
+	return p.queue.capacity
+}
+
+// TenantQueues returns the queue depth of each tenant with queued tasks.
+func (p *Pool) TenantQueues() []TenantStats {
+	return p.queue.stats()
+}
+
+func (p *Pool) Workers() int {
+	return int(p.workers.Load())
+}
+
+func (p *Pool) BusyWorkers() int {
+	return int(p.busy.Load())
+}
+
+type WorkerState struct {
+	ID     int
+	TaskID int64     // 0 when idle
+	Since  time.Time // when the current task started
+}
+
+func (p *Pool) setState(state WorkerState) {
+	p.statesMu.Lock()
+	defer p.statesMu.Unlock()
+	p.states[state.ID] = state
+}
+
+// WorkerStates lists the workers and what they are running, by worker ID.
+func (p *Pool) WorkerStates() []WorkerState {
+	p.statesMu.Lock()
+	defer p.statesMu.Unlock()
+
+	states := make([]WorkerState, 0, len(p.states))
+	for _, state := range p.states {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+	return states
+}
+
+// QueuedIDs returns the ids waiting in the queue, next one first. Tasks parked
+// on a concurrency key are not in the queue anymore.
+func (p *Pool) QueuedIDs() []int64 {
+	return p.queue.order()
 }
 
-func (p *Pool) Queue() <-chan int64 {
-	return p.queue
+// Closed reports whether Shutdown or Drain has been called.
+func (p *Pool) Closed() bool {
+	return p.closed.Load()
 }
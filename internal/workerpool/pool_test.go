@@ -1,9 +1,15 @@
 package workerpool
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"interview-task-worker-pool/internal/domain"
+	"interview-task-worker-pool/internal/tasklog"
+	"interview-task-worker-pool/internal/tracing"
+	"log/slog"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -66,6 +72,9 @@ func (ts *testStore) Fail(id int64, reason string) (domain.Task, error) {
 	if !ok {
 		return domain.Task{}, errors.New("task not found")
 	}
+	if t.Status.Terminal() {
+		return domain.Task{}, errors.New("task already finished")
+	}
 
 	t.Status = domain.StatusFailed
 	t.Error = reason
@@ -224,3 +233,629 @@ func TestPool_EnqueueAfterShutdown_ReturnsPoolClosed(t *testing.T) {
 		t.Fatalf("Enqueue() err=%v, want %v", err, ErrPoolClosed)
 	}
 }
+
+func TestPool_ConcurrencyKey_LimitsSameKeyOnly(t *testing.T) {
+	store := newTestStore()
+	for i := int64(1); i <= 2; i++ {
+		store.Put(domain.Task{
+			ID:               i,
+			Status:           domain.StatusPending,
+			ConcurrencyKey:   "printer",
+			ConcurrencyLimit: 1,
+			WorkDuration:     100 * time.Millisecond,
+		})
+	}
+	store.Put(domain.Task{ID: 3, Status: domain.StatusPending, WorkDuration: 10 * time.Millisecond})
+
+	pool := New(10, store)
+	pool.Start(2)
+
+	t.Cleanup(func() {
+		_ = pool.Shutdown(context.Background())
+	})
+
+	for i := int64(1); i <= 3; i++ {
+		if err := pool.Enqueue(i); err != nil {
+			t.Fatalf("Enqueue(%d) err=%v", i, err)
+		}
+	}
+
+	// the parked task must not hold a worker, so the unkeyed task runs meanwhile
+	first := waitID(t, store.running, 500*time.Millisecond)
+	second := waitID(t, store.running, 500*time.Millisecond)
+	if first != 3 && second != 3 {
+		t.Fatalf("running ids=%d,%d, want task 3 among them", first, second)
+	}
+	keyed := first
+	if keyed == 3 {
+		keyed = second
+	}
+
+	stats := pool.ConcurrencyStats()
+	if len(stats) != 1 || stats[0].Key != "printer" || stats[0].Running != 1 || stats[0].Waiting != 1 {
+		t.Fatalf("ConcurrencyStats()=%+v, want printer running=1 waiting=1", stats)
+	}
+
+	// the other keyed task only starts after the first one is done
+	next := waitID(t, store.running, 1*time.Second)
+	if next == keyed || next == 3 {
+		t.Fatalf("next running id=%d, want the parked task", next)
+	}
+	if task, _ := store.Get(keyed); task.Status != domain.StatusDone {
+		t.Fatalf("task %d status=%s when task %d started, want %s", keyed, task.Status, next, domain.StatusDone)
+	}
+}
+
+func TestPool_ConcurrencyKey_ShutdownDrainsParked(t *testing.T) {
+	store := newTestStore()
+	for i := int64(1); i <= 3; i++ {
+		store.Put(domain.Task{
+			ID:             i,
+			Status:         domain.StatusPending,
+			ConcurrencyKey: "k",
+			WorkDuration:   10 * time.Millisecond,
+		})
+	}
+
+	pool := New(10, store)
+	pool.Start(3)
+
+	for i := int64(1); i <= 3; i++ {
+		if err := pool.Enqueue(i); err != nil {
+			t.Fatalf("Enqueue(%d) err=%v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() err=%v, want nil", err)
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		if task, _ := store.Get(i); task.Status != domain.StatusDone {
+			t.Fatalf("task %d status=%s, want %s", i, task.Status, domain.StatusDone)
+		}
+	}
+	if stats := pool.ConcurrencyStats(); len(stats) != 0 {
+		t.Fatalf("ConcurrencyStats()=%+v, want empty", stats)
+	}
+}
+
+func TestPool_ConcurrencyKey_ScopedToOwner(t *testing.T) {
+	store := newTestStore()
+	store.Put(domain.Task{ID: 1, Owner: "alice", Status: domain.StatusPending, ConcurrencyKey: "printer", WorkDuration: 200 * time.Millisecond})
+	store.Put(domain.Task{ID: 2, Owner: "bob", Status: domain.StatusPending, ConcurrencyKey: "printer", WorkDuration: 200 * time.Millisecond})
+
+	pool := New(10, store)
+	pool.Start(2)
+	t.Cleanup(func() {
+		_ = pool.Shutdown(context.Background())
+	})
+
+	for i := int64(1); i <= 2; i++ {
+		if err := pool.Enqueue(i); err != nil {
+			t.Fatalf("Enqueue(%d) err=%v", i, err)
+		}
+	}
+
+	// the same key of two owners is two keys, both tasks run at once
+	waitID(t, store.running, 500*time.Millisecond)
+	waitID(t, store.running, 100*time.Millisecond)
+
+	stats := pool.ConcurrencyStats()
+	if len(stats) != 2 || stats[0].Owner != "alice" || stats[1].Owner != "bob" || stats[0].Running != 1 || stats[1].Running != 1 {
+		t.Fatalf("ConcurrencyStats()=%+v, want printer running=1 for alice and bob", stats)
+	}
+}
+
+func TestPool_ConcurrencyKey_HandoffWaitsWhilePaused(t *testing.T) {
+	store := newTestStore()
+	store.Put(domain.Task{ID: 1, Status: domain.StatusPending, ConcurrencyKey: "k", WorkDuration: 100 * time.Millisecond})
+	store.Put(domain.Task{ID: 2, Status: domain.StatusPending, ConcurrencyKey: "k", WorkDuration: 10 * time.Millisecond})
+
+	pool := New(10, store)
+	pool.Start(2)
+	t.Cleanup(func() {
+		_ = pool.Shutdown(context.Background())
+	})
+
+	for i := int64(1); i <= 2; i++ {
+		if err := pool.Enqueue(i); err != nil {
+			t.Fatalf("Enqueue(%d) err=%v", i, err)
+		}
+	}
+	waitID(t, store.running, 500*time.Millisecond)
+	// task 2 is parked behind task 1 once a worker picked it up
+	deadline := time.Now().Add(time.Second)
+	for stats := pool.ConcurrencyStats(); len(stats) != 1 || stats[0].Waiting != 1; stats = pool.ConcurrencyStats() {
+		if time.Now().After(deadline) {
+			t.Fatalf("ConcurrencyStats()=%+v, want k running=1 waiting=1", stats)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := pool.Pause(); err != nil {
+		t.Fatalf("Pause() err=%v", err)
+	}
+	select {
+	case id := <-store.running:
+		t.Fatalf("task %d handed off while paused", id)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	pool.Resume()
+	if id := waitID(t, store.running, time.Second); id != 2 {
+		t.Fatalf("running id=%d after Resume, want 2", id)
+	}
+}
+
+func TestPool_Reserve_HoldsRoomForTheBatch(t *testing.T) {
+	store := newTestStore()
+	for id := int64(1); id <= 3; id++ {
+		store.Put(domain.Task{ID: id, Status: domain.StatusPending})
+	}
+	pool := New(3, store)
+
+	r, err := pool.Reserve(2)
+	if err != nil {
+		t.Fatalf("Reserve(2) err=%v", err)
+	}
+	if _, err := pool.Reserve(2); !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("Reserve(2) over capacity err=%v, want %v", err, ErrPoolFull)
+	}
+
+	// the reserved room is kept from other producers
+	if err := pool.Enqueue(1); err != nil {
+		t.Fatalf("Enqueue(1) err=%v", err)
+	}
+	if err := pool.Enqueue(2); !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("Enqueue(2) err=%v, want %v", err, ErrPoolFull)
+	}
+	if err := r.Enqueue(3); err != nil {
+		t.Fatalf("Reservation.Enqueue(3) err=%v", err)
+	}
+
+	// the unused room goes back
+	r.Release()
+	if err := pool.Enqueue(2); err != nil {
+		t.Fatalf("Enqueue(2) after Release err=%v", err)
+	}
+	if got := pool.QueuedIDs(); len(got) != 3 {
+		t.Fatalf("QueuedIDs()=%v, want 3 tasks", got)
+	}
+}
+
+type recordingObserver struct {
+	mu                  sync.Mutex
+	enqueued, rejected  int
+	started, finishedOK int
+}
+
+func (o *recordingObserver) TaskEnqueued() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.enqueued++
+}
+
+func (o *recordingObserver) TaskRejected(error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.rejected++
+}
+
+func (o *recordingObserver) TaskStarted(time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.started++
+}
+
+func (o *recordingObserver) TaskFinished(status domain.TaskStatus, _ time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if status == domain.StatusDone {
+		o.finishedOK++
+	}
+}
+
+func TestPool_Observer_And_Gauges(t *testing.T) {
+	store := newTestStore()
+	store.Put(domain.Task{ID: 1, Status: domain.StatusPending, WorkDuration: 10 * time.Millisecond})
+
+	obs := &recordingObserver{}
+	pool := New(1, store, WithObserver(obs))
+
+	if err := pool.Enqueue(1); err != nil {
+		t.Fatalf("Enqueue(1) err=%v", err)
+	}
+	if err := pool.Enqueue(2); !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("Enqueue(2) err=%v, want %v", err, ErrPoolFull)
+	}
+	if pool.QueueDepth() != 1 || pool.QueueCapacity() != 1 {
+		t.Fatalf("depth=%d capacity=%d, want 1/1", pool.QueueDepth(), pool.QueueCapacity())
+	}
+
+	pool.Start(2)
+	if pool.Workers() != 2 {
+		t.Fatalf("Workers()=%d, want 2", pool.Workers())
+	}
+	_ = waitID(t, store.done, time.Second)
+
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() err=%v", err)
+	}
+
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	if obs.enqueued != 1 || obs.rejected != 1 || obs.started != 1 || obs.finishedOK != 1 {
+		t.Fatalf("observer=%+v, want 1 enqueued, 1 rejected, 1 started, 1 done", obs)
+	}
+	if pool.BusyWorkers() != 0 {
+		t.Fatalf("BusyWorkers()=%d, want 0", pool.BusyWorkers())
+	}
+}
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) Export(span tracing.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *spanRecorder) Shutdown(context.Context) error { return nil }
+
+func TestPool_Tracing_ContinuesTaskTrace(t *testing.T) {
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	store := newTestStore()
+	store.Put(domain.Task{ID: 1, Status: domain.StatusPending, WorkDuration: 10 * time.Millisecond, TraceParent: parent})
+
+	rec := &spanRecorder{}
+	pool := New(1, store, WithTracer(tracing.NewTracer("test", rec)))
+	pool.Start(1)
+
+	if err := pool.Enqueue(1); err != nil {
+		t.Fatalf("Enqueue(1) err=%v", err)
+	}
+	_ = waitID(t, store.done, time.Second)
+
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() err=%v", err)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	names := make(map[string]bool)
+	for _, s := range rec.spans {
+		names[s.Name] = true
+		if s.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || s.Parent.String() != "00f067aa0ba902b7" {
+			t.Fatalf("span %q=%+v parent=%s, want child of the task's trace", s.Name, s.SpanContext, s.Parent)
+		}
+	}
+	if !names["queue.wait"] || !names["task.execute"] {
+		t.Fatalf("spans=%v, want queue.wait and task.execute", names)
+	}
+}
+
+func TestPool_Logs_CarryTaskAttributes(t *testing.T) {
+	store := newTestStore()
+	store.Put(domain.Task{ID: 1, Status: domain.StatusPending, WorkDuration: 10 * time.Millisecond, RequestID: "req-1"})
+
+	var buf syncBuffer
+	pool := New(1, store, WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+	pool.Start(1)
+
+	if err := pool.Enqueue(1); err != nil {
+		t.Fatalf("Enqueue(1) err=%v", err)
+	}
+	_ = waitID(t, store.done, time.Second)
+
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() err=%v", err)
+	}
+
+	var completed map[string]any
+	for _, raw := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var line map[string]any
+		if err := json.Unmarshal(raw, &line); err != nil {
+			t.Fatalf("log line is not JSON: %q", raw)
+		}
+		if line["msg"] == "task completed" {
+			completed = line
+		}
+	}
+	if completed == nil {
+		t.Fatalf("no completion line in:\n%s", buf.Bytes())
+	}
+	if completed["task_id"] != float64(1) || completed["worker_id"] != float64(1) ||
+		completed["request_id"] != "req-1" || completed["status"] != "done" || completed["duration"] == nil {
+		t.Fatalf("completion line=%v", completed)
+	}
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func TestPool_Executor_FailureAndTaskLogs(t *testing.T) {
+	store := newTestStore()
+	store.Put(domain.Task{ID: 1, Status: domain.StatusPending})
+
+	logs := tasklog.NewStore(tasklog.Limits{})
+	exec := func(_ context.Context, task domain.Task, logger *slog.Logger) error {
+		logger.Info("connecting", "attempt", 1)
+		return errors.New("upstream unavailable")
+	}
+
+	finished := make(chan domain.TaskStatus, 1)
+	pool := New(1, store, WithExecutor(exec), WithTaskLogs(logs))
+	pool.OnFinish(func(_ int64, status domain.TaskStatus) { finished <- status })
+	pool.Start(1)
+
+	if err := pool.Enqueue(1); err != nil {
+		t.Fatalf("Enqueue(1) err=%v", err)
+	}
+
+	select {
+	case status := <-finished:
+		if status != domain.StatusFailed {
+			t.Fatalf("OnFinish status=%s, want %s", status, domain.StatusFailed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for OnFinish")
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() err=%v", err)
+	}
+
+	task, _ := store.Get(1)
+	if task.Status != domain.StatusFailed || task.Error != "upstream unavailable" {
+		t.Fatalf("task=%+v, want failed with the executor error", task)
+	}
+
+	buf, ok := logs.Lookup(1)
+	if !ok {
+		t.Fatalf("no log captured for task 1")
+	}
+	snap, _ := buf.Since(0)
+	if !snap.Closed || len(snap.Entries) != 1 || snap.Entries[0].Message != "connecting" {
+		t.Fatalf("log=%+v, want one closed line", snap)
+	}
+}
+
+// refusingStore refuses to record a task as done, canceling it first when
+// canceled is set.
+type refusingStore struct {
+	*testStore
+	canceled bool
+}
+
+func (s *refusingStore) UpdateStatus(id int64, status domain.TaskStatus) (domain.Task, error) {
+	if status != domain.StatusDone {
+		return s.testStore.UpdateStatus(id, status)
+	}
+	if s.canceled {
+		task, _ := s.Get(id)
+		task.Status = domain.StatusCanceled
+		s.Put(task)
+	}
+	return domain.Task{}, errors.New("store unavailable")
+}
+
+func TestPool_DoneRefused_StillReportsFinish(t *testing.T) {
+	for _, tt := range []struct {
+		canceled bool
+		want     domain.TaskStatus
+	}{
+		{canceled: false, want: domain.StatusFailed},  // failed in its place
+		{canceled: true, want: domain.StatusCanceled}, // how it finished meanwhile
+	} {
+		store := &refusingStore{testStore: newTestStore(), canceled: tt.canceled}
+		store.Put(domain.Task{ID: 1, Status: domain.StatusPending})
+
+		finished := make(chan domain.TaskStatus, 1)
+		pool := New(1, store)
+		pool.OnFinish(func(_ int64, status domain.TaskStatus) { finished <- status })
+		pool.Start(1)
+		if err := pool.Enqueue(1); err != nil {
+			t.Fatalf("Enqueue(1) err=%v", err)
+		}
+
+		select {
+		case status := <-finished:
+			if status != tt.want {
+				t.Fatalf("OnFinish status=%s, want %s", status, tt.want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for OnFinish, want %s", tt.want)
+		}
+		if task, _ := store.Get(1); task.Status != tt.want {
+			t.Fatalf("stored status=%s, want %s", task.Status, tt.want)
+		}
+		_ = pool.Shutdown(context.Background())
+	}
+}
+
+func TestPool_PauseResume_QueuedIDsAndWorkerStates(t *testing.T) {
+	store := newTestStore()
+	for id := int64(1); id <= 3; id++ {
+		store.Put(domain.Task{ID: id, Status: domain.StatusPending, WorkDuration: 50 * time.Millisecond})
+	}
+
+	pool := New(10, store)
+	if err := pool.Pause(); err != nil {
+		t.Fatalf("Pause() err=%v", err)
+	}
+	pool.Start(1)
+
+	for id := int64(1); id <= 3; id++ {
+		if err := pool.Enqueue(id); err != nil {
+			t.Fatalf("Enqueue(%d) err=%v", id, err)
+		}
+	}
+
+	// paused: nothing starts, the queue keeps its order
+	select {
+	case id := <-store.running:
+		t.Fatalf("task %d started while paused", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := pool.QueuedIDs(); len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Fatalf("QueuedIDs()=%v, want [1 2 3]", got)
+	}
+
+	pool.Resume()
+	if id := waitID(t, store.running, time.Second); id != 1 {
+		t.Fatalf("first running=%d, want 1", id)
+	}
+
+	states := pool.WorkerStates()
+	if len(states) != 1 || states[0].ID != 1 || states[0].TaskID != 1 {
+		t.Fatalf("WorkerStates()=%+v, want worker 1 on task 1", states)
+	}
+	if got := pool.QueuedIDs(); len(got) != 2 || got[0] != 2 {
+		t.Fatalf("QueuedIDs()=%v, want [2 3]", got)
+	}
+
+	// Drain closes the pool but keeps working off the queue
+	pool.Drain()
+	if err := pool.Enqueue(4); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Enqueue after Drain err=%v, want %v", err, ErrPoolClosed)
+	}
+	if err := pool.Pause(); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Pause after Drain err=%v, want %v", err, ErrPoolClosed)
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() err=%v", err)
+	}
+	for id := int64(1); id <= 3; id++ {
+		if task, _ := store.Get(id); task.Status != domain.StatusDone {
+			t.Fatalf("task %d status=%s, want done", id, task.Status)
+		}
+	}
+	if states := pool.WorkerStates(); states[0].TaskID != 0 {
+		t.Fatalf("WorkerStates()=%+v, want idle", states)
+	}
+}
+
+func TestPool_Shutdown_ResumesPausedPool(t *testing.T) {
+	store := newTestStore()
+	store.Put(domain.Task{ID: 1, Status: domain.StatusPending, WorkDuration: time.Millisecond})
+
+	pool := New(1, store)
+	pool.Start(1)
+	_ = pool.Pause()
+	if err := pool.Enqueue(1); err != nil {
+		t.Fatalf("Enqueue(1) err=%v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() err=%v, want nil", err)
+	}
+	if task, _ := store.Get(1); task.Status != domain.StatusDone {
+		t.Fatalf("status=%s, want done", task.Status)
+	}
+}
+
+func TestPool_FairQueue_RoundRobinAcrossTenants(t *testing.T) {
+	store := newTestStore()
+	// b floods the queue first, a and c come later
+	owners := map[int64]string{1: "b", 2: "b", 3: "b", 4: "b", 5: "b", 6: "a", 7: "a", 8: "c"}
+	for id, owner := range owners {
+		store.Put(domain.Task{ID: id, Owner: owner, Status: domain.StatusPending})
+	}
+
+	noop := func(context.Context, domain.Task, *slog.Logger) error { return nil }
+	pool := New(8, store, WithExecutor(noop), WithTenantWeights(map[string]int{"a": 2}))
+	for id := int64(1); id <= 8; id++ {
+		if err := pool.Enqueue(id); err != nil {
+			t.Fatalf("Enqueue(%d) err=%v", id, err)
+		}
+	}
+	if err := pool.Enqueue(9); !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("Enqueue over capacity err=%v, want %v", err, ErrPoolFull)
+	}
+
+	stats := pool.TenantQueues()
+	if len(stats) != 3 || stats[0] != (TenantStats{Tenant: "a", Queued: 2, Weight: 2}) || stats[1].Queued != 5 {
+		t.Fatalf("TenantQueues()=%+v", stats)
+	}
+
+	// a round is one of b, two of a (weight 2), one of c, then b's backlog
+	want := []int64{1, 6, 7, 8, 2, 3, 4, 5}
+	if got := pool.QueuedIDs(); !slices.Equal(got, want) {
+		t.Fatalf("QueuedIDs()=%v, want %v", got, want)
+	}
+
+	pool.Start(1)
+	var got []int64
+	for range want {
+		got = append(got, waitID(t, store.running, time.Second))
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("run order=%v, want %v", got, want)
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() err=%v", err)
+	}
+}
+
+func TestPool_Abort_StopsRunningExecutor(t *testing.T) {
+	store := newTestStore()
+	store.Put(domain.Task{ID: 1, Status: domain.StatusPending, WorkDuration: time.Minute})
+
+	finished := make(chan domain.TaskStatus, 1)
+	pool := New(1, store)
+	pool.OnFinish(func(_ int64, status domain.TaskStatus) { finished <- status })
+	pool.Start(1)
+
+	if pool.Abort(1) {
+		t.Fatalf("Abort(queued task)=true, want false")
+	}
+	if err := pool.Enqueue(1); err != nil {
+		t.Fatalf("Enqueue(1) err=%v", err)
+	}
+	waitID(t, store.running, time.Second)
+
+	// the service cancels the task in the store, then aborts its execution
+	task, _ := store.Get(1)
+	task.Status = domain.StatusCanceled
+	store.Put(task)
+	if !pool.Abort(1) {
+		t.Fatalf("Abort(running task)=false, want true")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() err=%v, want the aborted task to have stopped", err)
+	}
+	if task, _ := store.Get(1); task.Status != domain.StatusCanceled {
+		t.Fatalf("task status=%s, want %s", task.Status, domain.StatusCanceled)
+	}
+	select {
+	case status := <-finished:
+		t.Fatalf("OnFinish(%s) called for an aborted task", status)
+	default:
+	}
+}